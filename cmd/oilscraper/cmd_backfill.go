@@ -2,14 +2,13 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"os"
 	"time"
 
 	"github.com/spf13/cobra"
 
-	"github.com/andygrunwald/oil-price-scraper/internal/api/heizoel24"
-	"github.com/andygrunwald/oil-price-scraper/internal/api/hoyer"
-	"github.com/andygrunwald/oil-price-scraper/internal/database"
 	"github.com/andygrunwald/oil-price-scraper/internal/scraper"
 )
 
@@ -17,34 +16,59 @@ func backfillCmd() *cobra.Command {
 	var fromStr, toStr string
 	var provider string
 	var minDelay, maxDelay int
+	var progressEvery int
+	var progressBar bool
+	var quiet bool
+	var jsonSummary bool
+	var label string
+	var concurrency int
 
 	cmd := &cobra.Command{
 		Use:   "backfill",
 		Short: "Backfill historical data",
 		Long:  "Backfills historical data from APIs that support it (e.g., HeizOel24).",
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			problems := []error{
+				requireFlag("postgres-dsn", cfg.PostgresDSN),
+				requireFlag("zip-code", cfg.ZipCode),
+				requireFlag("from", fromStr),
+				checkLabel(label),
+			}
+			if !isKnownProvider(provider) {
+				problems = append(problems, fmt.Errorf("unknown provider: %q", provider))
+			}
+			if concurrency < 1 {
+				problems = append(problems, fmt.Errorf("--concurrency: must be at least 1, got %d", concurrency))
+			}
+			if minDelay < 0 || maxDelay < 0 {
+				problems = append(problems, fmt.Errorf("--min-delay and --max-delay must not be negative"))
+			}
+			return validateConfig(problems...)
+		},
 		RunE: func(cmd *cobra.Command, args []string) error {
-			logger := setupLogger()
-
-			if cfg.PostgresDSN == "" {
-				return fmt.Errorf("--postgres-dsn is required")
+			// Only warnings and errors are logged in quiet mode
+			if quiet {
+				cfg.LogLevel = "warn"
 			}
 
-			if cfg.ZipCode == "" {
-				return fmt.Errorf("--zip-code is required")
+			// Keep stdout clean for the summary
+			if jsonSummary {
+				logOutput = os.Stderr
 			}
 
-			if fromStr == "" {
-				return fmt.Errorf("--from is required")
-			}
+			logger := setupLogger()
+
+			// Dates are calendar days in the time zone of price dates (see api.PriceDate)
+			loc := cfg.PriceLocation()
 
-			from, err := time.Parse("2006-01-02", fromStr)
+			from, err := parseBackfillDate(fromStr, loc)
 			if err != nil {
 				return fmt.Errorf("parsing --from date: %w", err)
 			}
 
-			to := time.Now()
+			to := time.Now().In(loc)
 			if toStr != "" {
-				to, err = time.Parse("2006-01-02", toStr)
+				to, err = parseBackfillDate(toStr, loc)
 				if err != nil {
 					return fmt.Errorf("parsing --to date: %w", err)
 				}
@@ -54,12 +78,13 @@ func backfillCmd() *cobra.Command {
 				Str("provider", provider).
 				Str("from", from.Format("2006-01-02")).
 				Str("to", to.Format("2006-01-02")).
+				Str("timezone", loc.String()).
 				Int("minDelay", minDelay).
 				Int("maxDelay", maxDelay).
 				Msg("starting backfill")
 
 			// Connect to database
-			db, err := database.New(cfg.PostgresDSN, logger)
+			db, err := newDatabase(logger)
 			if err != nil {
 				return fmt.Errorf("connecting to database: %w", err)
 			}
@@ -70,21 +95,43 @@ func backfillCmd() *cobra.Command {
 			}()
 
 			// Create scraper
-			s := scraper.New(db, cfg.StoreRawResponse, logger)
+			s, err := newScraper(db, logger)
+			if err != nil {
+				return err
+			}
+			s.SetLabel(label)
 
-			// Register provider
-			switch provider {
-			case "heizoel24":
-				s.RegisterProvider(heizoel24.New(logger))
-			case "hoyer":
-				s.RegisterProvider(hoyer.New(logger, cfg.ZipCode, cfg.OrderAmount))
-			default:
-				return fmt.Errorf("unknown provider: %s", provider)
+			// Register provider. Backfill always requires a known provider.
+			p, ok, err := newProvider(provider, cfg.ZipCode, nil, logger)
+			if err != nil {
+				return err
+			}
+			if !ok {
+				return fmt.Errorf("unknown provider: %q", provider)
 			}
+			s.RegisterProvider(p)
+
+			pushMetrics := setupPushMetrics(s, "backfill", logger)
+			defer pushMetrics()
 
 			// Run backfill
 			ctx := context.Background()
-			if err := s.Backfill(ctx, provider, from, to, minDelay, maxDelay); err != nil {
+			opts := scraper.BackfillOptions{
+				MinDelay:      minDelay,
+				MaxDelay:      maxDelay,
+				ProgressEvery: progressEvery,
+				Concurrency:   concurrency,
+			}
+			if progressBar && isTerminal(os.Stdout) {
+				opts.OnProgress = renderProgressBar
+			}
+			result, err := s.Backfill(ctx, provider, from, to, opts)
+			if jsonSummary {
+				if err := printBackfillSummary(provider, result, err); err != nil {
+					return err
+				}
+			}
+			if err != nil {
 				return fmt.Errorf("backfilling: %w", err)
 			}
 
@@ -93,11 +140,64 @@ func backfillCmd() *cobra.Command {
 		},
 	}
 
-	cmd.Flags().StringVar(&fromStr, "from", "", "Start date (YYYY-MM-DD, required)")
-	cmd.Flags().StringVar(&toStr, "to", "", "End date (YYYY-MM-DD, defaults to today)")
+	cmd.Flags().StringVar(&fromStr, "from", "", "Start date (YYYY-MM-DD or RFC3339, required)")
+	cmd.Flags().StringVar(&toStr, "to", "", "End date (YYYY-MM-DD or RFC3339, defaults to today)")
 	cmd.Flags().StringVar(&provider, "provider", "heizoel24", "Provider to backfill from")
 	cmd.Flags().IntVar(&minDelay, "min-delay", 1, "Minimum delay between requests (seconds)")
 	cmd.Flags().IntVar(&maxDelay, "max-delay", 5, "Maximum delay between requests (seconds)")
+	cmd.Flags().IntVar(&concurrency, "concurrency", 1, "Number of monthly chunks fetched at the same time")
+	cmd.Flags().IntVar(&progressEvery, "progress-every", 1, "Log progress every N monthly chunks")
+	cmd.Flags().BoolVar(&progressBar, "progress-bar", false, "Render a progress bar when stdout is a terminal")
+	cmd.Flags().StringVar(&label, "label", "", "Free-form label stored with the backfilled prices (e.g. source=manual-import-2024)")
+	cmd.Flags().BoolVar(&quiet, "quiet", false, "Only log warnings and errors")
+	cmd.Flags().BoolVar(&jsonSummary, "json-summary", false, "Print a JSON summary to stdout when the backfill ends")
 
 	return cmd
 }
+
+// parseBackfillDate parses a date in the format YYYY-MM-DD in loc, or an RFC3339
+// datetime. RFC3339 datetimes are converted to loc, so the calendar day is always
+// the day in loc.
+func parseBackfillDate(value string, loc *time.Location) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t.In(loc), nil
+	}
+	return time.ParseInLocation("2006-01-02", value, loc)
+}
+
+// backfillSummary is the machine-readable summary printed with --json-summary.
+type backfillSummary struct {
+	Success         bool    `json:"success"`
+	Error           string  `json:"error,omitempty"`
+	Provider        string  `json:"provider"`
+	From            string  `json:"from"`
+	To              string  `json:"to"`
+	Chunks          int     `json:"chunks"`
+	Inserted        int     `json:"inserted"`
+	Skipped         int     `json:"skipped"`
+	Failed          int     `json:"failed"`
+	DurationSeconds float64 `json:"duration_seconds"`
+}
+
+// printBackfillSummary prints the result of a backfill as JSON to stdout.
+func printBackfillSummary(provider string, result scraper.BackfillResult, backfillErr error) error {
+	summary := backfillSummary{
+		Success:         backfillErr == nil && result.Failed == 0,
+		Provider:        provider,
+		From:            result.From.Format("2006-01-02"),
+		To:              result.To.Format("2006-01-02"),
+		Chunks:          result.Chunks,
+		Inserted:        result.Inserted,
+		Skipped:         result.Skipped,
+		Failed:          result.Failed,
+		DurationSeconds: result.Duration.Seconds(),
+	}
+	if backfillErr != nil {
+		summary.Error = backfillErr.Error()
+	}
+
+	if err := json.NewEncoder(os.Stdout).Encode(summary); err != nil {
+		return fmt.Errorf("encoding summary: %w", err)
+	}
+	return nil
+}
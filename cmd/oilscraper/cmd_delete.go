@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/andygrunwald/oil-price-scraper/internal/database"
+)
+
+func deleteCmd() *cobra.Command {
+	var fromStr, toStr string
+	var provider string
+	var zipCode string
+	var label string
+	var yes bool
+
+	cmd := &cobra.Command{
+		Use:   "delete",
+		Short: "Delete stored prices",
+		Long: `Deletes stored price records matching all given filters (provider, date range, zip code, label),
+e.g. to clean up after importing bad data before a re-backfill. At least one filter is required.
+The deletion runs in a single transaction.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			logger := setupLogger()
+
+			if cfg.PostgresDSN == "" {
+				return fmt.Errorf("--postgres-dsn is required")
+			}
+
+			filter := database.PriceFilter{
+				Provider: provider,
+				ZipCode:  zipCode,
+				Label:    label,
+			}
+
+			var err error
+			if fromStr != "" {
+				filter.From, err = time.Parse("2006-01-02", fromStr)
+				if err != nil {
+					return fmt.Errorf("parsing --from date: %w", err)
+				}
+			}
+			if toStr != "" {
+				filter.To, err = time.Parse("2006-01-02", toStr)
+				if err != nil {
+					return fmt.Errorf("parsing --to date: %w", err)
+				}
+			}
+
+			if filter.IsEmpty() {
+				return fmt.Errorf("at least one of --provider, --from, --to, --zip, or --label is required")
+			}
+
+			// Connect to database
+			db, err := newDatabase(logger)
+			if err != nil {
+				return fmt.Errorf("connecting to database: %w", err)
+			}
+			defer func() {
+				if err := db.Close(); err != nil {
+					panic(err)
+				}
+			}()
+
+			ctx := context.Background()
+
+			count, err := db.CountPrices(ctx, filter)
+			if err != nil {
+				return err
+			}
+			if count == 0 {
+				fmt.Println("No matching price records found.")
+				return nil
+			}
+
+			if !yes && !confirm(fmt.Sprintf("Delete %d price record(s)?", count)) {
+				fmt.Println("Aborted.")
+				return nil
+			}
+
+			deleted, err := db.DeletePrices(ctx, filter)
+			if err != nil {
+				return err
+			}
+
+			fmt.Printf("Deleted %d price record(s).\n", deleted)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&provider, "provider", "", "Only delete prices of this provider")
+	cmd.Flags().StringVar(&fromStr, "from", "", "Only delete prices on or after this date (YYYY-MM-DD)")
+	cmd.Flags().StringVar(&toStr, "to", "", "Only delete prices on or before this date (YYYY-MM-DD)")
+	cmd.Flags().StringVar(&zipCode, "zip", "", "Only delete prices of this zip code")
+	cmd.Flags().StringVar(&label, "label", "", "Only delete prices with this label")
+	cmd.Flags().BoolVar(&yes, "yes", false, "Skip the confirmation prompt")
+
+	return cmd
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/andygrunwald/oil-price-scraper/internal/api"
+	"github.com/andygrunwald/oil-price-scraper/internal/api/heizoel24"
+	"github.com/andygrunwald/oil-price-scraper/internal/api/hoyer"
+)
+
+// diffRange is the average price of a provider within a date range.
+type diffRange struct {
+	From    string   `json:"from"`
+	To      string   `json:"to"`
+	Count   int64    `json:"count"`
+	Average *float64 `json:"average"`
+}
+
+// providerDiff compares the average prices of a provider in two date ranges.
+type providerDiff struct {
+	Provider string    `json:"provider"`
+	Base     diffRange `json:"base"`
+	Compare  diffRange `json:"compare"`
+	// Difference is the compared average minus the base average (nil if either is missing).
+	Difference *float64 `json:"difference"`
+	// Percent is Difference relative to the base average in percent.
+	Percent *float64 `json:"percent"`
+}
+
+func diffCmd() *cobra.Command {
+	var fromStr, toStr string
+	var baseFromStr, baseToStr string
+	var providers []string
+	var productType string
+	var format string
+
+	cmd := &cobra.Command{
+		Use:   "diff",
+		Short: "Compare the average prices of two date ranges",
+		Long: `Compares the average price per provider of a date range (--from, --to; the last 30 days by
+default) with a base range (--base-from, --base-to; the range of the same length right before by
+default) and prints the absolute and percentage difference, e.g. to answer "is this month cheaper
+than last month?". Positive differences mean prices went up compared to the base range.`,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			problems := []error{
+				requireFlag("postgres-dsn", cfg.PostgresDSN),
+			}
+			for _, p := range providers {
+				if !isKnownProvider(p) {
+					problems = append(problems, fmt.Errorf("unknown provider: %q", p))
+				}
+			}
+			if format != "table" && format != "json" {
+				problems = append(problems, fmt.Errorf("--format: must be table or json, got %q", format))
+			}
+			return validateConfig(problems...)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if format == "json" {
+				logOutput = os.Stderr
+			}
+			logger := setupLogger()
+
+			today := api.PriceDate(time.Now(), cfg.PriceLocation())
+			to, err := parseDiffDate(toStr, today)
+			if err != nil {
+				return fmt.Errorf("parsing --to date: %w", err)
+			}
+			from, err := parseDiffDate(fromStr, to.AddDate(0, 0, -29))
+			if err != nil {
+				return fmt.Errorf("parsing --from date: %w", err)
+			}
+			if from.After(to) {
+				return fmt.Errorf("--from %s is after --to %s", from.Format("2006-01-02"), to.Format("2006-01-02"))
+			}
+
+			// The base range defaults to the range of the same length right before
+			days := int(to.Sub(from).Hours()/24) + 1
+			baseTo, err := parseDiffDate(baseToStr, from.AddDate(0, 0, -1))
+			if err != nil {
+				return fmt.Errorf("parsing --base-to date: %w", err)
+			}
+			baseFrom, err := parseDiffDate(baseFromStr, baseTo.AddDate(0, 0, -(days-1)))
+			if err != nil {
+				return fmt.Errorf("parsing --base-from date: %w", err)
+			}
+			if baseFrom.After(baseTo) {
+				return fmt.Errorf("--base-from %s is after --base-to %s", baseFrom.Format("2006-01-02"), baseTo.Format("2006-01-02"))
+			}
+
+			if len(providers) == 0 {
+				providers = []string{heizoel24.ProviderName, hoyer.ProviderName}
+			}
+
+			db, err := newDatabase(logger)
+			if err != nil {
+				return fmt.Errorf("connecting to database: %w", err)
+			}
+			defer func() {
+				if err := db.Close(); err != nil {
+					panic(err)
+				}
+			}()
+
+			ctx := context.Background()
+			averageOf := func(provider string, from, to time.Time) (diffRange, error) {
+				r := diffRange{From: from.Format("2006-01-02"), To: to.Format("2006-01-02")}
+				agg, err := db.GetPriceAggregate(ctx, provider, productType, from, to, "")
+				if err != nil {
+					return r, err
+				}
+				if agg != nil {
+					r.Count = agg.Count
+					r.Average = &agg.Average
+				}
+				return r, nil
+			}
+
+			diffs := []providerDiff{}
+			for _, provider := range providers {
+				base, err := averageOf(provider, baseFrom, baseTo)
+				if err != nil {
+					return fmt.Errorf("getting base prices of %s: %w", provider, err)
+				}
+				compare, err := averageOf(provider, from, to)
+				if err != nil {
+					return fmt.Errorf("getting prices of %s: %w", provider, err)
+				}
+				if base.Average == nil && compare.Average == nil {
+					logger.Debug().Str("provider", provider).Msg("no prices stored in either range, skipping")
+					continue
+				}
+
+				d := providerDiff{Provider: provider, Base: base, Compare: compare}
+				if base.Average != nil && compare.Average != nil {
+					difference := roundCents(*compare.Average - *base.Average)
+					d.Difference = &difference
+					if *base.Average != 0 {
+						percent := roundCents((*compare.Average - *base.Average) / *base.Average * 100)
+						d.Percent = &percent
+					}
+				}
+				diffs = append(diffs, d)
+			}
+
+			if format == "json" {
+				enc := json.NewEncoder(os.Stdout)
+				enc.SetIndent("", "  ")
+				if err := enc.Encode(diffs); err != nil {
+					return fmt.Errorf("encoding diff: %w", err)
+				}
+				return nil
+			}
+
+			if len(diffs) == 0 {
+				fmt.Println("No prices stored in either range.")
+				return nil
+			}
+
+			fmt.Printf("%s to %s compared with %s to %s\n\n",
+				from.Format("2006-01-02"), to.Format("2006-01-02"), baseFrom.Format("2006-01-02"), baseTo.Format("2006-01-02"))
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "PROVIDER\tBASE AVERAGE\tAVERAGE\tDIFFERENCE\tPERCENT")
+			for _, d := range diffs {
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n",
+					d.Provider, formatOptional("%.2f", d.Base.Average), formatOptional("%.2f", d.Compare.Average),
+					formatOptional("%+.2f", d.Difference), formatOptional("%+.2f%%", d.Percent))
+			}
+			return w.Flush()
+		},
+	}
+
+	cmd.Flags().StringVar(&fromStr, "from", "", "Start date of the compared range (YYYY-MM-DD, default 30 days up to --to)")
+	cmd.Flags().StringVar(&toStr, "to", "", "End date of the compared range (YYYY-MM-DD, default today)")
+	cmd.Flags().StringVar(&baseFromStr, "base-from", "", "Start date of the base range (YYYY-MM-DD, default the range of the same length before --from)")
+	cmd.Flags().StringVar(&baseToStr, "base-to", "", "End date of the base range (YYYY-MM-DD, default the day before --from)")
+	cmd.Flags().StringSliceVar(&providers, "provider", nil, "Providers to compare (default all)")
+	cmd.Flags().StringVar(&productType, "product-type", "", "Only compare prices of this product type (default all)")
+	cmd.Flags().StringVar(&format, "format", "table", "Output format (table, json)")
+
+	return cmd
+}
+
+// parseDiffDate parses a date in the format YYYY-MM-DD, returning def if value is empty.
+func parseDiffDate(value string, def time.Time) (time.Time, error) {
+	if value == "" {
+		return def, nil
+	}
+	return time.Parse("2006-01-02", value)
+}
+
+// formatOptional formats v with format, or returns "-" if v is nil.
+func formatOptional(format string, v *float64) string {
+	if v == nil {
+		return "-"
+	}
+	return fmt.Sprintf(format, *v)
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/andygrunwald/oil-price-scraper/internal/export"
+)
+
+// influxPushTimeout bounds pushing exported points to an InfluxDB endpoint.
+const influxPushTimeout = 5 * time.Minute
+
+func exportInfluxCmd() *cobra.Command {
+	var provider string
+	var fromStr, toStr string
+	var measurement string
+	var tags string
+	var output string
+	var url string
+	var token string
+
+	cmd := &cobra.Command{
+		Use:   "export-influx",
+		Short: "Export stored prices as InfluxDB line protocol",
+		Long: `Exports the stored prices of a date range as InfluxDB line protocol, one point per price
+(e.g. oil_price,provider=hoyer,scope=local,product_type=bestpreis value=97.81 <timestamp>).
+The points are written to --output (stdout by default) or pushed to an InfluxDB write endpoint
+with --url (e.g. http://influxdb:8086/api/v2/write?org=home&bucket=oil).`,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			problems := []error{
+				requireFlag("postgres-dsn", cfg.PostgresDSN),
+			}
+			if provider != "" && !isKnownProvider(provider) {
+				problems = append(problems, fmt.Errorf("unknown provider: %q", provider))
+			}
+			if _, err := export.ParseTags(tags); err != nil {
+				problems = append(problems, fmt.Errorf("--tags: %w", err))
+			}
+			if url != "" && output != "" {
+				problems = append(problems, fmt.Errorf("--output and --url are mutually exclusive"))
+			}
+			return validateConfig(problems...)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// Keep stdout clean for the exported points
+			logOutput = os.Stderr
+			logger := setupLogger()
+
+			var from time.Time
+			var err error
+			if fromStr != "" {
+				from, err = time.Parse("2006-01-02", fromStr)
+				if err != nil {
+					return fmt.Errorf("parsing --from date: %w", err)
+				}
+			}
+			to := time.Now()
+			if toStr != "" {
+				to, err = time.Parse("2006-01-02", toStr)
+				if err != nil {
+					return fmt.Errorf("parsing --to date: %w", err)
+				}
+			}
+
+			exporter := export.Influx{Measurement: measurement}
+			exporter.Tags, _ = export.ParseTags(tags)
+			if exporter.Tags == nil {
+				exporter.Tags = []string{}
+			}
+
+			db, err := newDatabase(logger)
+			if err != nil {
+				return fmt.Errorf("connecting to database: %w", err)
+			}
+			defer func() {
+				if err := db.Close(); err != nil {
+					panic(err)
+				}
+			}()
+
+			ctx := context.Background()
+			// The prices are written while they are read, so the export is never held in memory
+			var points int
+			writePoints := func(w io.Writer) error {
+				pw := exporter.NewWriter(w)
+				if err := db.EachPriceForDateRange(ctx, provider, from, to, pw.WritePrice); err != nil {
+					return err
+				}
+				points = pw.Points()
+				return pw.Flush()
+			}
+
+			if url != "" {
+				if err := pushInflux(ctx, url, token, writePoints); err != nil {
+					return err
+				}
+				logger.Info().Int("points", points).Str("url", url).Msg("pushed prices to InfluxDB")
+				return nil
+			}
+
+			if output == "" || output == "-" {
+				if err := writePoints(os.Stdout); err != nil {
+					return fmt.Errorf("writing points: %w", err)
+				}
+			} else {
+				f, err := os.Create(output)
+				if err != nil {
+					return fmt.Errorf("creating output file: %w", err)
+				}
+				err = writePoints(f)
+				// Closing reports write errors of the file, too
+				if closeErr := f.Close(); err == nil {
+					err = closeErr
+				}
+				if err != nil {
+					return fmt.Errorf("writing points: %w", err)
+				}
+			}
+			logger.Info().Int("points", points).Msg("exported prices")
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&provider, "provider", "", "Provider to export (default all)")
+	cmd.Flags().StringVar(&fromStr, "from", "", "Start date (YYYY-MM-DD, default the first stored price)")
+	cmd.Flags().StringVar(&toStr, "to", "", "End date (YYYY-MM-DD, default today)")
+	cmd.Flags().StringVar(&measurement, "measurement", export.DefaultMeasurement, "Measurement name of the points")
+	cmd.Flags().StringVar(&tags, "tags", "provider,scope,product_type,zip_code,region", "Comma-separated tags of the points (provider, scope, product_type, zip_code, region, label)")
+	cmd.Flags().StringVar(&output, "output", "", "File the points are written to (default stdout)")
+	cmd.Flags().StringVar(&url, "url", "", "InfluxDB write endpoint the points are pushed to instead (e.g. http://influxdb:8086/api/v2/write?org=home&bucket=oil)")
+	cmd.Flags().StringVar(&token, "token", os.Getenv("INFLUX_TOKEN"), "InfluxDB API token sent as Authorization: Token (prefer the INFLUX_TOKEN environment variable)")
+
+	return cmd
+}
+
+// pushInflux streams the points written by writePoints as line protocol to an InfluxDB write endpoint.
+// The timestamps are in nanoseconds, the default precision of InfluxDB.
+func pushInflux(ctx context.Context, url, token string, writePoints func(io.Writer) error) error {
+	ctx, cancel := context.WithTimeout(ctx, influxPushTimeout)
+	defer cancel()
+
+	body, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(writePoints(pw))
+	}()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, body)
+	if err != nil {
+		_ = body.Close()
+		return fmt.Errorf("creating request: %w", err)
+	}
+	req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	if token != "" {
+		req.Header.Set("Authorization", "Token "+token)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("pushing points: %w", err)
+	}
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			panic(err)
+		}
+	}()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("pushing points: unexpected status %d: %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/rs/zerolog"
+	"github.com/spf13/cobra"
+
+	"github.com/andygrunwald/oil-price-scraper/internal/database"
+	"github.com/andygrunwald/oil-price-scraper/migrations"
+)
+
+func migrateCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "migrate",
+		Short: "Apply or revert database schema migrations",
+		Long: `Applies or reverts the versioned schema migrations embedded in the binary.
+Applied migrations are recorded in the schema_migrations table.`,
+	}
+
+	cmd.AddCommand(migrateUpCmd())
+	cmd.AddCommand(migrateDownCmd())
+	cmd.AddCommand(migrateStatusCmd())
+
+	return cmd
+}
+
+func migrateUpCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "up",
+		Short: "Apply all pending migrations",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return withMigrations(func(ctx context.Context, db *database.DB, all []database.Migration) error {
+				applied, err := db.MigrateUp(ctx, all)
+				if err != nil {
+					return err
+				}
+				if len(applied) == 0 {
+					fmt.Println("Schema is up to date.")
+					return nil
+				}
+				for _, m := range applied {
+					fmt.Printf("Applied %s\n", m.Name)
+				}
+				return nil
+			})
+		},
+	}
+}
+
+func migrateDownCmd() *cobra.Command {
+	var steps int
+	var yes bool
+
+	cmd := &cobra.Command{
+		Use:   "down",
+		Short: "Revert the latest applied migrations",
+		Long: `Reverts the latest applied migrations, newest first. Reverting drops columns
+and tables including their data, e.g. reverting the initial schema drops all prices.`,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			if steps < 1 {
+				return validateConfig(fmt.Errorf("--steps: must be at least 1, got %d", steps))
+			}
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return withMigrations(func(ctx context.Context, db *database.DB, all []database.Migration) error {
+				if !yes && !confirm(fmt.Sprintf("Revert the latest %d migration(s)? Dropped columns and tables lose their data.", steps)) {
+					fmt.Println("Aborted.")
+					return nil
+				}
+
+				reverted, err := db.MigrateDown(ctx, all, steps)
+				for _, m := range reverted {
+					fmt.Printf("Reverted %s\n", m.Name)
+				}
+				if err != nil {
+					return err
+				}
+				if len(reverted) == 0 {
+					fmt.Println("No applied migrations to revert.")
+				}
+				return nil
+			})
+		},
+	}
+
+	cmd.Flags().IntVar(&steps, "steps", 1, "Number of migrations to revert")
+	cmd.Flags().BoolVar(&yes, "yes", false, "Skip the confirmation prompt")
+
+	return cmd
+}
+
+func migrateStatusCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "status",
+		Short: "Show applied and pending migrations",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return withMigrations(func(ctx context.Context, db *database.DB, all []database.Migration) error {
+				applied, err := db.AppliedMigrations(ctx)
+				if err != nil {
+					return err
+				}
+				appliedAt := make(map[int]string, len(applied))
+				for _, m := range applied {
+					appliedAt[m.Version] = m.AppliedAt.Format("2006-01-02 15:04:05")
+				}
+
+				for _, m := range all {
+					status, ok := appliedAt[m.Version]
+					if !ok {
+						status = "pending"
+					}
+					fmt.Printf("%-40s %s\n", m.Name, status)
+				}
+				return nil
+			})
+		},
+	}
+}
+
+// withMigrations connects to the database without verifying its schema
+// and calls fn with the embedded migrations.
+func withMigrations(fn func(ctx context.Context, db *database.DB, all []database.Migration) error) error {
+	logger := setupLogger()
+
+	if cfg.PostgresDSN == "" {
+		return fmt.Errorf("--postgres-dsn is required")
+	}
+
+	all, err := database.LoadMigrations(migrations.FS)
+	if err != nil {
+		return err
+	}
+
+	db, err := database.NewTenant(cfg.PostgresDSN, cfg.Tenant, logger)
+	if err != nil {
+		return fmt.Errorf("connecting to database: %w", err)
+	}
+	defer func() {
+		if err := db.Close(); err != nil {
+			panic(err)
+		}
+	}()
+
+	return fn(context.Background(), db, all)
+}
+
+// autoMigrate applies pending migrations if --auto-migrate is set.
+func autoMigrate(db *database.DB, logger zerolog.Logger) error {
+	if !cfg.AutoMigrate {
+		return nil
+	}
+
+	all, err := database.LoadMigrations(migrations.FS)
+	if err != nil {
+		return err
+	}
+	applied, err := db.MigrateUp(context.Background(), all)
+	if err != nil {
+		return err
+	}
+	logger.Info().Int("applied", len(applied)).Msg("database schema migrated")
+	return nil
+}
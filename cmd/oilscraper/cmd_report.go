@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/andygrunwald/oil-price-scraper/internal/models"
+)
+
+// qualityReport is the data-quality report of the stored prices.
+type qualityReport struct {
+	From             string                   `json:"from"`
+	To               string                   `json:"to"`
+	AnomalyThreshold float64                  `json:"anomaly_threshold_percent"`
+	Providers        []models.ProviderQuality `json:"providers"`
+}
+
+func reportCmd() *cobra.Command {
+	var days int
+	var anomalyThreshold float64
+	var format string
+
+	cmd := &cobra.Command{
+		Use:   "report",
+		Short: "Print a data-quality report of the stored prices",
+		Long: `Prints a data-quality report of the prices stored within the last days (90 by default),
+per provider: the covered date range, the number of gaps (runs of missing dates) and missing
+days, the number of anomalies (prices that changed by more than --anomaly-threshold percent
+compared to the previous price of the same series), the last update, the average price, and
+the average price trend per day.`,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			problems := []error{
+				requireFlag("postgres-dsn", cfg.PostgresDSN),
+			}
+			if days <= 0 {
+				problems = append(problems, fmt.Errorf("--days: must be positive, got %d", days))
+			}
+			if anomalyThreshold <= 0 {
+				problems = append(problems, fmt.Errorf("--anomaly-threshold: must be positive, got %v", anomalyThreshold))
+			}
+			if format != "text" && format != "json" {
+				problems = append(problems, fmt.Errorf("--format: must be text or json, got %q", format))
+			}
+			return validateConfig(problems...)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if format == "json" {
+				logOutput = os.Stderr
+			}
+			logger := setupLogger()
+
+			db, err := newDatabase(logger)
+			if err != nil {
+				return fmt.Errorf("connecting to database: %w", err)
+			}
+			defer func() {
+				if err := db.Close(); err != nil {
+					panic(err)
+				}
+			}()
+
+			to := time.Now().UTC()
+			from := to.AddDate(0, 0, -days)
+			providers, err := db.GetDataQuality(context.Background(), from, to, anomalyThreshold)
+			if err != nil {
+				return fmt.Errorf("getting data quality: %w", err)
+			}
+
+			report := qualityReport{
+				From:             from.Format("2006-01-02"),
+				To:               to.Format("2006-01-02"),
+				AnomalyThreshold: anomalyThreshold,
+				Providers:        providers,
+			}
+			if report.Providers == nil {
+				report.Providers = []models.ProviderQuality{}
+			}
+
+			if format == "json" {
+				enc := json.NewEncoder(os.Stdout)
+				enc.SetIndent("", "  ")
+				if err := enc.Encode(report); err != nil {
+					return fmt.Errorf("encoding report: %w", err)
+				}
+				return nil
+			}
+
+			fmt.Printf("Data quality from %s to %s\n\n", report.From, report.To)
+			if len(report.Providers) == 0 {
+				fmt.Println("No prices stored.")
+				return nil
+			}
+
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "PROVIDER\tPRICES\tFIRST\tLAST\tDAYS\tGAPS\tMISSING\tANOMALIES\tLAST UPDATE\tAVERAGE\tTREND/DAY")
+			for _, q := range report.Providers {
+				trend := "-"
+				if q.Trend != nil {
+					trend = fmt.Sprintf("%+.4f", *q.Trend)
+				}
+				fmt.Fprintf(w, "%s\t%d\t%s\t%s\t%d\t%d\t%d\t%d\t%s (%s ago)\t%.2f\t%s\n",
+					q.Provider, q.Prices, q.FirstDate.Format("2006-01-02"), q.LastDate.Format("2006-01-02"),
+					q.DaysCovered, q.Gaps, q.MissingDays, q.Anomalies,
+					q.LastFetchedAt.Format("2006-01-02 15:04"), time.Since(q.LastFetchedAt).Round(time.Minute),
+					q.AveragePrice, trend)
+			}
+			return w.Flush()
+		},
+	}
+
+	cmd.Flags().IntVar(&days, "days", 90, "Number of days covered by the report")
+	cmd.Flags().Float64Var(&anomalyThreshold, "anomaly-threshold", 10, "Change in percent to the previous price of a series counted as anomaly")
+	cmd.Flags().StringVar(&format, "format", "text", "Output format (text, json)")
+
+	return cmd
+}
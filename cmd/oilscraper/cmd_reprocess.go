@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+func reprocessCmd() *cobra.Command {
+	var fromStr, toStr string
+	var provider string
+	var dryRun bool
+
+	cmd := &cobra.Command{
+		Use:   "reprocess",
+		Short: "Re-derive prices from stored raw responses",
+		Long: `Re-parses stored raw API responses with the current parsing logic and updates
+the stored prices. Useful after parsing bugs are fixed, without calling the APIs again.
+Requires that raw responses were stored (--store-raw-response).`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			logger := setupLogger()
+
+			if cfg.PostgresDSN == "" {
+				return fmt.Errorf("--postgres-dsn is required")
+			}
+
+			if fromStr == "" {
+				return fmt.Errorf("--from is required")
+			}
+
+			from, err := time.Parse("2006-01-02", fromStr)
+			if err != nil {
+				return fmt.Errorf("parsing --from date: %w", err)
+			}
+
+			to := time.Now()
+			if toStr != "" {
+				to, err = time.Parse("2006-01-02", toStr)
+				if err != nil {
+					return fmt.Errorf("parsing --to date: %w", err)
+				}
+			}
+
+			// Connect to database
+			db, err := newDatabase(logger)
+			if err != nil {
+				return fmt.Errorf("connecting to database: %w", err)
+			}
+			defer func() {
+				if err := db.Close(); err != nil {
+					panic(err)
+				}
+			}()
+
+			// Create scraper and register provider
+			s, err := newScraper(db, logger)
+			if err != nil {
+				return err
+			}
+			p, ok, err := newProvider(provider, cfg.ZipCode, nil, logger)
+			if err != nil {
+				return err
+			}
+			if !ok {
+				return fmt.Errorf("unknown provider: %q", provider)
+			}
+			s.RegisterProvider(p)
+
+			result, err := s.Reprocess(context.Background(), provider, from, to, dryRun)
+			if err != nil {
+				return fmt.Errorf("reprocessing: %w", err)
+			}
+
+			logger.Info().
+				Str("provider", provider).
+				Int("processed", result.Processed).
+				Int("updated", result.Updated).
+				Int("unchanged", result.Unchanged).
+				Int("unmatched", result.Unmatched).
+				Int("failed", result.Failed).
+				Bool("dryRun", dryRun).
+				Msg("reprocess completed")
+
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&fromStr, "from", "", "Start date (YYYY-MM-DD, required)")
+	cmd.Flags().StringVar(&toStr, "to", "", "End date (YYYY-MM-DD, defaults to today)")
+	cmd.Flags().StringVar(&provider, "provider", "heizoel24", "Provider to reprocess")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Only log changes without updating the database")
+
+	return cmd
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+func retryFailedCmd() *cobra.Command {
+	var provider string
+	var dryRun bool
+
+	cmd := &cobra.Command{
+		Use:   "retry-failed",
+		Short: "Store prices kept in the dead-letter table",
+		Long: `Retries the inserts of prices kept in the failed_inserts table (see --dead-letter).
+Stored prices are removed from the table. Prices that were stored in the meantime (e.g., by a
+later scrape) are removed without writing them again, so running it repeatedly is safe.
+Prices failing again stay in the table with their new error.`,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			problems := []error{
+				requireFlag("postgres-dsn", cfg.PostgresDSN),
+			}
+			if provider != "" && !isKnownProvider(provider) {
+				problems = append(problems, fmt.Errorf("unknown provider: %q", provider))
+			}
+			return validateConfig(problems...)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			logger := setupLogger()
+
+			db, err := newDatabase(logger)
+			if err != nil {
+				return fmt.Errorf("connecting to database: %w", err)
+			}
+			defer func() {
+				if err := db.Close(); err != nil {
+					panic(err)
+				}
+			}()
+
+			ctx := context.Background()
+			failedInserts, err := db.GetFailedInserts(ctx, provider)
+			if err != nil {
+				return fmt.Errorf("getting failed inserts: %w", err)
+			}
+
+			var stored, resolved, failed int
+			for _, f := range failedInserts {
+				price := f.Price
+				log := logger.With().
+					Int64("id", f.ID).
+					Str("provider", price.Provider).
+					Str("product_type", price.ProductType).
+					Str("date", price.Date.Format("2006-01-02")).
+					Int("attempts", f.Attempts).
+					Logger()
+
+				if dryRun {
+					log.Info().Str("error", f.Error).Time("failedAt", f.FailedAt).Msg("failed insert")
+					continue
+				}
+
+				exists, err := db.ExistsForDate(ctx, price.Provider, price.ProductType, price.Date, price.ZipCode, price.Region)
+				if err != nil {
+					return fmt.Errorf("checking existence: %w", err)
+				}
+				if exists {
+					if err := db.DeleteFailedInsert(ctx, f.ID); err != nil {
+						return err
+					}
+					log.Info().Msg("price already stored, removed from dead-letter table")
+					resolved++
+					continue
+				}
+
+				if err := db.InsertPrice(ctx, price, cfg.StoreRawResponse, cfg.StoreResponseHeaders); err != nil {
+					log.Error().Err(err).Msg("failed to insert price")
+					if err := db.RecordFailedRetry(ctx, f.ID, err); err != nil {
+						return err
+					}
+					failed++
+					continue
+				}
+				if err := db.DeleteFailedInsert(ctx, f.ID); err != nil {
+					return err
+				}
+				log.Info().Msg("stored price")
+				stored++
+			}
+
+			logger.Info().
+				Int("total", len(failedInserts)).
+				Int("stored", stored).
+				Int("alreadyStored", resolved).
+				Int("failed", failed).
+				Bool("dryRun", dryRun).
+				Msg("retry of failed inserts completed")
+
+			if failed > 0 {
+				return fmt.Errorf("%d prices failed again and stay in the dead-letter table", failed)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&provider, "provider", "", "Only retry prices of this provider (default all)")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "List the kept prices without retrying them")
+
+	return cmd
+}
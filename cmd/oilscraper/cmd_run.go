@@ -2,17 +2,18 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"maps"
 	"os"
 	"os/signal"
-	"strings"
+	"slices"
 	"syscall"
 	"time"
 
+	"github.com/rs/zerolog"
 	"github.com/spf13/cobra"
 
-	"github.com/andygrunwald/oil-price-scraper/internal/api/heizoel24"
-	"github.com/andygrunwald/oil-price-scraper/internal/api/hoyer"
 	"github.com/andygrunwald/oil-price-scraper/internal/database"
 	"github.com/andygrunwald/oil-price-scraper/internal/http"
 	"github.com/andygrunwald/oil-price-scraper/internal/scheduler"
@@ -21,28 +22,61 @@ import (
 
 func runCmd() *cobra.Command {
 	var scrapeHour int
+	var providerScrapeHours map[string]int
 	var providers string
+	var scrapeCacheTTL time.Duration
+	var minWriteInterval time.Duration
+	var retry scheduler.RetryConfig
+	var oneCycle bool
+	var noInitialScrape bool
+	var retentionDays int
+	var scrapeJitter time.Duration
+	var enableUI bool
+	var reloadFile string
 
 	cmd := &cobra.Command{
 		Use:   "run",
 		Short: "Start the continuous scraper service",
-		Long:  "Starts the oil price scraper with an internal scheduler that runs daily at the specified hour.",
-		RunE: func(cmd *cobra.Command, args []string) error {
-			logger := setupLogger()
-
-			if cfg.PostgresDSN == "" {
-				return fmt.Errorf("--postgres-dsn is required")
+		Long: `Starts the oil price scraper with an internal scheduler that runs daily at the specified hour.
+On SIGHUP, the log level and the schedule are reloaded from --reload-file without a restart.`,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			problems := []error{
+				requireFlag("postgres-dsn", cfg.PostgresDSN),
+				requireFlag("zip-code", cfg.ZipCode),
+				checkProviders("providers", providers),
 			}
-
-			if cfg.ZipCode == "" {
-				return fmt.Errorf("--zip-code is required")
+			if scrapeHour < 0 || scrapeHour > 23 {
+				problems = append(problems, fmt.Errorf("--scrape-hour: must be between 0 and 23, got %d", scrapeHour))
+			}
+			for name, hour := range providerScrapeHours {
+				if hour < 0 || hour > 23 {
+					problems = append(problems, fmt.Errorf("--provider-scrape-hours: hour for %s must be between 0 and 23", name))
+				}
+			}
+			if scrapeJitter < 0 || scrapeJitter >= time.Hour {
+				problems = append(problems, fmt.Errorf("--scrape-jitter: must be between 0 and 1h (exclusive), got %s", scrapeJitter))
+			}
+			if retry.CycleAttempts < 0 {
+				problems = append(problems, fmt.Errorf("--cycle-retries: must not be negative, got %d", retry.CycleAttempts))
+			}
+			if retentionDays < 0 {
+				problems = append(problems, fmt.Errorf("--retention-days: must not be negative, got %d", retentionDays))
 			}
+			return validateConfig(problems...)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			logger := setupLogger()
 
 			// Parse providers
-			providerList := strings.Split(providers, ",")
-			for i := range providerList {
-				providerList[i] = strings.TrimSpace(providerList[i])
+			providerList := parseProviderList(providers)
+
+			// Every tenant gets its own database schema, scraper, and scheduler. Without
+			// --tenants, the only dataset is the one of --tenant (the default schema if empty).
+			datasets := map[string]string{cfg.Tenant: cfg.ZipCode}
+			if len(cfg.Tenants) > 0 {
+				datasets = cfg.Tenants
 			}
+			tenants := slices.Sorted(maps.Keys(datasets))
 
 			logger.Info().
 				Str("version", Version).
@@ -50,44 +84,127 @@ func runCmd() *cobra.Command {
 				Str("buildDate", BuildDate).
 				Str("httpAddr", cfg.HTTPAddr).
 				Int("scrapeHour", scrapeHour).
+				Interface("providerScrapeHours", providerScrapeHours).
+				Dur("scrapeJitter", scrapeJitter).
 				Strs("providers", providerList).
+				Strs("tenants", tenants).
 				Msg("starting oil price scraper")
 
-			// Connect to database
-			db, err := database.New(cfg.PostgresDSN, logger)
-			if err != nil {
-				return fmt.Errorf("connecting to database: %w", err)
+			// newService connects to the database of a tenant and creates its scraper and scheduler
+			newService := func(tenant, zipCode string) (*runService, error) {
+				logger := logger
+				if tenant != "" {
+					logger = logger.With().Str("tenant", tenant).Logger()
+				}
+
+				// Connect to database
+				db, err := newTenantDatabase(tenant, logger)
+				if err != nil {
+					return nil, fmt.Errorf("connecting to database: %w", err)
+				}
+				closeDB := func() {
+					if err := db.Close(); err != nil {
+						panic(err)
+					}
+				}
+
+				// Create scraper
+				s, err := newScraper(db, logger)
+				if err != nil {
+					closeDB()
+					return nil, err
+				}
+				s.SetCacheTTL(scrapeCacheTTL)
+				s.SetMinWriteInterval(minWriteInterval)
+
+				// Register providers
+				if err := registerProviders(s, providerList, zipCode, validatorStore(s, db, logger), logger); err != nil {
+					closeDB()
+					return nil, err
+				}
+				flushCounters := setupCounters(s, db, logger)
+				setupMetadata(s, db, logger)
+
+				// Create scheduler
+				sched := scheduler.New(s, scrapeHour, providerScrapeHours, logger)
+				sched.SetRetry(retry)
+				sched.SetJitter(scrapeJitter)
+				sched.SetInitialScrape(!noInitialScrape)
+				if retentionDays > 0 {
+					logger.Info().Int("retentionDays", retentionDays).Msg("trimming prices outside of the retention window after every scrape cycle")
+					sched.SetRetention(db, retentionDays)
+				}
+
+				return &runService{
+					tenant:    tenant,
+					db:        db,
+					scraper:   s,
+					scheduler: sched,
+					close: func() {
+						flushCounters()
+						closeDB()
+					},
+				}, nil
 			}
+
+			services := make([]*runService, 0, len(tenants))
 			defer func() {
-				if err := db.Close(); err != nil {
-					panic(err)
+				for _, svc := range services {
+					svc.close()
 				}
 			}()
+			for _, tenant := range tenants {
+				svc, err := newService(tenant, datasets[tenant])
+				if err != nil {
+					return err
+				}
+				services = append(services, svc)
+			}
 
-			// Create scraper
-			s := scraper.New(db, cfg.StoreRawResponse, logger)
+			// Create HTTP server. An empty address disables it. The address is bound
+			// right away, so an address in use fails fast before scraping starts.
+			var httpServer *http.Server
+			if cfg.HTTPAddr != "" {
+				if len(cfg.Tenants) > 0 {
+					httpServer = http.NewTenantServer(cfg.HTTPAddr, logger)
+					for _, svc := range services {
+						httpServer.AddTenant(svc.tenant, svc.scraper, svc.scheduler, svc.db)
+					}
+				} else {
+					httpServer = http.NewServer(cfg.HTTPAddr, services[0].scraper, services[0].scheduler, services[0].db, logger)
+				}
+				httpServer.SetVersion(Version)
+				if cfg.APIToken != "" {
+					httpServer.EnableRawResponses(cfg.APIToken, cfg.StoreRawResponse)
+					httpServer.EnableScrape(cfg.APIToken)
+				}
+				if enableUI {
+					httpServer.EnableUI()
+				}
+				if err := httpServer.Listen(); err != nil {
+					return fmt.Errorf("starting HTTP server (choose another --http-addr, e.g. :0 for a random free port, or \"\" to disable it): %w", err)
+				}
+			} else {
+				logger.Info().Msg("HTTP server disabled")
+			}
 
-			// Register providers
-			for _, p := range providerList {
-				switch p {
-				case "heizoel24":
-					s.RegisterProvider(heizoel24.New(logger))
-				case "hoyer":
-					s.RegisterProvider(hoyer.New(logger, cfg.ZipCode, cfg.OrderAmount))
+			// Wire Prometheus metrics to scrapers and schedulers. Metrics of tenants carry a tenant label.
+			schedulers := make([]*scheduler.Scheduler, 0, len(services))
+			for _, svc := range services {
+				var metrics *http.Metrics
+				switch {
+				case len(cfg.Tenants) > 0:
+					metrics = http.NewTenantMetrics(svc.tenant)
+				case httpServer != nil:
+					metrics = httpServer.Metrics()
 				default:
-					logger.Warn().Str("provider", p).Msg("unknown provider, skipping")
+					metrics = http.NewMetrics()
 				}
+				svc.scraper.SetPrometheusMetrics(metrics)
+				svc.scheduler.SetMetrics(metrics)
+				schedulers = append(schedulers, svc.scheduler)
 			}
 
-			// Create scheduler
-			sched := scheduler.New(s, scrapeHour, logger)
-
-			// Create HTTP server
-			httpServer := http.NewServer(cfg.HTTPAddr, s, sched, db, logger)
-
-			// Wire Prometheus metrics to scraper
-			s.SetPrometheusMetrics(httpServer.Metrics())
-
 			// Setup signal handling
 			ctx, cancel := context.WithCancel(context.Background())
 			defer cancel()
@@ -95,22 +212,44 @@ func runCmd() *cobra.Command {
 			sigCh := make(chan os.Signal, 1)
 			signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
 
-			// Start HTTP server in goroutine
-			go func() {
-				if err := httpServer.Start(); err != nil {
-					logger.Error().Err(err).Msg("HTTP server error")
-					cancel()
-				}
-			}()
+			// Start HTTP server in goroutine. Errors while serving do not stop scraping.
+			if httpServer != nil {
+				go func() {
+					if err := httpServer.Start(); err != nil {
+						logger.Error().Err(err).Msg("HTTP server error, continuing without HTTP server")
+					}
+				}()
+			}
 
-			// Start scheduler in goroutine
+			if oneCycle {
+				return runOneCycle(ctx, cancel, sigCh, services, httpServer, logger)
+			}
+
+			// Reload the log level and the schedule on SIGHUP
+			hupCh := make(chan os.Signal, 1)
+			signal.Notify(hupCh, syscall.SIGHUP)
+			defer signal.Stop(hupCh)
 			go func() {
-				if err := sched.Start(ctx); err != nil && err != context.Canceled {
-					logger.Error().Err(err).Msg("scheduler error")
-					cancel()
+				for {
+					select {
+					case <-hupCh:
+						reloadRun(reloadFile, schedulers, &scrapeHour, &providerScrapeHours, &scrapeJitter, logger)
+					case <-ctx.Done():
+						return
+					}
 				}
 			}()
 
+			// Start schedulers in goroutines
+			for _, sched := range schedulers {
+				go func() {
+					if err := sched.Start(ctx); err != nil && err != context.Canceled {
+						logger.Error().Err(err).Msg("scheduler error")
+						cancel()
+					}
+				}()
+			}
+
 			// Wait for signal
 			select {
 			case sig := <-sigCh:
@@ -122,8 +261,10 @@ func runCmd() *cobra.Command {
 			shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 30*time.Second)
 			defer shutdownCancel()
 
-			if err := httpServer.Shutdown(shutdownCtx); err != nil {
-				logger.Error().Err(err).Msg("HTTP server shutdown error")
+			if httpServer != nil {
+				if err := httpServer.Shutdown(shutdownCtx); err != nil {
+					logger.Error().Err(err).Msg("HTTP server shutdown error")
+				}
 			}
 
 			logger.Info().Msg("shutdown complete")
@@ -132,7 +273,124 @@ func runCmd() *cobra.Command {
 	}
 
 	cmd.Flags().IntVar(&scrapeHour, "scrape-hour", 6, "Hour of day (0-23) to scrape")
-	cmd.Flags().StringVar(&providers, "providers", "heizoel24,hoyer", "Comma-separated list of providers")
+	cmd.Flags().DurationVar(&scrapeJitter, "scrape-jitter", 0, "Delay scheduled scrapes by a random offset within this window after the scrape hour (e.g. 30m, 0 scrapes exactly at the hour)")
+	cmd.Flags().StringToIntVar(&providerScrapeHours, "provider-scrape-hours", nil, "Per-provider scrape hour overriding --scrape-hour (e.g. heizoel24=6,hoyer=14)")
+	cmd.Flags().StringVar(&providers, "providers", "heizoel24,hoyer", "Comma-separated list of providers or @groups")
+	cmd.Flags().DurationVar(&minWriteInterval, "min-write-interval", 0, "Skip database writes of unchanged prices of the same series within this duration (0 disables)")
+	cmd.Flags().DurationVar(&scrapeCacheTTL, "scrape-cache-ttl", 5*time.Minute, "Reuse fetched prices for manually triggered scrapes within this duration (0 disables)")
+	cmd.Flags().IntVar(&retry.Attempts, "scrape-retries", 0, "Retries of a failed provider within a scheduled scrape (0 disables)")
+	cmd.Flags().StringToIntVar(&retry.ProviderAttempts, "provider-scrape-retries", nil, "Per-provider retries overriding --scrape-retries (e.g. heizoel24=3,hoyer=1)")
+	cmd.Flags().BoolVar(&oneCycle, "one-cycle", false, "Scrape all providers once with the HTTP server up, then exit (e.g., for a Kubernetes CronJob)")
+	cmd.Flags().BoolVar(&noInitialScrape, "no-initial-scrape", false, "Skip the startup scrape of providers not scraped today and wait for the first scheduled scrape")
+	cmd.Flags().IntVar(&retentionDays, "retention-days", 0, "Delete stored prices older than this many days after every scrape cycle (0 keeps all)")
+	cmd.Flags().DurationVar(&retry.Delay, "scrape-retry-delay", 5*time.Minute, "Delay before the first retry, doubling with every further retry")
+	cmd.Flags().IntVar(&retry.CycleAttempts, "cycle-retries", 0, "Retries of a whole scrape cycle in which every provider failed (0 disables)")
+	cmd.Flags().DurationVar(&retry.CycleDelay, "cycle-retry-delay", 10*time.Minute, "Delay before every retry of a whole scrape cycle")
+	cmd.Flags().BoolVar(&enableUI, "enable-ui", false, "Serve a web UI charting the recent prices at /")
+	cmd.Flags().StringVar(&reloadFile, "reload-file", "", "File of KEY=VALUE settings (LOG_LEVEL, SCRAPE_HOUR, PROVIDER_SCRAPE_HOURS, SCRAPE_JITTER) applied on SIGHUP")
 
 	return cmd
 }
+
+// runService is the database, scraper, and scheduler of the dataset of a tenant
+// (or of the only dataset without --tenants) served by the run command.
+type runService struct {
+	tenant    string
+	db        *database.DB
+	scraper   *scraper.Scraper
+	scheduler *scheduler.Scheduler
+	// close writes the final counters and closes the database
+	close func()
+}
+
+// runOneCycle runs a single scrape cycle of every scheduler (including retries) while the
+// HTTP server (if any) is up, pushes the metrics if a Pushgateway is configured, and shuts down.
+// It returns an error if providers failed, so external schedulers can detect failed runs.
+func runOneCycle(ctx context.Context, cancel context.CancelFunc, sigCh <-chan os.Signal, services []*runService, httpServer *http.Server, logger zerolog.Logger) error {
+	go func() {
+		select {
+		case sig := <-sigCh:
+			logger.Info().Str("signal", sig.String()).Msg("received signal, shutting down")
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	var scrapeErrs []error
+	for _, svc := range services {
+		if err := svc.scheduler.RunOnce(ctx); err != nil {
+			if svc.tenant != "" {
+				err = fmt.Errorf("tenant %s: %w", svc.tenant, err)
+			}
+			scrapeErrs = append(scrapeErrs, err)
+		}
+	}
+	scrapeErr := errors.Join(scrapeErrs...)
+
+	if cfg.PushgatewayURL != "" {
+		if err := http.PushMetrics(cfg.PushgatewayURL, cfg.PushJob, "run"); err != nil {
+			logger.Error().Err(err).Msg("failed to push metrics")
+		} else {
+			logger.Info().Str("url", cfg.PushgatewayURL).Str("job", cfg.PushJob).Msg("pushed metrics")
+		}
+	}
+
+	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer shutdownCancel()
+
+	if httpServer != nil {
+		if err := httpServer.Shutdown(shutdownCtx); err != nil {
+			logger.Error().Err(err).Msg("HTTP server shutdown error")
+		}
+	}
+
+	if scrapeErr != nil {
+		return fmt.Errorf("running scrape cycle: %w", scrapeErr)
+	}
+
+	logger.Info().Msg("scrape cycle completed, shutdown complete")
+	return nil
+}
+
+// reloadRun applies the settings of the reload file to the running service: the log
+// level and the schedule of every scheduler. Other settings of the file are logged as
+// ignored, as they need a restart. An invalid file is logged and changes nothing.
+func reloadRun(path string, schedulers []*scheduler.Scheduler, scrapeHour *int, providerHours *map[string]int, jitter *time.Duration, logger zerolog.Logger) {
+	if path == "" {
+		logger.Warn().Msg("received SIGHUP, but no --reload-file is set, nothing to reload")
+		return
+	}
+
+	settings, err := readReloadFile(path)
+	if err != nil {
+		logger.Error().Err(err).Str("file", path).Msg("failed to reload configuration, keeping the current one")
+		return
+	}
+
+	if settings.logLevel != nil {
+		zerolog.SetGlobalLevel(*settings.logLevel)
+		logger.Info().Str("logLevel", settings.logLevel.String()).Msg("reloaded log level")
+	}
+	if settings.hasSchedule() {
+		if settings.scrapeHour != nil {
+			*scrapeHour = *settings.scrapeHour
+		}
+		if settings.providerHours != nil {
+			*providerHours = settings.providerHours
+		}
+		if settings.scrapeJitter != nil {
+			*jitter = *settings.scrapeJitter
+		}
+		for _, sched := range schedulers {
+			sched.Reschedule(*scrapeHour, *providerHours, *jitter)
+		}
+		logger.Info().
+			Int("scrapeHour", *scrapeHour).
+			Interface("providerScrapeHours", *providerHours).
+			Dur("scrapeJitter", *jitter).
+			Msg("reloaded schedule")
+	}
+	if len(settings.ignored) > 0 {
+		logger.Warn().Strs("settings", settings.ignored).Msg("ignored settings that cannot be applied without a restart")
+	}
+}
@@ -0,0 +1,219 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/andygrunwald/oil-price-scraper/internal/api"
+	"github.com/andygrunwald/oil-price-scraper/internal/api/hoyer"
+	"github.com/andygrunwald/oil-price-scraper/internal/models"
+)
+
+// savingsPrice is the price and total cost of an order at a reference price.
+type savingsPrice struct {
+	Name         string  `json:"name"`
+	Days         int     `json:"days,omitempty"`
+	Date         string  `json:"date,omitempty"`
+	PricePer100L float64 `json:"price_per_100l"`
+	Total        float64 `json:"total"`
+	// Difference is the current total minus this total. Positive means ordering now costs more.
+	Difference float64 `json:"difference"`
+}
+
+// savingsReport compares the cost of an order at the current price with historical prices.
+type savingsReport struct {
+	Provider    string         `json:"provider"`
+	ProductType string         `json:"product_type"`
+	ZipCode     string         `json:"zip_code,omitempty"`
+	Amount      int            `json:"amount"`
+	Currency    string         `json:"currency"`
+	Current     savingsPrice   `json:"current"`
+	References  []savingsPrice `json:"references"`
+}
+
+// orderTotal returns the cost of amount liters at a price per 100 liters, rounded to cents.
+func orderTotal(pricePer100L float64, amount int) float64 {
+	return roundCents(pricePer100L * float64(amount) / 100)
+}
+
+// roundCents rounds an amount of money to cents.
+func roundCents(v float64) float64 {
+	return math.Round(v*100) / 100
+}
+
+func savingsCmd() *cobra.Command {
+	var amount int
+	var provider string
+	var productType string
+	var days []int
+	var format string
+
+	cmd := &cobra.Command{
+		Use:   "savings",
+		Short: "Compare the cost of an order now with historical prices",
+		Long: `Compares what an order costs at the latest stored price with the average and minimum
+prices of the last days (30 and 90 by default), scaled from the price per 100 liters to the
+order amount. Positive differences mean ordering now costs more than at the reference price.`,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			problems := []error{
+				requireFlag("postgres-dsn", cfg.PostgresDSN),
+			}
+			if !isKnownProvider(provider) {
+				problems = append(problems, fmt.Errorf("unknown provider: %q", provider))
+			}
+			if amount < 0 {
+				problems = append(problems, fmt.Errorf("--amount: must not be negative (0 uses --order-amount), got %d", amount))
+			}
+			for _, d := range days {
+				if d <= 0 {
+					problems = append(problems, fmt.Errorf("--days: must be positive, got %d", d))
+				}
+			}
+			if format != "table" && format != "json" {
+				problems = append(problems, fmt.Errorf("--format: must be table or json, got %q", format))
+			}
+			return validateConfig(problems...)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if format == "json" {
+				logOutput = os.Stderr
+			}
+			logger := setupLogger()
+
+			if amount == 0 {
+				amount = cfg.OrderAmount
+			}
+
+			db, err := newDatabase(logger)
+			if err != nil {
+				return fmt.Errorf("connecting to database: %w", err)
+			}
+			defer func() {
+				if err := db.Close(); err != nil {
+					panic(err)
+				}
+			}()
+
+			ctx := context.Background()
+
+			// The scope of the provider decides whether prices are compared for a zip code
+			latest, err := db.GetLatestPrice(ctx, provider)
+			if err != nil {
+				return fmt.Errorf("getting latest price: %w", err)
+			}
+			if latest == nil {
+				return fmt.Errorf("no prices stored for %s", provider)
+			}
+
+			// Local prices are compared for the configured zip code only
+			zipCode := ""
+			if latest.Scope == models.PriceScopeLocal {
+				zipCode = cfg.ZipCode
+			}
+
+			// Without a product type, compare the product of the latest price of the zip code
+			current, err := db.GetLatestProductPrice(ctx, provider, productType, zipCode, "")
+			if err != nil {
+				return fmt.Errorf("getting current price: %w", err)
+			}
+			if current == nil {
+				what := "prices"
+				if productType != "" {
+					what = productType + " prices"
+				}
+				if zipCode != "" {
+					return fmt.Errorf("no %s stored for %s and zip code %s", what, provider, zipCode)
+				}
+				return fmt.Errorf("no %s stored for %s", what, provider)
+			}
+			productType = current.ProductType
+
+			report := savingsReport{
+				Provider:    provider,
+				ProductType: productType,
+				ZipCode:     zipCode,
+				Amount:      amount,
+				Currency:    current.Currency,
+				Current: savingsPrice{
+					Name:         "current",
+					Date:         current.PriceDate.Format("2006-01-02"),
+					PricePer100L: current.PricePer100L,
+					Total:        orderTotal(current.PricePer100L, amount),
+				},
+				References: []savingsPrice{},
+			}
+
+			// The windows end today in the timezone prices are dated in
+			to := api.PriceDate(time.Now(), cfg.PriceLocation())
+			for _, d := range days {
+				agg, err := db.GetPriceAggregate(ctx, provider, productType, to.AddDate(0, 0, -d), to, zipCode)
+				if err != nil {
+					return fmt.Errorf("getting %d-day prices: %w", d, err)
+				}
+				if agg == nil {
+					logger.Warn().Int("days", d).Msg("no prices stored within the window, skipping")
+					continue
+				}
+
+				average := orderTotal(agg.Average, amount)
+				minimum := orderTotal(agg.Min, amount)
+				report.References = append(report.References,
+					savingsPrice{
+						Name:         fmt.Sprintf("%d-day average", d),
+						Days:         d,
+						PricePer100L: agg.Average,
+						Total:        average,
+						Difference:   roundCents(report.Current.Total - average),
+					},
+					savingsPrice{
+						Name:         fmt.Sprintf("%d-day minimum", d),
+						Days:         d,
+						Date:         agg.MinDate.Format("2006-01-02"),
+						PricePer100L: agg.Min,
+						Total:        minimum,
+						Difference:   roundCents(report.Current.Total - minimum),
+					},
+				)
+			}
+
+			if format == "json" {
+				enc := json.NewEncoder(os.Stdout)
+				enc.SetIndent("", "  ")
+				if err := enc.Encode(report); err != nil {
+					return fmt.Errorf("encoding savings: %w", err)
+				}
+				return nil
+			}
+
+			fmt.Printf("%d liters of %s (%s)\n\n", report.Amount, report.ProductType, report.Provider)
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "REFERENCE\tDATE\tPRICE/100L\tTOTAL\tNOW VS. REFERENCE")
+			fmt.Fprintf(w, "%s\t%s\t%.2f\t%.2f %s\t-\n",
+				report.Current.Name, report.Current.Date, report.Current.PricePer100L, report.Current.Total, report.Currency)
+			for _, ref := range report.References {
+				date := ref.Date
+				if date == "" {
+					date = "-"
+				}
+				fmt.Fprintf(w, "%s\t%s\t%.2f\t%.2f %s\t%+.2f %s\n",
+					ref.Name, date, ref.PricePer100L, ref.Total, report.Currency, ref.Difference, report.Currency)
+			}
+			return w.Flush()
+		},
+	}
+
+	cmd.Flags().IntVar(&amount, "amount", 0, "Order amount in liters (default --order-amount)")
+	cmd.Flags().StringVar(&provider, "provider", hoyer.ProviderName, "Provider whose prices are compared")
+	cmd.Flags().StringVar(&productType, "product-type", "", "Product type to compare (default the product type of the latest price)")
+	cmd.Flags().IntSliceVar(&days, "days", []int{30, 90}, "Windows in days the current price is compared with")
+	cmd.Flags().StringVar(&format, "format", "table", "Output format (table, json)")
+
+	return cmd
+}
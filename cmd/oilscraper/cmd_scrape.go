@@ -2,83 +2,177 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"os"
 	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
 
-	"github.com/andygrunwald/oil-price-scraper/internal/api/heizoel24"
-	"github.com/andygrunwald/oil-price-scraper/internal/api/hoyer"
 	"github.com/andygrunwald/oil-price-scraper/internal/database"
+	"github.com/andygrunwald/oil-price-scraper/internal/models"
 	"github.com/andygrunwald/oil-price-scraper/internal/scraper"
 )
 
 func scrapeCmd() *cobra.Command {
 	var providers string
+	var output string
+	var dryRun bool
+	var label string
+	var failOnPartial bool
+	var failOnAll bool
+	var dateStr string
 
 	cmd := &cobra.Command{
 		Use:   "scrape",
 		Short: "Run a one-time scrape",
-		Long:  "Runs a one-time scrape from the specified providers. Useful for testing.",
+		Long: `Runs a one-time scrape from the specified providers. Useful for testing.
+Failed providers are logged, but do not change the exit code unless --fail-on-partial
+or --fail-on-all is set (e.g., to detect failed scrapes in cron jobs or CI).
+With --date, exactly that past day is fetched from providers with historical data
+(e.g., to fill a single missing day); it fails for providers that cannot fetch past dates.`,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			problems := []error{
+				requireFlag("zip-code", cfg.ZipCode),
+				checkProviders("providers", providers),
+				checkLabel(label),
+			}
+			if !dryRun {
+				problems = append(problems, requireFlag("postgres-dsn", cfg.PostgresDSN))
+			}
+			if output != "" && output != "json" && output != "table" {
+				problems = append(problems, fmt.Errorf("--output must be json or table"))
+			}
+			if dateStr != "" {
+				if date, err := time.Parse("2006-01-02", dateStr); err != nil {
+					problems = append(problems, fmt.Errorf("--date must be a date (YYYY-MM-DD): %w", err))
+				} else if date.After(time.Now()) {
+					problems = append(problems, fmt.Errorf("--date must not be in the future"))
+				}
+			}
+			return validateConfig(problems...)
+		},
 		RunE: func(cmd *cobra.Command, args []string) error {
-			logger := setupLogger()
 
-			if cfg.PostgresDSN == "" {
-				return fmt.Errorf("--postgres-dsn is required")
+			// Keep stdout clean for the printed prices
+			if output != "" {
+				logOutput = os.Stderr
 			}
 
-			if cfg.ZipCode == "" {
-				return fmt.Errorf("--zip-code is required")
-			}
+			logger := setupLogger()
 
 			// Parse providers
-			providerList := strings.Split(providers, ",")
-			for i := range providerList {
-				providerList[i] = strings.TrimSpace(providerList[i])
-			}
+			providerList := parseProviderList(providers)
 
 			logger.Info().
 				Strs("providers", providerList).
+				Bool("dryRun", dryRun).
 				Msg("running one-time scrape")
 
 			// Connect to database
-			db, err := database.New(cfg.PostgresDSN, logger)
-			if err != nil {
-				return fmt.Errorf("connecting to database: %w", err)
-			}
-			defer func() {
-				if err := db.Close(); err != nil {
-					panic(err)
+			var db *database.DB
+			if !dryRun {
+				var err error
+				db, err = newDatabase(logger)
+				if err != nil {
+					return fmt.Errorf("connecting to database: %w", err)
 				}
-			}()
+				defer func() {
+					if err := db.Close(); err != nil {
+						panic(err)
+					}
+				}()
+			}
 
 			// Create scraper
-			s := scraper.New(db, cfg.StoreRawResponse, logger)
+			s, err := newScraper(db, logger)
+			if err != nil {
+				return err
+			}
+			s.SetDryRun(dryRun)
+			s.SetLabel(label)
+
+			var fetched []models.PriceResult
+			s.SetFetchedHandler(func(providerName string, prices []models.PriceResult) {
+				fetched = append(fetched, prices...)
+			})
 
 			// Register providers
-			for _, p := range providerList {
-				switch p {
-				case "heizoel24":
-					s.RegisterProvider(heizoel24.New(logger))
-				case "hoyer":
-					s.RegisterProvider(hoyer.New(logger, cfg.ZipCode, cfg.OrderAmount))
-				default:
-					logger.Warn().Str("provider", p).Msg("unknown provider, skipping")
-				}
+			if err := registerProviders(s, providerList, cfg.ZipCode, validatorStore(s, db, logger), logger); err != nil {
+				return err
 			}
+			flushCounters := setupCounters(s, db, logger)
+			setupMetadata(s, db, logger)
+			defer flushCounters()
+
+			pushMetrics := setupPushMetrics(s, "scrape", logger)
+			defer pushMetrics()
 
 			// Run scrape
 			ctx := context.Background()
-			if err := s.ScrapeAll(ctx); err != nil {
-				return fmt.Errorf("scraping: %w", err)
+			if dateStr != "" {
+				date, _ := time.Parse("2006-01-02", dateStr)
+				return scrapeDate(ctx, s, providerList, date, output, &fetched)
+			}
+			summary := s.ScrapeAllSummary(ctx)
+
+			logger.Info().
+				Strs("succeeded", summary.Succeeded).
+				Strs("failed", summary.Failed).
+				Msg("scrape completed")
+
+			switch output {
+			case "json":
+				if err := printPricesJSON(fetched); err != nil {
+					return err
+				}
+			case "table":
+				if err := printPricesTable(fetched); err != nil {
+					return err
+				}
 			}
 
-			logger.Info().Msg("scrape completed")
+			if (failOnPartial && summary.AnyFailed()) || (failOnAll && summary.AllFailed()) {
+				return fmt.Errorf("scrape failed for providers: %s", strings.Join(summary.Failed, ", "))
+			}
 			return nil
 		},
 	}
 
-	cmd.Flags().StringVar(&providers, "providers", "heizoel24,hoyer", "Comma-separated list of providers")
+	cmd.Flags().StringVar(&providers, "providers", "heizoel24,hoyer", "Comma-separated list of providers or @groups")
+	cmd.Flags().StringVar(&output, "output", "", "Print the fetched prices to stdout (json, table)")
+	cmd.Flags().StringVar(&label, "label", "", "Free-form label stored with the scraped prices (e.g. source=manual-run)")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Fetch prices without storing them (no database required)")
+	cmd.Flags().BoolVar(&failOnPartial, "fail-on-partial", false, "Exit non-zero if any provider failed")
+	cmd.Flags().BoolVar(&failOnAll, "fail-on-all", false, "Exit non-zero if every provider failed")
+	cmd.Flags().StringVar(&dateStr, "date", "", "Fetch exactly this past day (YYYY-MM-DD) from providers with historical data")
 
 	return cmd
 }
+
+// scrapeDate fetches and stores a single past day from every provider and prints
+// the fetched prices. It fails if a provider failed or cannot fetch past dates.
+func scrapeDate(ctx context.Context, s *scraper.Scraper, providers []string, date time.Time, output string, fetched *[]models.PriceResult) error {
+	var errs []error
+	for _, name := range providers {
+		if !s.HasProvider(name) {
+			continue
+		}
+		if _, err := s.ScrapeDate(ctx, name, date); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", name, err))
+		}
+	}
+
+	switch output {
+	case "json":
+		if err := printPricesJSON(*fetched); err != nil {
+			return err
+		}
+	case "table":
+		if err := printPricesTable(*fetched); err != nil {
+			return err
+		}
+	}
+	return errors.Join(errs...)
+}
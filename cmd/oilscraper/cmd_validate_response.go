@@ -0,0 +1,109 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/andygrunwald/oil-price-scraper/internal/api"
+)
+
+func validateResponseCmd() *cobra.Command {
+	var provider string
+	var file string
+	var output string
+	var region string
+	var fetchedAtStr string
+
+	cmd := &cobra.Command{
+		Use:   "validate-response",
+		Short: "Parse a saved API response with a provider's parser",
+		Long: `Runs the parse step of a provider on a saved API response and prints the resulting prices
+or the parse error. No API is called and no database is required, which makes it a fast
+feedback loop when developing and debugging providers against captured payloads.`,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			problems := []error{
+				requireFlag("file", file),
+			}
+			if !isKnownProvider(provider) {
+				problems = append(problems, fmt.Errorf("unknown provider: %q", provider))
+			}
+			if output != "json" && output != "table" {
+				problems = append(problems, fmt.Errorf("--output must be json or table"))
+			}
+			return validateConfig(problems...)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// Keep stdout clean for the printed prices
+			logOutput = os.Stderr
+			logger := setupLogger()
+
+			fetchedAt := time.Now()
+			if fetchedAtStr != "" {
+				var err error
+				fetchedAt, err = time.Parse("2006-01-02", fetchedAtStr)
+				if err != nil {
+					return fmt.Errorf("parsing --fetched-at date: %w", err)
+				}
+			}
+
+			body, err := os.ReadFile(file)
+			if err != nil {
+				return fmt.Errorf("reading response: %w", err)
+			}
+
+			p, ok, err := newProvider(provider, cfg.ZipCode, nil, logger)
+			if err != nil {
+				return err
+			}
+			if !ok {
+				return fmt.Errorf("unknown provider: %q", provider)
+			}
+			parser, ok := p.(api.ResponseParser)
+			if !ok {
+				return fmt.Errorf("provider %s does not support parsing raw responses", provider)
+			}
+
+			prices, err := parser.ParseRawResponse(body, api.ParseParams{
+				FetchedAt:    fetchedAt,
+				ZipCode:      cfg.ZipCode,
+				Region:       strings.ToUpper(region),
+				DateLocation: cfg.PriceLocation(),
+			})
+			if err != nil {
+				return fmt.Errorf("validating response: %w", err)
+			}
+
+			switch output {
+			case "json":
+				err = printPricesJSON(prices)
+			default:
+				err = printPricesTable(prices)
+			}
+			if err != nil {
+				return err
+			}
+
+			if err := api.CheckResults(p, prices); err != nil {
+				return fmt.Errorf("validating response: %w", err)
+			}
+
+			logger.Info().
+				Str("provider", provider).
+				Int("prices", len(prices)).
+				Msg("response is valid")
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&provider, "provider", "heizoel24", "Provider whose parser is used")
+	cmd.Flags().StringVar(&file, "file", "", "Saved API response (required)")
+	cmd.Flags().StringVar(&output, "output", "table", "Output format (json, table)")
+	cmd.Flags().StringVar(&region, "region", "", "Region the response was fetched for (default DE)")
+	cmd.Flags().StringVar(&fetchedAtStr, "fetched-at", "", "Date the response was fetched (YYYY-MM-DD, defaults to today; sets the price date of current-price providers)")
+
+	return cmd
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+func verifyCmd() *cobra.Command {
+	var fix bool
+	var yes bool
+
+	cmd := &cobra.Command{
+		Use:   "verify",
+		Short: "Verify data integrity",
+		Long: `Verifies the integrity of the stored data by detecting duplicate price records
+(same provider, product type, date, zip code, and region). With --fix, duplicates are
+collapsed, keeping the most recently fetched record of each group.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			logger := setupLogger()
+
+			if cfg.PostgresDSN == "" {
+				return fmt.Errorf("--postgres-dsn is required")
+			}
+
+			// Connect to database
+			db, err := newDatabase(logger)
+			if err != nil {
+				return fmt.Errorf("connecting to database: %w", err)
+			}
+			defer func() {
+				if err := db.Close(); err != nil {
+					panic(err)
+				}
+			}()
+
+			ctx := context.Background()
+			groups, err := db.FindDuplicates(ctx)
+			if err != nil {
+				return fmt.Errorf("finding duplicates: %w", err)
+			}
+
+			if len(groups) == 0 {
+				fmt.Println("No duplicates found.")
+				return nil
+			}
+
+			fmt.Printf("Found %d duplicate group(s):\n", len(groups))
+			for _, g := range groups {
+				zipCode := "-"
+				if g.ZipCode != nil {
+					zipCode = *g.ZipCode
+				}
+				fmt.Printf("  %s  %-10s %-20s zip=%-6s region=%-3s rows=%d distinct_prices=%d min=%.4f max=%.4f\n",
+					g.PriceDate.Format("2006-01-02"), g.Provider, g.ProductType, zipCode, g.Region,
+					g.Count, g.DistinctPrices, g.MinPrice, g.MaxPrice)
+			}
+
+			if !fix {
+				fmt.Println("Run with --fix to collapse duplicates, keeping the latest record.")
+				return fmt.Errorf("found %d duplicate group(s)", len(groups))
+			}
+
+			if !yes && !confirm("Collapse duplicates, keeping the latest record of each group?") {
+				fmt.Println("Aborted.")
+				return nil
+			}
+
+			deleted, err := db.RemoveDuplicates(ctx)
+			if err != nil {
+				return fmt.Errorf("removing duplicates: %w", err)
+			}
+
+			fmt.Printf("Deleted %d duplicate record(s).\n", deleted)
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&fix, "fix", false, "Collapse duplicates, keeping the latest record")
+	cmd.Flags().BoolVar(&yes, "yes", false, "Skip the confirmation prompt")
+
+	return cmd
+}
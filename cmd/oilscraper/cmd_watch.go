@@ -0,0 +1,184 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/andygrunwald/oil-price-scraper/internal/database"
+	"github.com/andygrunwald/oil-price-scraper/internal/models"
+)
+
+func watchCmd() *cobra.Command {
+	var providers string
+	var interval time.Duration
+	var store bool
+
+	cmd := &cobra.Command{
+		Use:   "watch",
+		Short: "Poll and print current prices at an interval",
+		Long: `Scrapes the specified providers at an interval and prints a refreshing table of
+the current prices with the change since the previous poll. Prices are not stored
+unless --store is given. Stop with Ctrl-C.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if interval <= 0 {
+				return fmt.Errorf("--interval must be positive")
+			}
+
+			// Keep stdout clean for the price table
+			logOutput = os.Stderr
+			logger := setupLogger()
+
+			if cfg.PostgresDSN == "" && store {
+				return fmt.Errorf("--postgres-dsn is required with --store")
+			}
+
+			if cfg.ZipCode == "" {
+				return fmt.Errorf("--zip-code is required")
+			}
+
+			providerList := parseProviderList(providers)
+
+			// Connect to database
+			var db *database.DB
+			if store {
+				var err error
+				db, err = newDatabase(logger)
+				if err != nil {
+					return fmt.Errorf("connecting to database: %w", err)
+				}
+				defer func() {
+					if err := db.Close(); err != nil {
+						panic(err)
+					}
+				}()
+			}
+
+			// Create scraper
+			s, err := newScraper(db, logger)
+			if err != nil {
+				return err
+			}
+			s.SetDryRun(!store)
+
+			var fetched []models.PriceResult
+			s.SetFetchedHandler(func(providerName string, prices []models.PriceResult) {
+				fetched = append(fetched, prices...)
+			})
+
+			// Register providers
+			if err := registerProviders(s, providerList, cfg.ZipCode, validatorStore(s, db, logger), logger); err != nil {
+				return err
+			}
+			flushCounters := setupCounters(s, db, logger)
+			setupMetadata(s, db, logger)
+			defer flushCounters()
+
+			// Setup signal handling
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			sigCh := make(chan os.Signal, 1)
+			signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
+			defer signal.Stop(sigCh)
+
+			go func() {
+				select {
+				case <-sigCh:
+					cancel()
+				case <-ctx.Done():
+				}
+			}()
+
+			ticker := time.NewTicker(interval)
+			defer ticker.Stop()
+
+			previous := make(map[string]float64)
+			for {
+				fetched = nil
+				if err := s.ScrapeAll(ctx); err != nil {
+					return fmt.Errorf("scraping: %w", err)
+				}
+				if ctx.Err() != nil {
+					return nil
+				}
+
+				current := latestPrices(fetched)
+				if err := printWatchTable(current, previous, interval); err != nil {
+					return err
+				}
+				for _, p := range current {
+					previous[priceSeriesKey(p)] = p.PricePer100L
+				}
+
+				select {
+				case <-ctx.Done():
+					return nil
+				case <-ticker.C:
+				}
+			}
+		},
+	}
+
+	cmd.Flags().StringVar(&providers, "providers", "heizoel24,hoyer", "Comma-separated list of providers or @groups")
+	cmd.Flags().DurationVar(&interval, "interval", 60*time.Second, "Interval between polls")
+	cmd.Flags().BoolVar(&store, "store", false, "Store fetched prices in the database")
+
+	return cmd
+}
+
+// priceSeriesKey returns the key identifying the series of a price.
+func priceSeriesKey(p models.PriceResult) string {
+	return p.Provider + "|" + p.ProductType + "|" + p.ZipCode + "|" + p.Region
+}
+
+// latestPrices returns the price with the latest date of every series.
+func latestPrices(prices []models.PriceResult) []models.PriceResult {
+	latest := make(map[string]models.PriceResult)
+	for _, p := range prices {
+		key := priceSeriesKey(p)
+		if l, ok := latest[key]; !ok || p.Date.After(l.Date) {
+			latest[key] = p
+		}
+	}
+
+	result := make([]models.PriceResult, 0, len(latest))
+	for _, p := range latest {
+		result = append(result, p)
+	}
+	sortPrices(result)
+	return result
+}
+
+// printWatchTable prints the current prices with the change since the previous poll.
+// On a terminal, the screen is cleared first so the table refreshes in place.
+func printWatchTable(prices []models.PriceResult, previous map[string]float64, interval time.Duration) error {
+	if isTerminal(os.Stdout) {
+		fmt.Print("\033[H\033[2J")
+	}
+	fmt.Printf("Updated %s (every %s, Ctrl-C to stop)\n\n", time.Now().Format("15:04:05"), interval)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "PROVIDER\tPRODUCT\tDATE\tPRICE/100L\tDELTA\tREGION\tZIP")
+	for _, p := range prices {
+		zipCode := p.ZipCode
+		if zipCode == "" {
+			zipCode = "-"
+		}
+
+		delta := "-"
+		if prev, ok := previous[priceSeriesKey(p)]; ok {
+			delta = fmt.Sprintf("%+.2f", p.PricePer100L-prev)
+		}
+
+		fmt.Fprintf(w, "%s\t%s\t%s\t%.2f\t%s\t%s\t%s\n",
+			p.Provider, p.ProductType, p.Date.Format("2006-01-02"), p.PricePer100L, delta, p.Region, zipCode)
+	}
+	return w.Flush()
+}
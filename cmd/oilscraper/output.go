@@ -0,0 +1,60 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/andygrunwald/oil-price-scraper/internal/models"
+)
+
+// sortPrices sorts prices by provider, product type, zip code, and date.
+func sortPrices(prices []models.PriceResult) {
+	sort.SliceStable(prices, func(i, j int) bool {
+		a, b := prices[i], prices[j]
+		if a.Provider != b.Provider {
+			return a.Provider < b.Provider
+		}
+		if a.ProductType != b.ProductType {
+			return a.ProductType < b.ProductType
+		}
+		if a.ZipCode != b.ZipCode {
+			return a.ZipCode < b.ZipCode
+		}
+		return a.Date.Before(b.Date)
+	})
+}
+
+// printPricesJSON prints prices as a JSON array to stdout.
+func printPricesJSON(prices []models.PriceResult) error {
+	sortPrices(prices)
+	if prices == nil {
+		prices = []models.PriceResult{}
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(prices); err != nil {
+		return fmt.Errorf("encoding prices: %w", err)
+	}
+	return nil
+}
+
+// printPricesTable prints prices as an aligned table to stdout.
+func printPricesTable(prices []models.PriceResult) error {
+	sortPrices(prices)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "PROVIDER\tPRODUCT\tDATE\tPRICE/100L\tCURRENCY\tSCOPE\tREGION\tZIP")
+	for _, p := range prices {
+		zipCode := p.ZipCode
+		if zipCode == "" {
+			zipCode = "-"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%.2f\t%s\t%s\t%s\t%s\n",
+			p.Provider, p.ProductType, p.Date.Format("2006-01-02"), p.PricePer100L, p.Currency, p.Scope, p.Region, zipCode)
+	}
+	return w.Flush()
+}
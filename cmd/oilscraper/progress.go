@@ -0,0 +1,35 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/andygrunwald/oil-price-scraper/internal/scraper"
+)
+
+// progressBarWidth is the number of characters of the rendered progress bar.
+const progressBarWidth = 30
+
+// isTerminal returns true if f is connected to a terminal.
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+// renderProgressBar renders a single-line progress bar for a backfill to stdout.
+func renderProgressBar(p scraper.BackfillProgress) {
+	filled := int(p.Percent() / 100 * progressBarWidth)
+	bar := strings.Repeat("#", filled) + strings.Repeat("-", progressBarWidth-filled)
+
+	fmt.Printf("\r[%s] %d/%d chunks (%.0f%%) inserted=%d skipped=%d ETA %s ",
+		bar, p.Chunk, p.TotalChunks, p.Percent(), p.Inserted, p.Skipped, p.ETA.Round(time.Second))
+
+	if p.Chunk == p.TotalChunks {
+		fmt.Println()
+	}
+}
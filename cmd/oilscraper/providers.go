@@ -0,0 +1,338 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/rs/zerolog"
+
+	"github.com/andygrunwald/oil-price-scraper/internal/api"
+	"github.com/andygrunwald/oil-price-scraper/internal/api/heizoel24"
+	"github.com/andygrunwald/oil-price-scraper/internal/api/hoyer"
+	"github.com/andygrunwald/oil-price-scraper/internal/api/mock"
+	"github.com/andygrunwald/oil-price-scraper/internal/database"
+	"github.com/andygrunwald/oil-price-scraper/internal/scraper"
+	"github.com/andygrunwald/oil-price-scraper/internal/tracing"
+)
+
+// newProvider creates the provider with the given name from the global configuration,
+// fetching local prices for zipCode. With a validator store, requests are conditional
+// (see api.ConditionalTransport).
+// It returns false if the provider is unknown, and an error if its transport
+// cannot be set up (see newProviderTransport).
+func newProvider(name, zipCode string, validators api.ValidatorStore, logger zerolog.Logger) (api.Provider, bool, error) {
+	if !isKnownProvider(name) {
+		return nil, false, nil
+	}
+	transport, err := newProviderTransport(name, validators, logger)
+	if err != nil {
+		return nil, true, err
+	}
+	return buildProvider(name, zipCode, transport, logger), true, nil
+}
+
+// newProviderTransport creates the HTTP transport of a provider, serving responses
+// from its fixture if one is configured. Invalid credentials, proxies, CA files, or
+// fixtures are errors: silently sending requests differently than configured (e.g.,
+// to the network instead of a fixture) must not pass unnoticed.
+func newProviderTransport(name string, validators api.ValidatorStore, logger zerolog.Logger) (http.RoundTripper, error) {
+	if path, ok := cfg.ProviderFixtures[name]; ok {
+		fixture, err := api.NewFileFixtureTransport(path)
+		if err != nil {
+			return nil, fmt.Errorf("loading fixture of provider %s: %w", name, err)
+		}
+		logger.Warn().Str("provider", name).Str("fixture", path).Msg("serving provider responses from fixture")
+		return fixture, nil
+	}
+
+	credentials, err := providerCredentials(name)
+	if err != nil {
+		return nil, err
+	}
+	return newTransport(name, validators, credentials, logger)
+}
+
+// buildProvider creates the provider with the given name using transport.
+// The name must be known (see isKnownProvider). A configured region the provider
+// does not support (see api.Capabilities) is logged as a warning.
+func buildProvider(name, zipCode string, transport http.RoundTripper, logger zerolog.Logger) api.Provider {
+	provider := newProviderByName(name, zipCode, transport, logger)
+
+	if region, ok := cfg.ProviderRegions[name]; ok {
+		if capabilities := provider.Capabilities(); !capabilities.SupportsRegion(region) {
+			logger.Warn().
+				Str("provider", name).
+				Str("region", region).
+				Strs("supportedRegions", capabilities.Regions).
+				Msg("provider does not support the configured region")
+		}
+	}
+
+	return provider
+}
+
+// newProviderByName creates the provider with the given name from the global configuration.
+func newProviderByName(name, zipCode string, transport http.RoundTripper, logger zerolog.Logger) api.Provider {
+	headers := cfg.ProviderHeaders.For(name)
+	cookies := cfg.ProviderCookies.For(name)
+	query := cfg.ProviderQuery.For(name)
+	acceptLanguage := cfg.ProviderAcceptLanguages[name]
+
+	switch name {
+	case heizoel24.ProviderName:
+		region, ok := cfg.ProviderRegions[name]
+		if !ok {
+			region = api.DefaultRegion
+		}
+		return heizoel24.New(logger,
+			heizoel24.WithRegion(region),
+			heizoel24.WithTimeout(cfg.ProviderTimeout(name)),
+			heizoel24.WithHeaders(headers),
+			heizoel24.WithCookies(cookies),
+			heizoel24.WithQuery(query),
+			heizoel24.WithAcceptLanguage(acceptLanguage),
+			heizoel24.WithTransport(transport),
+			heizoel24.WithBaseURL(cfg.ProviderBaseURLs[name]),
+			heizoel24.WithFallbackURL(cfg.ProviderFallbackURLs[name]),
+			heizoel24.WithDateLocation(cfg.PriceLocation()),
+		)
+	case hoyer.ProviderName:
+		return hoyer.New(logger,
+			hoyer.WithZipCode(zipCode),
+			hoyer.WithOrderAmount(cfg.OrderAmount),
+			hoyer.WithTimeout(cfg.ProviderTimeout(name)),
+			hoyer.WithHeaders(headers),
+			hoyer.WithCookies(cookies),
+			hoyer.WithQuery(query),
+			hoyer.WithAcceptLanguage(acceptLanguage),
+			hoyer.WithTransport(transport),
+			hoyer.WithBaseURL(cfg.ProviderBaseURLs[name]),
+			hoyer.WithFallbackURL(cfg.ProviderFallbackURLs[name]),
+			hoyer.WithDateLocation(cfg.PriceLocation()),
+		)
+	case mock.ProviderName:
+		return mock.New(logger,
+			mock.WithDelay(cfg.MockProvider.Delay),
+			mock.WithFailure(cfg.MockProvider.Failure),
+		)
+	default:
+		panic(fmt.Sprintf("unknown provider: %s", name))
+	}
+}
+
+// providerCredentials returns the configured credentials of a provider, or nil if none are configured.
+func providerCredentials(name string) (*api.Credentials, error) {
+	value, ok := cfg.ProviderCredentials[name]
+	if !ok {
+		return nil, nil
+	}
+	credentials, err := api.ParseCredentials(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid credentials of provider %s: %w", name, err)
+	}
+	return credentials, nil
+}
+
+// providerHosts returns the hosts of the base and fallback URL of a provider,
+// the only hosts its credentials are sent to.
+func providerHosts(name string) []string {
+	baseURL := cfg.ProviderBaseURLs[name]
+	if baseURL == "" {
+		switch name {
+		case heizoel24.ProviderName:
+			baseURL = heizoel24.DefaultBaseURL
+		case hoyer.ProviderName:
+			baseURL = hoyer.DefaultBaseURL
+		}
+	}
+
+	var hosts []string
+	for _, rawURL := range []string{baseURL, cfg.ProviderFallbackURLs[name]} {
+		if u, err := url.Parse(rawURL); err == nil && u.Host != "" {
+			hosts = append(hosts, u.Host)
+		}
+	}
+	return hosts
+}
+
+// newTransport creates the HTTP transport of a provider from the proxy and TLS
+// settings of the global configuration. It returns nil (the default transport,
+// which honors HTTP_PROXY/HTTPS_PROXY) if none of them is set.
+// Credentials are added by the innermost transport, so no other transport sees them.
+func newTransport(name string, validators api.ValidatorStore, credentials *api.Credentials, logger zerolog.Logger) (http.RoundTripper, error) {
+	if len(cfg.Proxies) == 0 && cfg.CAFile == "" && !cfg.InsecureSkipVerify && cfg.OTelEndpoint == "" && validators == nil && credentials == nil {
+		return nil, nil
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+
+	if len(cfg.Proxies) > 0 {
+		rotator, err := api.NewProxyRotator(cfg.Proxies, cfg.ProxyRotation, logger.With().Str("provider", name).Logger())
+		if err != nil {
+			return nil, fmt.Errorf("setting up proxies of provider %s: %w", name, err)
+		}
+		transport.Proxy = rotator.Proxy
+	}
+
+	if cfg.CAFile != "" || cfg.InsecureSkipVerify {
+		tlsConfig, err := api.NewTLSConfig(cfg.CAFile, cfg.InsecureSkipVerify)
+		if err != nil {
+			return nil, fmt.Errorf("loading CA file %s: %w", cfg.CAFile, err)
+		}
+		transport.TLSClientConfig = tlsConfig
+		if transport.TLSClientConfig != nil && transport.TLSClientConfig.InsecureSkipVerify {
+			logger.Warn().Str("provider", name).Msg("TLS CERTIFICATE VERIFICATION IS DISABLED, responses may be intercepted or forged; use --ca-file instead")
+		}
+	}
+
+	var roundTripper http.RoundTripper = transport
+	if credentials != nil {
+		roundTripper = api.NewCredentialsTransport(roundTripper, credentials, providerHosts(name)...)
+	}
+	if validators != nil {
+		roundTripper = api.NewConditionalTransport(roundTripper, name, validators, logger)
+	}
+	if cfg.OTelEndpoint != "" {
+		roundTripper = tracing.Transport(roundTripper)
+	}
+	return roundTripper, nil
+}
+
+// validatorStore returns the store of conditional request validators, or nil if
+// conditional requests are disabled or no database is connected (e.g., dry runs).
+// Validators are only persisted once the prices of their response were stored.
+func validatorStore(s *scraper.Scraper, db *database.DB, logger zerolog.Logger) api.ValidatorStore {
+	if !cfg.ConditionalRequests || db == nil {
+		return nil
+	}
+	store := api.NewDeferredValidatorStore(db)
+	s.SetStoredHandler(func(ctx context.Context, providerName string, stored bool) {
+		if !stored {
+			store.Discard(providerName)
+			return
+		}
+		if err := store.Commit(ctx, providerName); err != nil {
+			logger.Warn().Err(err).Str("provider", providerName).Msg("failed to save cache validators")
+		}
+	})
+	return store
+}
+
+// isKnownProvider returns true if newProvider can create a provider with the given name.
+func isKnownProvider(name string) bool {
+	switch name {
+	case heizoel24.ProviderName, hoyer.ProviderName:
+		return true
+	case mock.ProviderName:
+		return cfg.MockProvider.Enabled
+	default:
+		return false
+	}
+}
+
+// parseProviderList splits a comma-separated list of provider names.
+// Empty entries are ignored.
+func parseProviderList(providers string) []string {
+	var list []string
+	for _, p := range strings.Split(providers, ",") {
+		p = strings.TrimSpace(p)
+		if p != "" {
+			list = append(list, p)
+		}
+	}
+	return list
+}
+
+// expandProviderGroups replaces group references (@name) with the providers of the group.
+// Duplicates are removed. Unknown groups are skipped with a warning, or rejected if
+// strict providers are enabled.
+func expandProviderGroups(names []string, logger zerolog.Logger) ([]string, error) {
+	seen := make(map[string]bool)
+	var expanded []string
+	add := func(name string) {
+		if !seen[name] {
+			seen[name] = true
+			expanded = append(expanded, name)
+		}
+	}
+
+	for _, name := range names {
+		group, isGroup := strings.CutPrefix(name, "@")
+		if !isGroup {
+			add(name)
+			continue
+		}
+
+		members, ok := cfg.ProviderGroups[group]
+		if !ok {
+			if cfg.StrictProviders {
+				return nil, fmt.Errorf("unknown provider group: %s", group)
+			}
+			logger.Warn().Str("group", group).Msg("unknown provider group, skipping")
+			continue
+		}
+		for _, member := range members {
+			add(member)
+		}
+	}
+
+	return expanded, nil
+}
+
+// registerProviders creates and registers the named providers with the scraper.
+// Group references (@name) are expanded first. Unknown providers are skipped with
+// a warning, or rejected if strict providers are enabled. With lazy providers, they
+// are created on first use (see api.Lazy). It returns an error if no provider could
+// be registered.
+func registerProviders(s *scraper.Scraper, names []string, zipCode string, validators api.ValidatorStore, logger zerolog.Logger) error {
+	names, err := expandProviderGroups(names, logger)
+	if err != nil {
+		return err
+	}
+
+	registered := 0
+	for _, name := range names {
+		provider, ok, err := registeredProvider(name, zipCode, validators, logger)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			if cfg.StrictProviders {
+				return fmt.Errorf("unknown provider: %s", name)
+			}
+			logger.Warn().Str("provider", name).Msg("unknown provider, skipping")
+			continue
+		}
+		s.RegisterProvider(provider)
+		registered++
+	}
+
+	if registered == 0 {
+		return fmt.Errorf("no providers registered (requested: %q)", names)
+	}
+
+	return nil
+}
+
+// registeredProvider creates the provider to register with the given name, which
+// is created on first use with lazy providers. Its transport is always set up
+// immediately, so configuration errors fail at startup. It returns false if the
+// provider is unknown.
+func registeredProvider(name, zipCode string, validators api.ValidatorStore, logger zerolog.Logger) (api.Provider, bool, error) {
+	if !cfg.LazyProviders {
+		return newProvider(name, zipCode, validators, logger)
+	}
+	if !isKnownProvider(name) {
+		return nil, false, nil
+	}
+	transport, err := newProviderTransport(name, validators, logger)
+	if err != nil {
+		return nil, true, err
+	}
+	return api.NewLazy(name, func() api.Provider {
+		return buildProvider(name, zipCode, transport, logger)
+	}), true, nil
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// reloadSettings are the settings of a reload file that are applied without a restart.
+// Settings missing in the file are nil and keep their current value.
+type reloadSettings struct {
+	logLevel      *zerolog.Level
+	scrapeHour    *int
+	providerHours map[string]int
+	scrapeJitter  *time.Duration
+	// ignored are the keys of the file that cannot be applied without a restart
+	ignored []string
+}
+
+// hasSchedule returns true if the settings change the scrape schedule.
+func (r reloadSettings) hasSchedule() bool {
+	return r.scrapeHour != nil || r.providerHours != nil || r.scrapeJitter != nil
+}
+
+// readReloadFile reads a reload file of KEY=VALUE lines using the names of the
+// environment variables (e.g., LOG_LEVEL=debug). Empty lines and lines starting
+// with # are skipped. Invalid values fail the whole file, so a typo never applies
+// half of a change.
+func readReloadFile(path string) (reloadSettings, error) {
+	var settings reloadSettings
+
+	f, err := os.Open(path)
+	if err != nil {
+		return settings, fmt.Errorf("opening reload file: %w", err)
+	}
+	defer func() {
+		if err := f.Close(); err != nil {
+			panic(err)
+		}
+	}()
+
+	scanner := bufio.NewScanner(f)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			return settings, fmt.Errorf("line %d: expected KEY=VALUE, got %q", lineNo, line)
+		}
+		key = strings.TrimSpace(key)
+		value = strings.Trim(strings.TrimSpace(value), `"'`)
+
+		switch key {
+		case "LOG_LEVEL":
+			level, err := zerolog.ParseLevel(value)
+			if err != nil {
+				return settings, fmt.Errorf("line %d: LOG_LEVEL: %w", lineNo, err)
+			}
+			settings.logLevel = &level
+		case "SCRAPE_HOUR":
+			hour, err := strconv.Atoi(value)
+			if err != nil || hour < 0 || hour > 23 {
+				return settings, fmt.Errorf("line %d: SCRAPE_HOUR: must be between 0 and 23, got %q", lineNo, value)
+			}
+			settings.scrapeHour = &hour
+		case "PROVIDER_SCRAPE_HOURS":
+			hours, err := parseProviderHours(value)
+			if err != nil {
+				return settings, fmt.Errorf("line %d: PROVIDER_SCRAPE_HOURS: %w", lineNo, err)
+			}
+			settings.providerHours = hours
+		case "SCRAPE_JITTER":
+			jitter, err := time.ParseDuration(value)
+			if err != nil || jitter < 0 || jitter >= time.Hour {
+				return settings, fmt.Errorf("line %d: SCRAPE_JITTER: must be between 0 and 1h (exclusive), got %q", lineNo, value)
+			}
+			settings.scrapeJitter = &jitter
+		default:
+			if !slices.Contains(settings.ignored, key) {
+				settings.ignored = append(settings.ignored, key)
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return settings, fmt.Errorf("reading reload file: %w", err)
+	}
+	return settings, nil
+}
+
+// parseProviderHours parses per-provider scrape hours (e.g., heizoel24=6,hoyer=14).
+// An empty value removes all per-provider hours.
+func parseProviderHours(value string) (map[string]int, error) {
+	hours := make(map[string]int)
+	for entry := range strings.SplitSeq(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		name, hourStr, ok := strings.Cut(entry, "=")
+		if !ok {
+			return nil, fmt.Errorf("expected provider=hour, got %q", entry)
+		}
+		hour, err := strconv.Atoi(strings.TrimSpace(hourStr))
+		if err != nil || hour < 0 || hour > 23 {
+			return nil, fmt.Errorf("hour for %s must be between 0 and 23, got %q", name, hourStr)
+		}
+		hours[strings.TrimSpace(name)] = hour
+	}
+	return hours, nil
+}
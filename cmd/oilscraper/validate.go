@@ -0,0 +1,87 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// validateConfig validates the global configuration together with the given
+// command specific problems and reports all of them at once.
+func validateConfig(problems ...error) error {
+	errs := cfg.Validate()
+	if cfg.PrimaryProvider != "" && !isKnownProvider(cfg.PrimaryProvider) {
+		errs = append(errs, fmt.Errorf("--primary-provider: unknown provider %q", cfg.PrimaryProvider))
+	}
+	for _, err := range problems {
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) == 0 {
+		return nil
+	}
+
+	msgs := make([]string, 0, len(errs))
+	for _, err := range errs {
+		msgs = append(msgs, err.Error())
+	}
+	return fmt.Errorf("invalid configuration:\n  - %s", strings.Join(msgs, "\n  - "))
+}
+
+// requireFlag returns an error if a required flag is empty.
+func requireFlag(name, value string) error {
+	if value == "" {
+		return fmt.Errorf("--%s is required", name)
+	}
+	return nil
+}
+
+// checkProviders returns an error for unknown providers and groups in a
+// comma-separated provider list. Unknown entries are only errors with strict
+// providers, otherwise they are skipped at registration. Without any known
+// provider, the list is always rejected.
+func checkProviders(flag, providers string) error {
+	var unknown []string
+	known := 0
+	for _, name := range parseProviderList(providers) {
+		if group, isGroup := strings.CutPrefix(name, "@"); isGroup {
+			members, ok := cfg.ProviderGroups[group]
+			if !ok {
+				unknown = append(unknown, name)
+				continue
+			}
+			for _, member := range members {
+				if isKnownProvider(member) {
+					known++
+				} else {
+					unknown = append(unknown, member)
+				}
+			}
+			continue
+		}
+		if isKnownProvider(name) {
+			known++
+		} else {
+			unknown = append(unknown, name)
+		}
+	}
+
+	if known == 0 || (cfg.StrictProviders && len(unknown) > 0) {
+		if len(unknown) == 0 {
+			return fmt.Errorf("--%s: no providers given", flag)
+		}
+		return fmt.Errorf("--%s: unknown providers: %s", flag, strings.Join(unknown, ", "))
+	}
+	return nil
+}
+
+// maxLabelLength is the length of the label column.
+const maxLabelLength = 100
+
+// checkLabel returns an error if a label does not fit into the label column.
+func checkLabel(label string) error {
+	if len(label) > maxLabelLength {
+		return fmt.Errorf("--label: must not be longer than %d characters", maxLabelLength)
+	}
+	return nil
+}
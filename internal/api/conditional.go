@@ -0,0 +1,165 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// ErrNotModified indicates that a provider answered a conditional request with
+// HTTP 304 Not Modified, i.e. the prices did not change since the last response.
+var ErrNotModified = errors.New("not modified")
+
+// Validators are the cache validators of the last response of a provider.
+type Validators struct {
+	// URL is the request URL the validators belong to.
+	URL          string
+	ETag         string
+	LastModified string
+	SavedAt      time.Time
+}
+
+// ValidatorStore persists the validators of the last response per provider.
+type ValidatorStore interface {
+	// GetValidators returns the validators of a provider, or nil if none are stored.
+	GetValidators(ctx context.Context, provider string) (*Validators, error)
+	// SaveValidators replaces the validators of a provider.
+	SaveValidators(ctx context.Context, provider string, v Validators) error
+}
+
+// DeferredValidatorStore holds saved validators back until the prices of the response
+// they belong to were stored. Otherwise, a response that fails parsing, checks, or
+// storing would still be skipped as unchanged by the next conditional request.
+type DeferredValidatorStore struct {
+	store   ValidatorStore
+	pending map[string]Validators
+	mu      sync.Mutex
+}
+
+// NewDeferredValidatorStore creates a DeferredValidatorStore persisting to store.
+func NewDeferredValidatorStore(store ValidatorStore) *DeferredValidatorStore {
+	return &DeferredValidatorStore{
+		store:   store,
+		pending: make(map[string]Validators),
+	}
+}
+
+// GetValidators returns the persisted validators of a provider. Pending validators are not returned.
+func (d *DeferredValidatorStore) GetValidators(ctx context.Context, provider string) (*Validators, error) {
+	return d.store.GetValidators(ctx, provider)
+}
+
+// SaveValidators keeps the validators of a provider pending until Commit or Discard.
+func (d *DeferredValidatorStore) SaveValidators(_ context.Context, provider string, v Validators) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.pending[provider] = v
+	return nil
+}
+
+// Commit persists the pending validators of a provider, if any.
+func (d *DeferredValidatorStore) Commit(ctx context.Context, provider string) error {
+	d.mu.Lock()
+	v, ok := d.pending[provider]
+	delete(d.pending, provider)
+	d.mu.Unlock()
+	if !ok {
+		return nil
+	}
+	return d.store.SaveValidators(ctx, provider, v)
+}
+
+// Discard drops the pending validators of a provider, if any.
+func (d *DeferredValidatorStore) Discard(provider string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	delete(d.pending, provider)
+}
+
+// ConditionalTransport sends If-None-Match and If-Modified-Since headers based on
+// the ETag and Last-Modified headers of the previous response to the same URL.
+// Providers return ErrNotModified for HTTP 304 responses.
+//
+// Validators are only reused on the day they were saved (UTC). Prices of providers
+// without historical data are dated by the fetch day, so the first request of a day
+// always fetches the full response. Responses without validators are not affected.
+type ConditionalTransport struct {
+	base     http.RoundTripper
+	provider string
+	store    ValidatorStore
+	logger   zerolog.Logger
+}
+
+// NewConditionalTransport creates a ConditionalTransport for a provider.
+// If base is nil, http.DefaultTransport is used.
+func NewConditionalTransport(base http.RoundTripper, provider string, store ValidatorStore, logger zerolog.Logger) *ConditionalTransport {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &ConditionalTransport{
+		base:     base,
+		provider: provider,
+		store:    store,
+		logger:   logger.With().Str("provider", provider).Logger(),
+	}
+}
+
+// RoundTrip implements the http.RoundTripper interface.
+func (t *ConditionalTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != http.MethodGet {
+		return t.base.RoundTrip(req)
+	}
+
+	ctx := req.Context()
+	// Credentials in the URL's user info must not be stored
+	u := *req.URL
+	u.User = nil
+	url := u.String()
+
+	v, err := t.store.GetValidators(ctx, t.provider)
+	if err != nil {
+		t.logger.Warn().Err(err).Msg("failed to load cache validators, sending unconditional request")
+	}
+	if v != nil && v.URL == url && sameDay(v.SavedAt, time.Now()) {
+		// RoundTrippers must not modify the request
+		req = req.Clone(ctx)
+		if v.ETag != "" {
+			req.Header.Set("If-None-Match", v.ETag)
+		}
+		if v.LastModified != "" {
+			req.Header.Set("If-Modified-Since", v.LastModified)
+		}
+	}
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	etag := resp.Header.Get("ETag")
+	lastModified := resp.Header.Get("Last-Modified")
+	if resp.StatusCode == http.StatusOK && (etag != "" || lastModified != "") {
+		err := t.store.SaveValidators(ctx, t.provider, Validators{
+			URL:          url,
+			ETag:         etag,
+			LastModified: lastModified,
+			SavedAt:      time.Now().UTC(),
+		})
+		if err != nil {
+			t.logger.Warn().Err(err).Msg("failed to save cache validators")
+		}
+	}
+
+	return resp, nil
+}
+
+// sameDay reports whether a and b are on the same day in UTC.
+func sameDay(a, b time.Time) bool {
+	ay, am, ad := a.UTC().Date()
+	by, bm, bd := b.UTC().Date()
+	return ay == by && am == bm && ad == bd
+}
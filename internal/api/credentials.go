@@ -0,0 +1,135 @@
+package api
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Credential types supported by Credentials.
+const (
+	// CredentialsHeader sends the key in an HTTP header.
+	CredentialsHeader = "header"
+	// CredentialsQuery sends the key as a URL query parameter.
+	CredentialsQuery = "query"
+	// CredentialsHMAC signs every request with the key (HMAC-SHA256).
+	CredentialsHMAC = "hmac"
+)
+
+// Default names of the header or query parameter carrying the credentials.
+const (
+	DefaultCredentialsHeader = "X-API-Key"
+	DefaultCredentialsQuery  = "api_key"
+	DefaultSignatureHeader   = "X-Signature"
+	// TimestampHeader carries the Unix timestamp covered by an HMAC signature.
+	TimestampHeader = "X-Timestamp"
+)
+
+// Credentials authenticate requests to a provider API, for providers that
+// require an API key or signed requests.
+type Credentials struct {
+	// Type is CredentialsHeader, CredentialsQuery, or CredentialsHMAC.
+	Type string
+	// Name is the header or query parameter name. Empty uses the default of the type.
+	Name string
+	// Key is the API key or, for CredentialsHMAC, the signing secret.
+	Key string
+}
+
+// ParseCredentials parses credentials in the form type:name:key (e.g.,
+// "header:X-API-Key:secret", "query:apikey:secret", or "hmac::secret").
+// An empty name uses the default of the type. The key may contain colons.
+func ParseCredentials(value string) (*Credentials, error) {
+	parts := strings.SplitN(value, ":", 3)
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("invalid credentials (expected type:name:key)")
+	}
+
+	c := &Credentials{Type: parts[0], Name: parts[1], Key: parts[2]}
+	switch c.Type {
+	case CredentialsHeader, CredentialsQuery, CredentialsHMAC:
+	default:
+		return nil, fmt.Errorf("unknown credentials type %q (expected %s, %s, or %s)", c.Type, CredentialsHeader, CredentialsQuery, CredentialsHMAC)
+	}
+	if c.Key == "" {
+		return nil, fmt.Errorf("empty credentials key")
+	}
+	return c, nil
+}
+
+// credentialsTransport authenticates every request to the provider's hosts with Credentials.
+type credentialsTransport struct {
+	base        http.RoundTripper
+	credentials *Credentials
+	hosts       map[string]bool
+}
+
+// NewCredentialsTransport returns a transport authenticating every request to one of
+// hosts (host or host:port as in URL.Host, e.g. of the provider's base and fallback URL)
+// with credentials before passing it to base. Requests to other hosts, e.g. redirects
+// followed by the HTTP client, are passed on without credentials.
+//
+// It must be the innermost transport wrapping the HTTP transport: the key is only added
+// to the request sent over the wire, so it never appears in URLs stored with prices or
+// cache validators, in trace spans, or in logs. If base is nil, http.DefaultTransport is used.
+func NewCredentialsTransport(base http.RoundTripper, credentials *Credentials, hosts ...string) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	t := &credentialsTransport{base: base, credentials: credentials, hosts: make(map[string]bool, len(hosts))}
+	for _, host := range hosts {
+		t.hosts[strings.ToLower(host)] = true
+	}
+	return t
+}
+
+// RoundTrip implements the http.RoundTripper interface.
+func (t *credentialsTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// Every redirect passes the transport, so a redirect to another host must not receive the secret
+	if !t.hosts[strings.ToLower(req.URL.Host)] {
+		return t.base.RoundTrip(req)
+	}
+
+	// RoundTrippers must not modify the request
+	req = req.Clone(req.Context())
+	t.credentials.Apply(req)
+	return t.base.RoundTrip(req)
+}
+
+// Apply authenticates the request. It must be called after all other changes to the
+// request, as HMAC signatures cover the method, path, and query of the final URL.
+func (c *Credentials) Apply(req *http.Request) {
+	switch c.Type {
+	case CredentialsHeader:
+		req.Header.Set(c.nameOr(DefaultCredentialsHeader), c.Key)
+	case CredentialsQuery:
+		query := req.URL.Query()
+		query.Set(c.nameOr(DefaultCredentialsQuery), c.Key)
+		req.URL.RawQuery = query.Encode()
+	case CredentialsHMAC:
+		timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+		req.Header.Set(TimestampHeader, timestamp)
+		req.Header.Set(c.nameOr(DefaultSignatureHeader), c.sign(req.Method, req.URL.RequestURI(), timestamp))
+	}
+}
+
+// sign returns the hex-encoded HMAC-SHA256 of method, request URI, and timestamp,
+// separated by newlines.
+func (c *Credentials) sign(method, requestURI, timestamp string) string {
+	mac := hmac.New(sha256.New, []byte(c.Key))
+	mac.Write([]byte(method + "\n" + requestURI + "\n" + timestamp))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// nameOr returns the configured name or fallback.
+func (c *Credentials) nameOr(fallback string) string {
+	if c.Name == "" {
+		return fallback
+	}
+	return c.Name
+}
@@ -0,0 +1,136 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func TestParseCredentials(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		want    Credentials
+		wantErr bool
+	}{
+		{name: "header", value: "header:X-Token:secret", want: Credentials{Type: CredentialsHeader, Name: "X-Token", Key: "secret"}},
+		{name: "default name", value: "query::secret", want: Credentials{Type: CredentialsQuery, Key: "secret"}},
+		{name: "key containing colons", value: "hmac::a:b:c", want: Credentials{Type: CredentialsHMAC, Key: "a:b:c"}},
+		{name: "empty key", value: "header:X-Token:", wantErr: true},
+		{name: "unknown type", value: "basic:user:secret", wantErr: true},
+		{name: "missing parts", value: "header:secret", wantErr: true},
+		{name: "empty", value: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseCredentials(tt.value)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("ParseCredentials(%q) = %+v, want error", tt.value, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseCredentials(%q) returned error: %v", tt.value, err)
+			}
+			if *got != tt.want {
+				t.Errorf("ParseCredentials(%q) = %+v, want %+v", tt.value, *got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCredentialsSign(t *testing.T) {
+	c := &Credentials{Type: CredentialsHMAC, Key: "secret"}
+
+	// Computed independently: HMAC-SHA256("secret", "GET\n/rest/heatingoil/12345?amount=3000\n1700000000")
+	want := "813ff83451eff58317026d39ff680ee3ca9d1b74454a7d6c96da4e1171f90282"
+	if got := c.sign(http.MethodGet, "/rest/heatingoil/12345?amount=3000", "1700000000"); got != want {
+		t.Errorf("sign() = %s, want %s", got, want)
+	}
+}
+
+// recordingTransport records the last request instead of sending it.
+type recordingTransport struct {
+	req *http.Request
+}
+
+func (t *recordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.req = req
+	return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody, Request: req}, nil
+}
+
+func TestCredentialsTransportHosts(t *testing.T) {
+	tests := []struct {
+		name     string
+		url      string
+		wantSent bool
+	}{
+		{name: "provider host", url: "https://api.example.com/prices", wantSent: true},
+		{name: "provider host in upper case", url: "https://API.example.com/prices", wantSent: true},
+		{name: "other host", url: "https://evil.example.org/prices", wantSent: false},
+		{name: "other port", url: "https://api.example.com:8443/prices", wantSent: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			base := &recordingTransport{}
+			transport := NewCredentialsTransport(base, &Credentials{Type: CredentialsHeader, Key: "secret"}, "api.example.com")
+
+			req, err := http.NewRequest(http.MethodGet, tt.url, nil)
+			if err != nil {
+				t.Fatalf("creating request: %v", err)
+			}
+			if _, err := transport.RoundTrip(req); err != nil {
+				t.Fatalf("RoundTrip returned error: %v", err)
+			}
+
+			sent := base.req.Header.Get(DefaultCredentialsHeader) == "secret"
+			if sent != tt.wantSent {
+				t.Errorf("credentials sent = %v, want %v", sent, tt.wantSent)
+			}
+			if req.Header.Get(DefaultCredentialsHeader) != "" {
+				t.Error("RoundTrip modified the original request")
+			}
+		})
+	}
+}
+
+func TestCredentialsTransportRedirect(t *testing.T) {
+	var redirectedKey string
+	other := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		redirectedKey = r.Header.Get(DefaultCredentialsHeader)
+	}))
+	defer other.Close()
+
+	var providerKey string
+	provider := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		providerKey = r.Header.Get(DefaultCredentialsHeader)
+		http.Redirect(w, r, other.URL, http.StatusFound)
+	}))
+	defer provider.Close()
+
+	u, err := url.Parse(provider.URL)
+	if err != nil {
+		t.Fatalf("parsing URL: %v", err)
+	}
+	client := &http.Client{
+		Transport: NewCredentialsTransport(nil, &Credentials{Type: CredentialsHeader, Key: "secret"}, u.Host),
+	}
+	resp, err := client.Get(provider.URL)
+	if err != nil {
+		t.Fatalf("GET returned error: %v", err)
+	}
+	if err := resp.Body.Close(); err != nil {
+		t.Fatalf("closing body: %v", err)
+	}
+
+	if providerKey != "secret" {
+		t.Errorf("provider received key %q, want %q", providerKey, "secret")
+	}
+	if redirectedKey != "" {
+		t.Errorf("redirect target received key %q, want none", redirectedKey)
+	}
+}
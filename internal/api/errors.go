@@ -0,0 +1,136 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// Error reasons used to categorize provider errors in metrics.
+const (
+	// ErrorReasonTransport is a network error (e.g., connection refused) before a response was received.
+	ErrorReasonTransport = "transport"
+	// ErrorReasonTimeout is a request that did not complete within its deadline.
+	ErrorReasonTimeout = "timeout"
+	// ErrorReasonStatus is an unexpected HTTP status code.
+	ErrorReasonStatus = "http_status"
+	// ErrorReasonRateLimit is an HTTP 429 Too Many Requests response.
+	ErrorReasonRateLimit = "rate_limit"
+	// ErrorReasonParse is a response that could not be parsed.
+	ErrorReasonParse = "parse"
+	// ErrorReasonUnsuccessful is a response that did not meet the provider's success criterion.
+	ErrorReasonUnsuccessful = "unsuccessful"
+	// ErrorReasonOther is any other error.
+	ErrorReasonOther = "other"
+)
+
+// ErrTimeout indicates that a provider request did not complete within its deadline
+// (see RequestOptions.Timeout). Use errors.Is to check for it.
+var ErrTimeout = errors.New("request timed out")
+
+// isTimeout reports whether err is a timeout of a request or its context.
+func isTimeout(err error) bool {
+	var netErr net.Error
+	return errors.Is(err, ErrTimeout) ||
+		errors.Is(err, context.DeadlineExceeded) ||
+		(errors.As(err, &netErr) && netErr.Timeout())
+}
+
+// WrapTimeout wraps timeouts (e.g., an exceeded context deadline or HTTP client timeout)
+// with ErrTimeout, so they can be told apart from other transport errors.
+// Other errors, and errors already wrapping ErrTimeout, are returned unchanged.
+func WrapTimeout(err error) error {
+	if err == nil || errors.Is(err, ErrTimeout) || !isTimeout(err) {
+		return err
+	}
+	return fmt.Errorf("%w: %w", ErrTimeout, err)
+}
+
+// maxErrorBodySize limits how much of a response body is kept in a StatusError.
+const maxErrorBodySize = 1024
+
+// StatusError indicates that a provider API responded with an unexpected HTTP status code.
+type StatusError struct {
+	StatusCode int
+	Body       string
+}
+
+// Error implements the error interface.
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("unexpected status code %d: %s", e.StatusCode, e.Body)
+}
+
+// RateLimitError indicates that a provider API rejected the request with
+// HTTP 429 Too Many Requests.
+type RateLimitError struct {
+	// RetryAfter is the delay requested by the Retry-After header (zero if absent).
+	RetryAfter time.Duration
+}
+
+// Error implements the error interface.
+func (e *RateLimitError) Error() string {
+	if e.RetryAfter > 0 {
+		return fmt.Sprintf("rate limited, retry after %s", e.RetryAfter)
+	}
+	return "rate limited"
+}
+
+// NewStatusError creates the error for an unexpected HTTP response.
+// It returns a *RateLimitError for HTTP 429 and a *StatusError otherwise.
+func NewStatusError(resp *http.Response) error {
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return &RateLimitError{RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After"))}
+	}
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBodySize))
+	return &StatusError{
+		StatusCode: resp.StatusCode,
+		Body:       string(body),
+	}
+}
+
+// parseRetryAfter parses a Retry-After header given in seconds or as an HTTP date.
+func parseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return time.Duration(seconds) * time.Second
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		return max(time.Until(t), 0)
+	}
+	return 0
+}
+
+// ErrorReason categorizes a provider error (see the ErrorReason constants).
+func ErrorReason(err error) string {
+	var parseErr *ParseError
+	var rateLimitErr *RateLimitError
+	var statusErr *StatusError
+	var urlErr *url.Error
+	var netErr net.Error
+
+	switch {
+	case errors.Is(err, ErrUnsuccessful):
+		return ErrorReasonUnsuccessful
+	case errors.As(err, &parseErr):
+		return ErrorReasonParse
+	case errors.As(err, &rateLimitErr):
+		return ErrorReasonRateLimit
+	case errors.As(err, &statusErr):
+		return ErrorReasonStatus
+	case isTimeout(err):
+		return ErrorReasonTimeout
+	case errors.As(err, &urlErr), errors.As(err, &netErr):
+		return ErrorReasonTransport
+	default:
+		return ErrorReasonOther
+	}
+}
@@ -0,0 +1,31 @@
+package api
+
+import (
+	"context"
+	"errors"
+
+	"github.com/rs/zerolog"
+
+	"github.com/andygrunwald/oil-price-scraper/internal/models"
+)
+
+// FetchFunc fetches prices from the API at baseURL.
+type FetchFunc func(ctx context.Context, baseURL string) ([]models.PriceResult, error)
+
+// FetchWithFallback fetches prices from baseURL. If that fails and a fallbackURL
+// is set (e.g., a mirror or the provider's new API path), the fetch is retried
+// with fallbackURL. The error of the fallback is returned if both fail.
+func FetchWithFallback(ctx context.Context, baseURL, fallbackURL string, logger zerolog.Logger, fetch FetchFunc) ([]models.PriceResult, error) {
+	results, err := fetch(ctx, baseURL)
+	if err == nil || fallbackURL == "" || ctx.Err() != nil || errors.Is(err, ErrNotModified) {
+		return results, err
+	}
+
+	logger.Warn().
+		Err(err).
+		Str("baseURL", baseURL).
+		Str("fallbackURL", fallbackURL).
+		Msg("fetching from base URL failed, trying fallback URL")
+
+	return fetch(ctx, fallbackURL)
+}
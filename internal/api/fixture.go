@@ -0,0 +1,62 @@
+package api
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+)
+
+// FixtureTransport is an http.RoundTripper that answers every request with a
+// canned response instead of calling the network. It allows running providers
+// against saved responses (e.g., in tests or CI without network access).
+type FixtureTransport struct {
+	// StatusCode of the response. Zero means http.StatusOK.
+	StatusCode int
+	// Header of the response.
+	Header http.Header
+	// Body of the response.
+	Body []byte
+}
+
+// NewFileFixtureTransport creates a FixtureTransport answering with the content of a file.
+func NewFileFixtureTransport(path string) (*FixtureTransport, error) {
+	body, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading fixture: %w", err)
+	}
+	return &FixtureTransport{
+		Header: http.Header{"Content-Type": []string{"application/json"}},
+		Body:   body,
+	}, nil
+}
+
+// RoundTrip implements the http.RoundTripper interface.
+func (t *FixtureTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if err := req.Context().Err(); err != nil {
+		return nil, err
+	}
+
+	statusCode := t.StatusCode
+	if statusCode == 0 {
+		statusCode = http.StatusOK
+	}
+
+	header := t.Header.Clone()
+	if header == nil {
+		header = http.Header{}
+	}
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", statusCode, http.StatusText(statusCode)),
+		StatusCode:    statusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(t.Body)),
+		ContentLength: int64(len(t.Body)),
+		Request:       req,
+	}, nil
+}
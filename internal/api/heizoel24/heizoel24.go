@@ -5,10 +5,11 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"io"
 	"net/http"
+	"strings"
 	"time"
 
+	"github.com/andygrunwald/oil-price-scraper/internal/api"
 	"github.com/andygrunwald/oil-price-scraper/internal/models"
 	"github.com/andygrunwald/oil-price-scraper/internal/useragent"
 	"github.com/rs/zerolog"
@@ -19,12 +20,20 @@ const (
 	ProviderName = "heizoel24"
 	// ProductType is the standard product type for HeizOel24.
 	ProductType = "standard"
-	// baseURL is the API endpoint for HeizOel24.
-	baseURL = "https://www.heizoel24.de/api/chartapi/GetAveragePriceHistory"
-	// countryID for Germany.
-	countryID = 1
+	// DefaultBaseURL is the API endpoint for HeizOel24.
+	DefaultBaseURL = "https://www.heizoel24.de/api/chartapi/GetAveragePriceHistory"
 )
 
+// countryIDs maps regions to the countryId parameter of the HeizOel24 API.
+// Only the ID of Germany is known. Other regions need an explicit countryId query parameter.
+var countryIDs = map[string]int{
+	"DE": 1,
+}
+
+// Currency is the only currency HeizOel24 prices are stored in. Responses in other
+// currencies are rejected, as prices of a series must be comparable.
+const Currency = "EUR"
+
 // apiResponse represents the JSON response from HeizOel24 API.
 type apiResponse struct {
 	Values        []priceValue `json:"Values"`
@@ -32,30 +41,46 @@ type apiResponse struct {
 	ProductName   string       `json:"ProductName"`
 	ChartName     string       `json:"ChartName"`
 	ChartUnit     string       `json:"ChartUnit"`
-	CurrentPrice  float64      `json:"CurrentPrice"`
-	ChangePercent float64      `json:"ChangePercent"`
+	CurrentPrice  number       `json:"CurrentPrice"`
+	ChangePercent number       `json:"ChangePercent"`
 }
 
 // priceValue represents a single price data point.
+// Values may be numbers, numeric strings, or null (see number).
 type priceValue struct {
-	Date  int64   `json:"date"`
-	Value float64 `json:"value"`
+	Date  int64  `json:"date"`
+	Value number `json:"value"`
 }
 
 // Provider implements the API provider interface for HeizOel24.
 type Provider struct {
-	client *http.Client
-	logger zerolog.Logger
+	client         *http.Client
+	transport      http.RoundTripper
+	logger         zerolog.Logger
+	region         string
+	dateLocation   *time.Location
+	baseURL        string
+	fallbackURL    string
+	requestOptions api.RequestOptions
 }
 
 // New creates a new HeizOel24 provider.
-func New(logger zerolog.Logger) *Provider {
-	return &Provider{
-		client: &http.Client{
-			Timeout: 30 * time.Second,
-		},
-		logger: logger.With().Str("provider", ProviderName).Logger(),
+func New(logger zerolog.Logger, opts ...Option) *Provider {
+	p := &Provider{
+		logger:  logger.With().Str("provider", ProviderName).Logger(),
+		region:  api.DefaultRegion,
+		baseURL: DefaultBaseURL,
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+
+	p.client = &http.Client{
+		Timeout:   p.requestOptions.RequestTimeout(),
+		Transport: p.transport,
 	}
+
+	return p
 }
 
 // Name returns the provider identifier.
@@ -63,14 +88,32 @@ func (p *Provider) Name() string {
 	return ProviderName
 }
 
-// SupportsBackfill returns true as HeizOel24 supports historical data.
-func (p *Provider) SupportsBackfill() bool {
-	return true
+// Capabilities returns the capabilities of HeizOel24: nationwide average prices
+// of a single product with historical data for Germany. With an explicit countryId
+// query parameter, the configured region is supported as well.
+func (p *Provider) Capabilities() api.Capabilities {
+	regions := []string{"DE"}
+	if _, overridden := p.requestOptions.Query["countryId"]; overridden && p.region != "DE" {
+		regions = append(regions, p.region)
+	}
+	return api.Capabilities{
+		Backfill: true,
+		Scope:    models.PriceScopeNational,
+		Regions:  regions,
+	}
 }
 
-// PriceScope returns national as HeizOel24 provides nationwide average prices.
-func (p *Provider) PriceScope() models.PriceScope {
-	return models.PriceScopeNational
+// Metadata returns the provider metadata.
+// Every response must contain at least one value for the requested range,
+// and a scrape only succeeds with a valid price of today or yesterday.
+// Backfill ranges of several years stay well below the response size limit.
+func (p *Provider) Metadata() api.Metadata {
+	return api.Metadata{
+		MinResults:      1,
+		CurrentPrice:    api.CurrentPriceLatest,
+		Success:         api.RequireRecentPrice(1),
+		MaxResponseSize: 8 << 20,
+	}
 }
 
 // FetchCurrentPrices fetches today's price from HeizOel24.
@@ -81,14 +124,32 @@ func (p *Provider) FetchCurrentPrices(ctx context.Context) ([]models.PriceResult
 }
 
 // FetchHistoricalPrices fetches prices for a date range from HeizOel24.
+// If the base URL fails, the fallback URL is tried (see WithFallbackURL).
 func (p *Provider) FetchHistoricalPrices(ctx context.Context, from, to time.Time) ([]models.PriceResult, error) {
+	// A countryId query parameter set via WithQuery takes precedence
+	countryID, ok := countryIDs[p.region]
+	if _, overridden := p.requestOptions.Query["countryId"]; !ok && !overridden {
+		return nil, fmt.Errorf("unsupported region %q", p.region)
+	}
+
+	return api.FetchWithFallback(ctx, p.baseURL, p.fallbackURL, p.logger, func(ctx context.Context, baseURL string) ([]models.PriceResult, error) {
+		return p.fetch(ctx, baseURL, countryID, from, to)
+	})
+}
+
+// fetch fetches prices for a date range from the HeizOel24 API at baseURL.
+func (p *Provider) fetch(ctx context.Context, baseURL string, countryID int, from, to time.Time) ([]models.PriceResult, error) {
 	fromStr := from.Format("2006-01-02")
 	toStr := to.Format("2006-01-02")
 
+	ctx, cancel := p.requestOptions.WithDeadline(ctx)
+	defer cancel()
+
 	apiURL := fmt.Sprintf("%s?countryId=%d&minDate=%s&maxDate=%s", baseURL, countryID, fromStr, toStr)
 
 	p.logger.Debug().
 		Str("url", apiURL).
+		Str("region", p.region).
 		Str("from", fromStr).
 		Str("to", toStr).
 		Msg("fetching prices from HeizOel24")
@@ -100,6 +161,7 @@ func (p *Provider) FetchHistoricalPrices(ctx context.Context, from, to time.Time
 
 	req.Header.Set("User-Agent", useragent.Random())
 	req.Header.Set("Accept", "application/json")
+	p.requestOptions.Apply(req)
 
 	resp, err := p.client.Do(req)
 	if err != nil {
@@ -111,46 +173,102 @@ func (p *Provider) FetchHistoricalPrices(ctx context.Context, from, to time.Time
 		}
 	}()
 
+	if resp.StatusCode == http.StatusNotModified {
+		return nil, api.ErrNotModified
+	}
 	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("unexpected status code %d: %s", resp.StatusCode, string(body))
+		return nil, api.NewStatusError(resp)
 	}
 
-	body, err := io.ReadAll(resp.Body)
+	body, err := api.ReadBody(resp.Body, p.Metadata().ResponseSizeLimit())
 	if err != nil {
 		return nil, fmt.Errorf("reading response body: %w", err)
 	}
 
+	results, err := ParseResponse(body, api.ParseParams{FetchedAt: time.Now(), Region: p.region, DateLocation: p.dateLocation})
+	if err != nil {
+		return nil, err
+	}
+
+	snapshot := api.NewResponseSnapshot(resp)
+	for i := range results {
+		results[i].SourceURL = p.requestOptions.SourceURL(req)
+		results[i].ResponseSnapshot = snapshot
+	}
+
+	p.logger.Info().
+		Int("count", len(results)).
+		Str("baseURL", baseURL).
+		Str("from", fromStr).
+		Str("to", toStr).
+		Msg("fetched prices from HeizOel24")
+
+	return results, nil
+}
+
+// ParseRawResponse derives prices from a raw HeizOel24 API response.
+func (p *Provider) ParseRawResponse(body []byte, params api.ParseParams) ([]models.PriceResult, error) {
+	return ParseResponse(body, params)
+}
+
+// ParseResponse derives prices from a raw HeizOel24 API response.
+// It does not perform any I/O, so it can be used with stored responses and fixtures.
+// Invalid responses, including responses in another currency than Currency, return an *api.ParseError.
+func ParseResponse(body []byte, params api.ParseParams) ([]models.PriceResult, error) {
 	var apiResp apiResponse
 	if err := json.Unmarshal(body, &apiResp); err != nil {
-		return nil, fmt.Errorf("parsing response JSON: %w", err)
+		return nil, &api.ParseError{Err: fmt.Errorf("decoding JSON: %w", err)}
+	}
+	currency, err := parseCurrency(apiResp.Currency)
+	if err != nil {
+		return nil, &api.ParseError{Err: err}
 	}
 
-	fetchedAt := time.Now()
 	results := make([]models.PriceResult, 0, len(apiResp.Values))
+	metadata := &models.ProviderMetadata{
+		Unit:        strings.TrimSpace(apiResp.ChartUnit),
+		ProductName: strings.TrimSpace(apiResp.ProductName),
+		ChartName:   strings.TrimSpace(apiResp.ChartName),
+		Currency:    strings.TrimSpace(apiResp.Currency),
+	}
 
 	for _, v := range apiResp.Values {
-		// Convert milliseconds timestamp to time.Time
-		priceDate := time.Unix(v.Date/1000, 0).UTC()
+		// Data points without a value have no price, they are not stored as 0
+		if !v.Value.valid {
+			continue
+		}
+
+		// Convert milliseconds timestamp to time.Time.
+		// The timestamp is when HeizOel24 recorded the data point, its day is the price date.
+		publishedAt := time.UnixMilli(v.Date).UTC()
+		priceDate := api.PriceDate(publishedAt, params.DateLocation)
 
 		results = append(results, models.PriceResult{
 			Date:         priceDate,
-			PricePer100L: v.Value,
-			Currency:     "EUR",
+			PublishedAt:  &publishedAt,
+			PricePer100L: v.Value.value,
+			Currency:     currency,
 			Provider:     ProviderName,
 			ProductType:  ProductType,
 			Scope:        models.PriceScopeNational,
 			ZipCode:      "",
+			Region:       params.RegionOrDefault(),
+			Metadata:     metadata,
 			RawResponse:  body,
-			FetchedAt:    fetchedAt,
+			FetchedAt:    params.FetchedAt,
 		})
 	}
 
-	p.logger.Info().
-		Int("count", len(results)).
-		Str("from", fromStr).
-		Str("to", toStr).
-		Msg("fetched prices from HeizOel24")
-
 	return results, nil
 }
+
+// parseCurrency returns the ISO 4217 code of the currency of a response.
+// Responses without a currency are prices in euros, as the German API has always sent them.
+func parseCurrency(currency string) (string, error) {
+	switch strings.ToUpper(strings.TrimSpace(currency)) {
+	case "", "EUR", "€":
+		return Currency, nil
+	default:
+		return "", fmt.Errorf("unsupported currency %q (expected %s)", currency, Currency)
+	}
+}
@@ -0,0 +1,53 @@
+package heizoel24
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// number is a JSON number that also accepts numbers encoded as strings
+// (e.g., "97.81" or "9.781e1") and null. Decoding numbers explicitly
+// keeps a format change of the API from silently turning prices into 0.
+type number struct {
+	value float64
+	// valid is false if the JSON value was null or an empty string.
+	valid bool
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface.
+func (n *number) UnmarshalJSON(data []byte) error {
+	*n = number{}
+
+	data = bytes.TrimSpace(data)
+	if bytes.Equal(data, []byte("null")) {
+		return nil
+	}
+
+	var raw json.Number
+	if data[0] == '"' {
+		var s string
+		if err := json.Unmarshal(data, &s); err != nil {
+			return fmt.Errorf("decoding number string: %w", err)
+		}
+		s = strings.TrimSpace(s)
+		if s == "" {
+			return nil
+		}
+		raw = json.Number(s)
+	} else if err := json.Unmarshal(data, &raw); err != nil {
+		return fmt.Errorf("invalid number %s: %w", data, err)
+	}
+
+	value, err := strconv.ParseFloat(raw.String(), 64)
+	if err != nil || math.IsNaN(value) || math.IsInf(value, 0) {
+		return fmt.Errorf("invalid number %s", data)
+	}
+
+	n.value = value
+	n.valid = true
+	return nil
+}
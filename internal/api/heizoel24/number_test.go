@@ -0,0 +1,66 @@
+package heizoel24
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestNumberUnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		name      string
+		input     string
+		wantValue float64
+		wantValid bool
+		wantErr   bool
+	}{
+		{name: "plain number", input: `97.81`, wantValue: 97.81, wantValid: true},
+		{name: "plain integer", input: `98`, wantValue: 98, wantValid: true},
+		{name: "plain exponent", input: `9.781e1`, wantValue: 97.81, wantValid: true},
+		{name: "quoted number", input: `"97.81"`, wantValue: 97.81, wantValid: true},
+		{name: "quoted exponent", input: `"9.781e1"`, wantValue: 97.81, wantValid: true},
+		{name: "quoted number with spaces", input: `" 97.81 "`, wantValue: 97.81, wantValid: true},
+		{name: "null", input: `null`},
+		{name: "empty string", input: `""`},
+		{name: "blank string", input: `"   "`},
+		{name: "invalid string", input: `"n/a"`, wantErr: true},
+		{name: "decimal comma", input: `"97,81"`, wantErr: true},
+		{name: "NaN string", input: `"NaN"`, wantErr: true},
+		{name: "infinity string", input: `"Inf"`, wantErr: true},
+		{name: "boolean", input: `true`, wantErr: true},
+		{name: "object", input: `{}`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got struct {
+				Value number `json:"value"`
+			}
+			err := json.Unmarshal([]byte(`{"value":`+tt.input+`}`), &got)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected an error, got value %v (valid %v)", got.Value.value, got.Value.valid)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got.Value.valid != tt.wantValid {
+				t.Errorf("valid = %v, want %v", got.Value.valid, tt.wantValid)
+			}
+			if got.Value.value != tt.wantValue {
+				t.Errorf("value = %v, want %v", got.Value.value, tt.wantValue)
+			}
+		})
+	}
+}
+
+func TestNumberUnmarshalJSONResetsValue(t *testing.T) {
+	n := number{value: 97.81, valid: true}
+	if err := json.Unmarshal([]byte(`null`), &n); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if n.valid || n.value != 0 {
+		t.Errorf("got %+v, want the zero value", n)
+	}
+}
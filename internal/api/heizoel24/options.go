@@ -0,0 +1,86 @@
+package heizoel24
+
+import (
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Option configures a Provider.
+type Option func(*Provider)
+
+// WithRegion sets the country/region prices are fetched for (e.g., "DE").
+// Other regions require a countryId query parameter set with WithQuery.
+func WithRegion(region string) Option {
+	return func(p *Provider) {
+		p.region = strings.ToUpper(region)
+	}
+}
+
+// WithBaseURL sets the API endpoint. Empty keeps DefaultBaseURL.
+func WithBaseURL(baseURL string) Option {
+	return func(p *Provider) {
+		if baseURL != "" {
+			p.baseURL = baseURL
+		}
+	}
+}
+
+// WithFallbackURL sets an API endpoint that is tried if a request to the base URL fails.
+func WithFallbackURL(fallbackURL string) Option {
+	return func(p *Provider) {
+		p.fallbackURL = fallbackURL
+	}
+}
+
+// WithDateLocation sets the time zone whose calendar day is the price date (see api.PriceDate).
+// Nil keeps UTC.
+func WithDateLocation(loc *time.Location) Option {
+	return func(p *Provider) {
+		p.dateLocation = loc
+	}
+}
+
+// WithTimeout sets the per-request budget (see api.RequestOptions.Timeout).
+func WithTimeout(timeout time.Duration) Option {
+	return func(p *Provider) {
+		p.requestOptions.Timeout = timeout
+	}
+}
+
+// WithHeaders sets extra HTTP headers applied to every request.
+func WithHeaders(headers map[string]string) Option {
+	return func(p *Provider) {
+		p.requestOptions.Headers = headers
+	}
+}
+
+// WithCookies sets extra HTTP cookies applied to every request.
+func WithCookies(cookies map[string]string) Option {
+	return func(p *Provider) {
+		p.requestOptions.Cookies = cookies
+	}
+}
+
+// WithAcceptLanguage sets the Accept-Language header sent with every request.
+func WithAcceptLanguage(acceptLanguage string) Option {
+	return func(p *Provider) {
+		p.requestOptions.AcceptLanguage = acceptLanguage
+	}
+}
+
+// WithQuery sets extra URL query parameters applied to every request.
+// They override query parameters set by the provider.
+func WithQuery(query map[string]string) Option {
+	return func(p *Provider) {
+		p.requestOptions.Query = query
+	}
+}
+
+// WithTransport sets the HTTP transport used for requests.
+// This allows injecting canned responses (see api.FixtureTransport).
+func WithTransport(transport http.RoundTripper) Option {
+	return func(p *Provider) {
+		p.transport = transport
+	}
+}
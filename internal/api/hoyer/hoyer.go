@@ -5,12 +5,13 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"io"
 	"net/http"
+	"regexp"
 	"strconv"
 	"strings"
 	"time"
 
+	"github.com/andygrunwald/oil-price-scraper/internal/api"
 	"github.com/andygrunwald/oil-price-scraper/internal/models"
 	"github.com/andygrunwald/oil-price-scraper/internal/useragent"
 	"github.com/rs/zerolog"
@@ -19,8 +20,10 @@ import (
 const (
 	// ProviderName is the identifier for this provider.
 	ProviderName = "hoyer"
-	// baseURL is the API endpoint for Hoyer.
-	baseURL = "https://api.hoyer.de/rest/heatingoil"
+	// DefaultBaseURL is the API endpoint for Hoyer.
+	DefaultBaseURL = "https://api.hoyer.de/rest/heatingoil"
+	// DefaultOrderAmount is the default order amount in liters.
+	DefaultOrderAmount = 3000
 )
 
 // apiResponse represents the JSON response from Hoyer API.
@@ -62,22 +65,38 @@ type settings struct {
 
 // Provider implements the API provider interface for Hoyer.
 type Provider struct {
-	client      *http.Client
-	logger      zerolog.Logger
-	zipCode     string
-	orderAmount int
+	api.BaseProvider
+
+	client         *http.Client
+	transport      http.RoundTripper
+	logger         zerolog.Logger
+	zipCode        string
+	dateLocation   *time.Location
+	orderAmount    int
+	baseURL        string
+	fallbackURL    string
+	requestOptions api.RequestOptions
 }
 
 // New creates a new Hoyer provider.
-func New(logger zerolog.Logger, zipCode string, orderAmount int) *Provider {
-	return &Provider{
-		client: &http.Client{
-			Timeout: 30 * time.Second,
-		},
+// Without options, prices are fetched for DefaultOrderAmount liters; a zip code
+// should always be set with WithZipCode.
+func New(logger zerolog.Logger, opts ...Option) *Provider {
+	p := &Provider{
 		logger:      logger.With().Str("provider", ProviderName).Logger(),
-		zipCode:     zipCode,
-		orderAmount: orderAmount,
+		orderAmount: DefaultOrderAmount,
+		baseURL:     DefaultBaseURL,
+	}
+	for _, opt := range opts {
+		opt(p)
 	}
+
+	p.client = &http.Client{
+		Timeout:   p.requestOptions.RequestTimeout(),
+		Transport: p.transport,
+	}
+
+	return p
 }
 
 // Name returns the provider identifier.
@@ -85,18 +104,40 @@ func (p *Provider) Name() string {
 	return ProviderName
 }
 
-// SupportsBackfill returns false as Hoyer does not support historical data.
-func (p *Provider) SupportsBackfill() bool {
-	return false
+// Capabilities returns the capabilities of Hoyer: zip code specific prices of
+// several products in Germany, without historical data.
+func (p *Provider) Capabilities() api.Capabilities {
+	return api.Capabilities{
+		MultipleProducts: true,
+		Scope:            models.PriceScopeLocal,
+		Regions:          []string{api.DefaultRegion},
+	}
 }
 
-// PriceScope returns local as Hoyer provides zip code specific prices.
-func (p *Provider) PriceScope() models.PriceScope {
-	return models.PriceScopeLocal
+// Metadata returns the provider metadata.
+// Every response must contain at least one product,
+// and a scrape only succeeds with at least one valid product price.
+// A response lists the products of a single zip code, so it is small.
+func (p *Provider) Metadata() api.Metadata {
+	return api.Metadata{
+		MinResults:      1,
+		CurrentPrice:    api.CurrentPriceToday,
+		Success:         api.RequireValidPrices(1),
+		MaxResponseSize: 2 << 20,
+	}
 }
 
 // FetchCurrentPrices fetches current prices from Hoyer for all available products.
+// If the base URL fails, the fallback URL is tried (see WithFallbackURL).
 func (p *Provider) FetchCurrentPrices(ctx context.Context) ([]models.PriceResult, error) {
+	return api.FetchWithFallback(ctx, p.baseURL, p.fallbackURL, p.logger, p.fetch)
+}
+
+// fetch fetches current prices from the Hoyer API at baseURL.
+func (p *Provider) fetch(ctx context.Context, baseURL string) ([]models.PriceResult, error) {
+	ctx, cancel := p.requestOptions.WithDeadline(ctx)
+	defer cancel()
+
 	// Hoyer API: /rest/heatingoil/<PLZ>/<Menge>/<Abladestellen>
 	url := fmt.Sprintf("%s/%s/%d/1", baseURL, p.zipCode, p.orderAmount)
 
@@ -114,6 +155,7 @@ func (p *Provider) FetchCurrentPrices(ctx context.Context) ([]models.PriceResult
 	// Hoyer requires a browser-like User-Agent
 	req.Header.Set("User-Agent", useragent.Random())
 	req.Header.Set("Accept", "application/json")
+	p.requestOptions.Apply(req)
 
 	resp, err := p.client.Do(req)
 	if err != nil {
@@ -125,33 +167,77 @@ func (p *Provider) FetchCurrentPrices(ctx context.Context) ([]models.PriceResult
 		}
 	}()
 
+	if resp.StatusCode == http.StatusNotModified {
+		return nil, api.ErrNotModified
+	}
 	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("unexpected status code %d: %s", resp.StatusCode, string(body))
+		return nil, api.NewStatusError(resp)
 	}
 
-	body, err := io.ReadAll(resp.Body)
+	body, err := api.ReadBody(resp.Body, p.Metadata().ResponseSizeLimit())
 	if err != nil {
 		return nil, fmt.Errorf("reading response body: %w", err)
 	}
 
+	results, err := p.ParseRawResponse(body, api.ParseParams{FetchedAt: time.Now(), ZipCode: p.zipCode, DateLocation: p.dateLocation})
+	if err != nil {
+		return nil, err
+	}
+
+	snapshot := api.NewResponseSnapshot(resp)
+	for i := range results {
+		results[i].SourceURL = p.requestOptions.SourceURL(req)
+		results[i].ResponseSnapshot = snapshot
+	}
+
+	p.logger.Info().
+		Int("productCount", len(results)).
+		Str("baseURL", baseURL).
+		Str("zipCode", p.zipCode).
+		Msg("fetched prices from Hoyer")
+
+	return results, nil
+}
+
+// ParseRawResponse derives prices from a raw Hoyer API response.
+// Products with an unparsable price are skipped with a warning.
+func (p *Provider) ParseRawResponse(body []byte, params api.ParseParams) ([]models.PriceResult, error) {
+	results, skipped, err := parseResponse(body, params)
+	for _, prod := range skipped {
+		p.logger.Warn().
+			Str("productName", prod.Name).
+			Str("priceGross", prod.Prices.PriceGross).
+			Msg("failed to parse priceGross, skipping product")
+	}
+	return results, err
+}
+
+// ParseResponse derives prices from a raw Hoyer API response.
+// As Hoyer only provides current prices, the price date is the day the response was fetched.
+// Products with an unparsable price are skipped. It does not perform any I/O, so it can
+// be used with stored responses and fixtures. Invalid responses return an *api.ParseError.
+func ParseResponse(body []byte, params api.ParseParams) ([]models.PriceResult, error) {
+	results, _, err := parseResponse(body, params)
+	return results, err
+}
+
+// parseResponse derives prices from a raw Hoyer API response and
+// additionally returns the products that were skipped.
+func parseResponse(body []byte, params api.ParseParams) ([]models.PriceResult, []product, error) {
 	var apiResp apiResponse
 	if err := json.Unmarshal(body, &apiResp); err != nil {
-		return nil, fmt.Errorf("parsing response JSON: %w", err)
+		return nil, nil, &api.ParseError{Err: fmt.Errorf("decoding JSON: %w", err)}
 	}
 
-	fetchedAt := time.Now()
-	today := time.Now().Truncate(24 * time.Hour)
+	today := api.PriceDate(params.FetchedAt, params.DateLocation)
 	results := make([]models.PriceResult, 0, len(apiResp.Products))
+	var skipped []product
 
 	for _, prod := range apiResp.Products {
 		// Parse the gross price (with taxes) from German format
 		pricePer100L, ok := parseGermanPrice(prod.Prices.PriceGross)
 		if !ok {
-			p.logger.Warn().
-				Str("productName", prod.Name).
-				Str("priceGross", prod.Prices.PriceGross).
-				Msg("failed to parse priceGross, skipping product")
+			skipped = append(skipped, prod)
 			continue
 		}
 
@@ -159,29 +245,34 @@ func (p *Provider) FetchCurrentPrices(ctx context.Context) ([]models.PriceResult
 		productType := normalizeProductType(prod.Name)
 
 		results = append(results, models.PriceResult{
-			Date:         today,
-			PricePer100L: pricePer100L,
-			Currency:     "EUR",
-			Provider:     ProviderName,
-			ProductType:  productType,
-			Scope:        models.PriceScopeLocal,
-			ZipCode:      p.zipCode,
-			RawResponse:  body,
-			FetchedAt:    fetchedAt,
+			Date:             today,
+			PricePer100L:     pricePer100L,
+			Currency:         "EUR",
+			Provider:         ProviderName,
+			ProductType:      productType,
+			Scope:            models.PriceScopeLocal,
+			ZipCode:          params.ZipCode,
+			Region:           params.RegionOrDefault(),
+			DeliveryDays:     deliveryDays(prod),
+			DeliveryTimeType: strings.TrimSpace(prod.DeliveryTimeType),
+			IsPremium:        &prod.IsPremium,
+			IsClimateNeutral: &prod.IsClimateNeutral,
+			RawResponse:      body,
+			FetchedAt:        params.FetchedAt,
 		})
 	}
 
-	p.logger.Info().
-		Int("productCount", len(results)).
-		Str("zipCode", p.zipCode).
-		Msg("fetched prices from Hoyer")
-
-	return results, nil
+	return results, skipped, nil
 }
 
-// FetchHistoricalPrices returns an error as Hoyer does not support historical data.
-func (p *Provider) FetchHistoricalPrices(ctx context.Context, from, to time.Time) ([]models.PriceResult, error) {
-	return nil, fmt.Errorf("hoyer does not support historical data")
+// deliveryDays returns the delivery time of a product in days, or nil if the
+// response does not state one (missing or zero days).
+func deliveryDays(prod product) *int {
+	if prod.Days <= 0 {
+		return nil
+	}
+	days := prod.Days
+	return &days
 }
 
 // normalizeProductType converts product names to consistent lowercase identifiers.
@@ -196,11 +287,30 @@ func normalizeProductType(name string) string {
 	return normalized
 }
 
-// parseGermanPrice converts a German-formatted price string (e.g., "90,99") to float64.
+// germanThousands matches numbers using dots as thousands separators without decimals (e.g., "1.234").
+var germanThousands = regexp.MustCompile(`^[-+]?\d{1,3}(\.\d{3})+$`)
+
+// parseGermanPrice converts a German-formatted price string to float64.
+// It supports a decimal comma and dots as thousands separators
+// (e.g., "90,99", "1.234,56", "-3,50", "1.234"). Plain numbers with a
+// decimal dot (e.g., "90.99") are accepted as well. A number without comma
+// whose dots are all followed by exactly three digits is ambiguous and read
+// as German thousands: "99.999" is 99999, not 99.999.
 // Returns the parsed value and true on success, or 0 and false on failure.
 func parseGermanPrice(s string) (float64, bool) {
-	// Replace German decimal comma with dot
-	normalized := strings.ReplaceAll(s, ",", ".")
+	normalized := strings.TrimSpace(s)
+	normalized = strings.TrimSpace(strings.TrimSuffix(normalized, "€"))
+
+	switch {
+	case strings.Contains(normalized, ","):
+		// German format: dots separate thousands, the comma separates decimals
+		normalized = strings.ReplaceAll(normalized, ".", "")
+		normalized = strings.ReplaceAll(normalized, ",", ".")
+	case germanThousands.MatchString(normalized):
+		// Dots as thousands separators without decimals
+		normalized = strings.ReplaceAll(normalized, ".", "")
+	}
+
 	value, err := strconv.ParseFloat(normalized, 64)
 	if err != nil {
 		return 0, false
@@ -0,0 +1,52 @@
+package hoyer
+
+import "testing"
+
+func TestParseGermanPrice(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  string
+		want   float64
+		wantOK bool
+	}{
+		// Decimal comma
+		{name: "decimal comma", input: "90,99", want: 90.99, wantOK: true},
+		{name: "single decimal", input: "90,9", want: 90.9, wantOK: true},
+		{name: "negative decimal comma", input: "-3,50", want: -3.5, wantOK: true},
+		{name: "euro sign", input: "90,99 €", want: 90.99, wantOK: true},
+		{name: "surrounding spaces", input: "  90,99  ", want: 90.99, wantOK: true},
+
+		// Thousands separators
+		{name: "thousands and decimal comma", input: "1.234,56", want: 1234.56, wantOK: true},
+		{name: "several thousands separators", input: "1.234.567,89", want: 1234567.89, wantOK: true},
+		{name: "thousands without decimals", input: "1.234", want: 1234, wantOK: true},
+		{name: "negative thousands", input: "-1.234", want: -1234, wantOK: true},
+
+		// Without separators or with a decimal dot
+		{name: "missing decimals", input: "91", want: 91, wantOK: true},
+		{name: "decimal dot", input: "90.99", want: 90.99, wantOK: true},
+		{name: "decimal dot with four digits", input: "90.9999", want: 90.9999, wantOK: true},
+
+		// A dot followed by exactly three digits is a thousands separator
+		{name: "ambiguous three digits after dot", input: "99.999", want: 99999, wantOK: true},
+		{name: "ambiguous with comma", input: "99,999", want: 99.999, wantOK: true},
+
+		// Invalid
+		{name: "empty", input: "", wantOK: false},
+		{name: "euro sign only", input: "€", wantOK: false},
+		{name: "text", input: "n/a", wantOK: false},
+		{name: "two commas", input: "1,2,3", wantOK: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := parseGermanPrice(tt.input)
+			if ok != tt.wantOK {
+				t.Fatalf("parseGermanPrice(%q) ok = %v, want %v", tt.input, ok, tt.wantOK)
+			}
+			if got != tt.want {
+				t.Errorf("parseGermanPrice(%q) = %v, want %v", tt.input, got, tt.want)
+			}
+		})
+	}
+}
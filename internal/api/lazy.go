@@ -0,0 +1,73 @@
+package api
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/andygrunwald/oil-price-scraper/internal/models"
+)
+
+// Lazy is a provider that is created on first use, e.g., to build its HTTP client
+// from configuration that may change at runtime. Reset discards the created provider,
+// so the next use creates it again from the configuration at that time.
+// It is safe for concurrent use: requests in flight during a Reset finish with the
+// provider they started with.
+type Lazy struct {
+	name        string
+	newProvider func() Provider
+
+	mu       sync.Mutex
+	provider Provider
+}
+
+// NewLazy returns a provider named name that is created by newProvider on first use.
+// newProvider must return a provider with the same name.
+func NewLazy(name string, newProvider func() Provider) *Lazy {
+	return &Lazy{
+		name:        name,
+		newProvider: newProvider,
+	}
+}
+
+// Name returns the provider identifier without creating the provider.
+func (l *Lazy) Name() string {
+	return l.name
+}
+
+// Provider returns the current provider, creating it if necessary.
+func (l *Lazy) Provider() Provider {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.provider == nil {
+		l.provider = l.newProvider()
+	}
+	return l.provider
+}
+
+// Reset discards the current provider. The next use creates a new one.
+func (l *Lazy) Reset() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.provider = nil
+}
+
+// FetchCurrentPrices fetches today's prices from the current provider.
+func (l *Lazy) FetchCurrentPrices(ctx context.Context) ([]models.PriceResult, error) {
+	return l.Provider().FetchCurrentPrices(ctx)
+}
+
+// FetchHistoricalPrices fetches prices for a date range from the current provider.
+func (l *Lazy) FetchHistoricalPrices(ctx context.Context, from, to time.Time) ([]models.PriceResult, error) {
+	return l.Provider().FetchHistoricalPrices(ctx, from, to)
+}
+
+// Capabilities returns the capabilities of the current provider.
+func (l *Lazy) Capabilities() Capabilities {
+	return l.Provider().Capabilities()
+}
+
+// Metadata returns the metadata of the current provider.
+func (l *Lazy) Metadata() Metadata {
+	return MetadataOf(l.Provider())
+}
@@ -0,0 +1,172 @@
+// Package mock provides a deterministic provider that does not call any API.
+// It exercises the scraper, scheduler, metrics, and database offline
+// (e.g., for end-to-end tests and demos).
+package mock
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/andygrunwald/oil-price-scraper/internal/api"
+	"github.com/andygrunwald/oil-price-scraper/internal/models"
+)
+
+const (
+	// ProviderName is the identifier for this provider.
+	ProviderName = "mock"
+	// ProductType is the product type of all mock prices.
+	ProductType = "standard"
+	// sourceURL is reported as source of all mock prices.
+	sourceURL = "mock://prices"
+)
+
+// Failure modes of the mock provider, named after the error reasons (see api.ErrorReason).
+const (
+	// FailNone returns prices.
+	FailNone = ""
+	// FailTransport fails like a network error.
+	FailTransport = api.ErrorReasonTransport
+	// FailTimeout fails like a request exceeding its deadline.
+	FailTimeout = api.ErrorReasonTimeout
+	// FailStatus fails like an HTTP 503 response.
+	FailStatus = api.ErrorReasonStatus
+	// FailRateLimit fails like an HTTP 429 response.
+	FailRateLimit = api.ErrorReasonRateLimit
+	// FailParse fails like an unparsable response.
+	FailParse = api.ErrorReasonParse
+)
+
+// Provider implements the API provider interface with deterministic prices.
+// The price of a day only depends on the date, so repeated runs produce the same data.
+type Provider struct {
+	logger  zerolog.Logger
+	delay   time.Duration
+	failure string
+}
+
+// New creates a new mock provider.
+func New(logger zerolog.Logger, opts ...Option) *Provider {
+	p := &Provider{
+		logger: logger.With().Str("provider", ProviderName).Logger(),
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+// Name returns the provider identifier.
+func (p *Provider) Name() string {
+	return ProviderName
+}
+
+// Capabilities returns the capabilities of the mock provider: national prices
+// that exist for every day.
+func (p *Provider) Capabilities() api.Capabilities {
+	return api.Capabilities{
+		Backfill: true,
+		Scope:    models.PriceScopeNational,
+	}
+}
+
+// FetchCurrentPrices returns today's mock price.
+func (p *Provider) FetchCurrentPrices(ctx context.Context) ([]models.PriceResult, error) {
+	now := time.Now()
+	return p.FetchHistoricalPrices(ctx, now, now)
+}
+
+// FetchHistoricalPrices returns one mock price per day between from and to (inclusive).
+func (p *Provider) FetchHistoricalPrices(ctx context.Context, from, to time.Time) ([]models.PriceResult, error) {
+	if err := p.wait(ctx); err != nil {
+		return nil, fmt.Errorf("executing request: %w", err)
+	}
+	if err := p.fail(); err != nil {
+		return nil, err
+	}
+
+	fetchedAt := time.Now()
+	from = time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, time.UTC)
+	to = time.Date(to.Year(), to.Month(), to.Day(), 0, 0, 0, 0, time.UTC)
+
+	var results []models.PriceResult
+	for day := from; !day.After(to); day = day.AddDate(0, 0, 1) {
+		results = append(results, models.PriceResult{
+			Date:         day,
+			PricePer100L: Price(day),
+			Currency:     "EUR",
+			Provider:     ProviderName,
+			ProductType:  ProductType,
+			Scope:        models.PriceScopeNational,
+			Region:       api.DefaultRegion,
+			SourceURL:    sourceURL,
+			FetchedAt:    fetchedAt,
+		})
+	}
+
+	// Serve a raw response like real providers do, so it can be stored and reprocessed
+	body, err := json.Marshal(results)
+	if err != nil {
+		return nil, fmt.Errorf("encoding response: %w", err)
+	}
+	for i := range results {
+		results[i].RawResponse = body
+	}
+
+	p.logger.Info().
+		Int("count", len(results)).
+		Str("from", from.Format("2006-01-02")).
+		Str("to", to.Format("2006-01-02")).
+		Msg("generated mock prices")
+
+	return results, nil
+}
+
+// Price returns the deterministic mock price of a day. It cycles between
+// 90 and 103 EUR per 100 liters over two weeks.
+func Price(day time.Time) float64 {
+	days := day.UTC().Unix() / int64(24*time.Hour/time.Second)
+	return 90 + float64(days%14)
+}
+
+// wait delays the request, honoring the context.
+func (p *Provider) wait(ctx context.Context) error {
+	if p.delay <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(p.delay)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// fail returns the error of the configured failure mode.
+func (p *Provider) fail() error {
+	switch p.failure {
+	case FailNone:
+		return nil
+	case FailTransport:
+		return fmt.Errorf("executing request: %w", &url.Error{Op: "Get", URL: sourceURL, Err: errors.New("connection refused")})
+	case FailTimeout:
+		return fmt.Errorf("executing request: %w", &url.Error{Op: "Get", URL: sourceURL, Err: context.DeadlineExceeded})
+	case FailStatus:
+		return &api.StatusError{StatusCode: 503, Body: "service unavailable"}
+	case FailRateLimit:
+		return &api.RateLimitError{RetryAfter: time.Minute}
+	case FailParse:
+		return &api.ParseError{Err: errors.New("decoding JSON: unexpected end of JSON input")}
+	default:
+		return fmt.Errorf("mock failure %q", p.failure)
+	}
+}
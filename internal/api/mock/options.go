@@ -0,0 +1,21 @@
+package mock
+
+import "time"
+
+// Option configures a Provider.
+type Option func(*Provider)
+
+// WithDelay delays every request by the given duration (e.g., to exercise timeouts).
+func WithDelay(delay time.Duration) Option {
+	return func(p *Provider) {
+		p.delay = delay
+	}
+}
+
+// WithFailure makes every request fail with the given failure mode (e.g., FailTransport).
+// FailNone returns prices.
+func WithFailure(failure string) Option {
+	return func(p *Provider) {
+		p.failure = failure
+	}
+}
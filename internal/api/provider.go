@@ -3,6 +3,11 @@ package api
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
 	"time"
 
 	"github.com/andygrunwald/oil-price-scraper/internal/models"
@@ -19,9 +24,427 @@ type Provider interface {
 	// FetchHistoricalPrices fetches prices for a date range (if supported).
 	FetchHistoricalPrices(ctx context.Context, from, to time.Time) ([]models.PriceResult, error)
 
-	// SupportsBackfill returns true if the provider supports historical data.
-	SupportsBackfill() bool
+	// Capabilities describes what the provider supports.
+	Capabilities() Capabilities
+}
+
+// Capabilities describes what a provider supports. New capabilities are added
+// as fields whose zero value means "not supported", so existing providers keep
+// compiling and report them as unsupported.
+type Capabilities struct {
+	// Backfill is true if FetchHistoricalPrices returns historical data.
+	Backfill bool
+	// MultipleProducts is true if a response contains prices of several product types.
+	MultipleProducts bool
+	// Scope is whether prices are local (zip code) or nationwide.
+	Scope models.PriceScope
+	// Regions lists the supported countries/regions (e.g., "DE"). Empty means any.
+	Regions []string
+}
+
+// SupportsRegion returns true if prices can be fetched for region.
+func (c Capabilities) SupportsRegion(region string) bool {
+	if len(c.Regions) == 0 {
+		return true
+	}
+	for _, r := range c.Regions {
+		if strings.EqualFold(r, region) {
+			return true
+		}
+	}
+	return false
+}
+
+// ErrBackfillNotSupported is returned by FetchHistoricalPrices of providers without historical data.
+var ErrBackfillNotSupported = errors.New("historical data not supported")
+
+// BaseProvider provides defaults for optional parts of the Provider interface.
+// Providers embed it and override what they support.
+type BaseProvider struct{}
+
+// Capabilities returns no capabilities.
+func (BaseProvider) Capabilities() Capabilities {
+	return Capabilities{}
+}
+
+// FetchHistoricalPrices returns ErrBackfillNotSupported.
+func (BaseProvider) FetchHistoricalPrices(ctx context.Context, from, to time.Time) ([]models.PriceResult, error) {
+	return nil, ErrBackfillNotSupported
+}
+
+// Metadata describes static properties of a provider.
+type Metadata struct {
+	// MinResults is the minimum number of prices a successful response must contain.
+	// Responses with fewer prices (e.g., an empty JSON object with HTTP 200)
+	// are treated as errors instead of silently storing nothing.
+	MinResults int
+	// CurrentPrice decides which stored price represents the current price.
+	// Empty means CurrentPriceLatest.
+	CurrentPrice CurrentPricePolicy
+	// Success decides whether a fetch of current prices counts as a successful scrape
+	// (see CheckSuccess). Nil accepts every response with at least MinResults prices.
+	Success SuccessCriterion
+	// MaxResponseSize is the maximum size of a response body in bytes; larger responses
+	// fail (see ReadBody). Memory for raw responses is reserved with it before a request.
+	// Zero means DefaultMaxResponseSize.
+	MaxResponseSize int64
+}
+
+// DefaultMaxResponseSize is the maximum size of a response body of providers that
+// do not set Metadata.MaxResponseSize.
+const DefaultMaxResponseSize = 8 << 20
+
+// ResponseSizeLimit returns MaxResponseSize or DefaultMaxResponseSize.
+func (m Metadata) ResponseSizeLimit() int64 {
+	if m.MaxResponseSize <= 0 {
+		return DefaultMaxResponseSize
+	}
+	return m.MaxResponseSize
+}
+
+// DefaultMetadata is used for providers that do not implement MetadataProvider.
+var DefaultMetadata = Metadata{
+	MinResults:      1,
+	CurrentPrice:    CurrentPriceLatest,
+	Success:         RequireValidPrices(1),
+	MaxResponseSize: DefaultMaxResponseSize,
+}
+
+// SuccessCriterion returns an error if prices, fetched at now, do not count as a
+// successful scrape of current prices.
+type SuccessCriterion func(prices []models.PriceResult, now time.Time) error
+
+// RequireValidPrices requires at least n prices with a positive price per 100 liters.
+// It fits local providers, where any quoted product is a successful scrape.
+func RequireValidPrices(n int) SuccessCriterion {
+	return func(prices []models.PriceResult, now time.Time) error {
+		valid := 0
+		for _, price := range prices {
+			if price.PricePer100L > 0 {
+				valid++
+			}
+		}
+		if valid < n {
+			return fmt.Errorf("expected at least %d valid prices, got %d", n, valid)
+		}
+		return nil
+	}
+}
+
+// RequireRecentPrice requires at least one price with a positive price per 100 liters
+// dated at most maxAgeDays days before the calendar day of now (see PriceDate).
+// It fits national providers that publish prices with a delay.
+func RequireRecentPrice(maxAgeDays int) SuccessCriterion {
+	return func(prices []models.PriceResult, now time.Time) error {
+		oldest := PriceDate(now, now.Location()).AddDate(0, 0, -maxAgeDays)
+		for _, price := range prices {
+			if price.PricePer100L > 0 && !price.Date.Before(oldest) {
+				return nil
+			}
+		}
+		return fmt.Errorf("expected a valid price dated %s or later", oldest.Format("2006-01-02"))
+	}
+}
+
+// CurrentPricePolicy decides which price of a provider is its current price.
+type CurrentPricePolicy string
+
+const (
+	// CurrentPriceLatest uses the latest published price, whatever its date.
+	// This fits national providers that publish prices with a delay.
+	CurrentPriceLatest CurrentPricePolicy = "latest"
+	// CurrentPriceToday uses only today's price. This fits local providers
+	// that quote a price for the day of the request.
+	CurrentPriceToday CurrentPricePolicy = "today"
+)
+
+// ParseCurrentPricePolicy parses a policy name.
+func ParseCurrentPricePolicy(value string) (CurrentPricePolicy, error) {
+	switch policy := CurrentPricePolicy(value); policy {
+	case CurrentPriceLatest, CurrentPriceToday:
+		return policy, nil
+	default:
+		return "", fmt.Errorf("unknown current price policy %q (expected %q or %q)", value, CurrentPriceLatest, CurrentPriceToday)
+	}
+}
+
+// CurrentPrices returns the prices that are current according to policy at now,
+// at most one per series (product type, zip code, region). Today is the calendar
+// day of now in its location (see PriceDate).
+func CurrentPrices(policy CurrentPricePolicy, prices []models.PriceResult, now time.Time) []models.PriceResult {
+	today := PriceDate(now, now.Location()).Format("2006-01-02")
+
+	type seriesKey struct {
+		productType string
+		zipCode     string
+		region      string
+	}
+	latest := make(map[seriesKey]int)
+	var order []seriesKey
+	for i, price := range prices {
+		if policy == CurrentPriceToday && price.Date.UTC().Format("2006-01-02") != today {
+			continue
+		}
+		key := seriesKey{price.ProductType, price.ZipCode, price.Region}
+		j, ok := latest[key]
+		if !ok {
+			order = append(order, key)
+		}
+		if !ok || price.Date.After(prices[j].Date) {
+			latest[key] = i
+		}
+	}
+
+	current := make([]models.PriceResult, 0, len(order))
+	for _, key := range order {
+		current = append(current, prices[latest[key]])
+	}
+	return current
+}
+
+// Headline strategies select the price of a response that represents the provider,
+// e.g., the last price in /status. Any other strategy names a product type.
+const (
+	// HeadlineFirst selects the first price of the response.
+	HeadlineFirst = "first"
+	// HeadlineCheapest selects the cheapest price of the response.
+	HeadlineCheapest = "cheapest"
+)
+
+// HeadlinePrice returns the price of prices selected by strategy (HeadlineFirst,
+// HeadlineCheapest, or a product type). An empty strategy means HeadlineFirst.
+// It returns false if prices is empty or no price has the named product type.
+// Callers pass the current prices (see CurrentPrices), so the headline is never
+// an older price of the response.
+func HeadlinePrice(strategy string, prices []models.PriceResult) (models.PriceResult, bool) {
+	if len(prices) == 0 {
+		return models.PriceResult{}, false
+	}
+
+	switch strategy {
+	case "", HeadlineFirst:
+		return prices[0], true
+	case HeadlineCheapest:
+		cheapest := prices[0]
+		for _, price := range prices[1:] {
+			if price.PricePer100L < cheapest.PricePer100L {
+				cheapest = price
+			}
+		}
+		return cheapest, true
+	default:
+		for _, price := range prices {
+			if strings.EqualFold(price.ProductType, strategy) {
+				return price, true
+			}
+		}
+		return models.PriceResult{}, false
+	}
+}
+
+// MetadataProvider is implemented by providers that describe their own Metadata.
+type MetadataProvider interface {
+	// Metadata returns the provider metadata.
+	Metadata() Metadata
+}
+
+// MetadataOf returns the metadata of a provider or DefaultMetadata.
+func MetadataOf(p Provider) Metadata {
+	if mp, ok := p.(MetadataProvider); ok {
+		return mp.Metadata()
+	}
+	return DefaultMetadata
+}
+
+// ErrTooFewResults indicates that a response contained fewer prices than the
+// provider's Metadata.MinResults.
+var ErrTooFewResults = errors.New("too few results")
+
+// CheckResults asserts that prices satisfy the minimums of the provider metadata.
+// Violations return an *ParseError wrapping ErrTooFewResults.
+func CheckResults(p Provider, prices []models.PriceResult) error {
+	minResults := MetadataOf(p).MinResults
+	if len(prices) < minResults {
+		return &ParseError{Err: fmt.Errorf("%w: expected at least %d, got %d", ErrTooFewResults, minResults, len(prices))}
+	}
+	return nil
+}
+
+// ErrUnsuccessful indicates that a response did not meet the provider's
+// Metadata.Success criterion.
+var ErrUnsuccessful = errors.New("unsuccessful scrape")
+
+// CheckSuccess asserts that prices, fetched at now, meet the success criterion of the
+// provider metadata. Violations return an error wrapping ErrUnsuccessful.
+func CheckSuccess(p Provider, prices []models.PriceResult, now time.Time) error {
+	success := MetadataOf(p).Success
+	if success == nil {
+		return nil
+	}
+	if err := success(prices, now); err != nil {
+		return fmt.Errorf("%w: %w", ErrUnsuccessful, err)
+	}
+	return nil
+}
+
+// ParseParams holds the context of an API response needed to derive prices from it.
+type ParseParams struct {
+	// FetchedAt is when the response was fetched.
+	FetchedAt time.Time
+	// ZipCode is the zip code the response was fetched for (local providers only).
+	ZipCode string
+	// Region is the country/region the response was fetched for (e.g., "DE").
+	// Empty means DefaultRegion.
+	Region string
+	// DateLocation is the time zone whose calendar day is the price date
+	// (see PriceDate). Nil means UTC.
+	DateLocation *time.Location
+}
+
+// PriceDate returns the calendar day of t in loc as midnight UTC, the canonical form of
+// a price date. All providers derive their price dates with it, so prices of the same
+// calendar day share the same price_date regardless of how a provider reports time.
+// A nil loc means UTC.
+func PriceDate(t time.Time, loc *time.Location) time.Time {
+	if loc == nil {
+		loc = time.UTC
+	}
+	t = t.In(loc)
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// RegionOrDefault returns the configured region or DefaultRegion.
+func (p ParseParams) RegionOrDefault() string {
+	if p.Region == "" {
+		return DefaultRegion
+	}
+	return p.Region
+}
+
+// ResponseParser is implemented by providers that can derive prices from a raw
+// API response without a network request (e.g., to reprocess stored responses).
+type ResponseParser interface {
+	// ParseRawResponse derives prices from a raw API response.
+	ParseRawResponse(body []byte, params ParseParams) ([]models.PriceResult, error)
+}
+
+// ParseError indicates that a provider response could not be parsed,
+// as opposed to transport or HTTP status errors.
+type ParseError struct {
+	Err error
+}
+
+// Error implements the error interface.
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("parsing response: %v", e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// DefaultRequestTimeout is the per-request budget used if none is configured.
+const DefaultRequestTimeout = 30 * time.Second
+
+const (
+	// DefaultRegion is the country/region prices are fetched for if none is configured.
+	DefaultRegion = "DE"
+	// DefaultAcceptLanguage is the Accept-Language header sent if none is configured.
+	DefaultAcceptLanguage = "de-DE,de;q=0.9"
+)
+
+// RequestOptions holds additional settings applied to every provider request.
+type RequestOptions struct {
+	// Timeout is the authoritative per-request budget. Both the HTTP client
+	// timeout and the request context deadline derive from it.
+	// Zero means DefaultRequestTimeout.
+	Timeout time.Duration
+	// Headers are extra HTTP headers (e.g., Referer, Origin).
+	Headers map[string]string
+	// Cookies are extra HTTP cookies (e.g., a session cookie).
+	Cookies map[string]string
+	// AcceptLanguage is the Accept-Language header. Empty means DefaultAcceptLanguage.
+	AcceptLanguage string
+	// Query are extra URL query parameters (e.g., a country or region parameter).
+	// They override query parameters set by the provider.
+	Query map[string]string
+}
+
+// Apply sets the configured Accept-Language header, query parameters, headers,
+// and cookies on the request. Headers override previously set values with
+// the same name, including the Accept-Language header. Credentials are not part of
+// the options; they are added by the transport (see NewCredentialsTransport).
+func (o RequestOptions) Apply(req *http.Request) {
+	acceptLanguage := o.AcceptLanguage
+	if acceptLanguage == "" {
+		acceptLanguage = DefaultAcceptLanguage
+	}
+	req.Header.Set("Accept-Language", acceptLanguage)
+
+	if len(o.Query) > 0 {
+		query := req.URL.Query()
+		for name, value := range o.Query {
+			query.Set(name, value)
+		}
+		req.URL.RawQuery = query.Encode()
+	}
+
+	for name, value := range o.Headers {
+		req.Header.Set(name, value)
+	}
+	for name, value := range o.Cookies {
+		req.AddCookie(&http.Cookie{Name: name, Value: value})
+	}
+}
+
+// SourceURL returns the URL of the request as stored with prices, with the password
+// of the URL's user info redacted. Credentials never appear in it, as they are only
+// added by the transport (see NewCredentialsTransport).
+func (o RequestOptions) SourceURL(req *http.Request) string {
+	return req.URL.Redacted()
+}
+
+// RequestTimeout returns the configured per-request budget or DefaultRequestTimeout.
+func (o RequestOptions) RequestTimeout() time.Duration {
+	if o.Timeout <= 0 {
+		return DefaultRequestTimeout
+	}
+	return o.Timeout
+}
+
+// WithDeadline returns a context bounded by the per-request budget.
+// An earlier deadline or cancellation of the parent context still takes precedence.
+func (o RequestOptions) WithDeadline(ctx context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(ctx, o.RequestTimeout())
+}
+
+// NewResponseSnapshot captures the status code and headers of an HTTP response.
+// Multiple values of the same header are joined with ", ".
+func NewResponseSnapshot(resp *http.Response) *models.ResponseSnapshot {
+	headers := make(map[string]string, len(resp.Header))
+	for name, values := range resp.Header {
+		headers[name] = strings.Join(values, ", ")
+	}
+	return &models.ResponseSnapshot{
+		StatusCode: resp.StatusCode,
+		Headers:    headers,
+	}
+}
+
+// ErrResponseTooLarge indicates that a response body exceeded the provider's
+// Metadata.MaxResponseSize.
+var ErrResponseTooLarge = errors.New("response too large")
 
-	// PriceScope returns whether the price is local (zip code) or nationwide.
-	PriceScope() models.PriceScope
+// ReadBody reads a response body of at most limit bytes. Larger bodies are not read
+// further and return an *ParseError wrapping ErrResponseTooLarge.
+func ReadBody(r io.Reader, limit int64) ([]byte, error) {
+	body, err := io.ReadAll(io.LimitReader(r, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(body)) > limit {
+		return nil, &ParseError{Err: fmt.Errorf("%w: exceeds %d bytes", ErrResponseTooLarge, limit)}
+	}
+	return body, nil
 }
@@ -0,0 +1,58 @@
+package api
+
+import (
+	"testing"
+	"time"
+	_ "time/tzdata" // Europe/Berlin without relying on the system's zoneinfo
+)
+
+func TestPriceDate(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Fatalf("loading Europe/Berlin: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		t    time.Time
+		loc  *time.Location
+		want string
+	}{
+		// Winter time (CET, UTC+1)
+		{name: "23:00 Berlin", t: time.Date(2024, 1, 15, 23, 0, 0, 0, berlin), loc: berlin, want: "2024-01-15"},
+		{name: "23:59 Berlin", t: time.Date(2024, 1, 15, 23, 59, 59, 0, berlin), loc: berlin, want: "2024-01-15"},
+		{name: "00:30 Berlin is the previous day in UTC", t: time.Date(2024, 1, 16, 0, 30, 0, 0, berlin), loc: berlin, want: "2024-01-16"},
+		{name: "23:30 UTC is the next day in Berlin", t: time.Date(2024, 1, 15, 23, 30, 0, 0, time.UTC), loc: berlin, want: "2024-01-16"},
+		{name: "23:30 UTC in UTC", t: time.Date(2024, 1, 15, 23, 30, 0, 0, time.UTC), loc: time.UTC, want: "2024-01-15"},
+
+		// Summer time starts on 2024-03-31 at 02:00 CET (UTC+1 -> UTC+2)
+		{name: "00:30 Berlin before the switch to summer time", t: time.Date(2024, 3, 31, 0, 30, 0, 0, berlin), loc: berlin, want: "2024-03-31"},
+		{name: "23:00 Berlin after the switch to summer time", t: time.Date(2024, 3, 31, 23, 0, 0, 0, berlin), loc: berlin, want: "2024-03-31"},
+		{name: "22:30 UTC after the switch to summer time", t: time.Date(2024, 3, 31, 22, 30, 0, 0, time.UTC), loc: berlin, want: "2024-04-01"},
+		{name: "21:59 UTC after the switch to summer time", t: time.Date(2024, 3, 31, 21, 59, 0, 0, time.UTC), loc: berlin, want: "2024-03-31"},
+
+		// Summer time ends on 2024-10-27 at 03:00 CEST (UTC+2 -> UTC+1)
+		{name: "00:30 Berlin before the switch to winter time", t: time.Date(2024, 10, 27, 0, 30, 0, 0, berlin), loc: berlin, want: "2024-10-27"},
+		{name: "22:30 UTC before the switch to winter time", t: time.Date(2024, 10, 26, 22, 30, 0, 0, time.UTC), loc: berlin, want: "2024-10-27"},
+		{name: "23:00 Berlin after the switch to winter time", t: time.Date(2024, 10, 27, 23, 0, 0, 0, berlin), loc: berlin, want: "2024-10-27"},
+		{name: "22:59 UTC after the switch to winter time", t: time.Date(2024, 10, 27, 22, 59, 0, 0, time.UTC), loc: berlin, want: "2024-10-27"},
+		{name: "23:00 UTC after the switch to winter time", t: time.Date(2024, 10, 27, 23, 0, 0, 0, time.UTC), loc: berlin, want: "2024-10-28"},
+
+		{name: "nil location is UTC", t: time.Date(2024, 1, 15, 23, 30, 0, 0, berlin), loc: nil, want: "2024-01-15"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := PriceDate(tt.t, tt.loc)
+			if got.Format("2006-01-02") != tt.want {
+				t.Errorf("PriceDate(%s, %v) = %s, want %s", tt.t.Format(time.RFC3339), tt.loc, got.Format("2006-01-02"), tt.want)
+			}
+			if got.Location() != time.UTC {
+				t.Errorf("PriceDate(%s, %v) is in %v, want UTC", tt.t.Format(time.RFC3339), tt.loc, got.Location())
+			}
+			if h, m, s := got.Clock(); h != 0 || m != 0 || s != 0 || got.Nanosecond() != 0 {
+				t.Errorf("PriceDate(%s, %v) = %s, want midnight", tt.t.Format(time.RFC3339), tt.loc, got.Format(time.RFC3339Nano))
+			}
+		})
+	}
+}
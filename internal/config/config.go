@@ -2,7 +2,10 @@
 package config
 
 import (
+	"errors"
+	"fmt"
 	"os"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -12,24 +15,128 @@ import (
 type Config struct {
 	// PostgreSQL connection string
 	PostgresDSN string
+	// Tenant whose tables live in the PostgreSQL schema of the same name (empty uses the default schema)
+	Tenant string
+	// Tenants served by one run process (tenant -> zip code), each in its own schema
+	Tenants map[string]string
 	// Log level (debug, info, warn, error)
 	LogLevel string
 	// Log format (json, console)
 	LogFormat string
 	// Store raw API responses in database
 	StoreRawResponse bool
+	// Store HTTP status and response headers with raw API responses
+	StoreResponseHeaders bool
+	// Store raw API responses gzip-compressed
+	CompressRawResponse bool
+	// Maximum total size of raw API responses held in memory at the same time in MiB (0 is unlimited)
+	MaxRawResponseMemory int
 	// HTTP server address
 	HTTPAddr string
+	// Apply pending database schema migrations after connecting
+	AutoMigrate bool
+	// Bearer token required by the authenticated HTTP endpoints (empty disables them)
+	APIToken string
 	// Zip code for local price APIs
 	ZipCode string
 	// Order amount in liters
 	OrderAmount int
 	// Scrape hour (0-23)
 	ScrapeHour int
+	// Time zone whose calendar day is the price date of all providers (IANA name)
+	PriceTimezone string
 	// Enabled providers
 	Providers []string
+	// Fail on unknown providers instead of skipping them
+	StrictProviders bool
+	// Create providers on first use instead of at startup
+	LazyProviders bool
+	// Named provider groups usable as @name in provider lists
+	ProviderGroups ProviderGroups
+	// Per-request budget for provider API calls
+	RequestTimeout time.Duration
+	// Send conditional requests (If-None-Match/If-Modified-Since) based on the last response
+	ConditionalRequests bool
+	// Per-request budget per provider overriding RequestTimeout (provider -> duration, e.g. "10s")
+	ProviderTimeouts map[string]string
+	// Credentials per provider (provider -> type:name:key, see api.ParseCredentials)
+	ProviderCredentials map[string]string
+	// Proxy URLs requests to provider APIs are rotated through
+	Proxies []string
+	// Proxy rotation strategy (round-robin, random)
+	ProxyRotation string
+	// PEM file with CA certificates trusted for provider requests in addition to the system pool
+	CAFile string
+	// Skip TLS certificate verification of provider requests (testing only)
+	InsecureSkipVerify bool
+	// Saved responses served instead of calling the provider API (provider -> file)
+	ProviderFixtures map[string]string
+	// Directory every scraped raw response is written to (empty disables recording)
+	RecordResponses string
+	// Extra HTTP headers per provider
+	ProviderHeaders ProviderValues
+	// Extra HTTP cookies per provider
+	ProviderCookies ProviderValues
+	// Country/region per provider (provider -> region, e.g. "AT")
+	ProviderRegions map[string]string
+	// Accept-Language header per provider
+	ProviderAcceptLanguages map[string]string
+	// Extra URL query parameters per provider
+	ProviderQuery ProviderValues
+	// API base URL per provider overriding the built-in endpoint
+	ProviderBaseURLs map[string]string
+	// API base URL per provider tried if the base URL fails
+	ProviderFallbackURLs map[string]string
+	// Prometheus Pushgateway URL metrics of one-off runs are pushed to
+	PushgatewayURL string
+	// Job name used when pushing metrics
+	PushJob string
+	// OTLP/HTTP endpoint traces are exported to (empty disables tracing)
+	OTelEndpoint string
+	// Current price policy per provider (provider -> "latest" or "today")
+	CurrentPricePolicies map[string]string
+	// Price representing a provider per provider (provider -> first, cheapest, or a product type)
+	HeadlineProducts map[string]string
+	// Provider whose headline price is the headline price of the service (empty is the first registered provider)
+	PrimaryProvider string
+	// Webhook URL notified when a provider fails
+	FailureWebhookURL string
+	// Webhook URL a summary of the current prices and their trends is posted to after every scrape
+	SummaryWebhookURL string
+	// Webhook URL price alerts are posted to
+	AlertWebhookURL string
+	// Price per 100 liters below which an alert is sent
+	AlertThreshold float64
+	// Margin above the threshold a price must recover to before alerting again
+	AlertHysteresis float64
+	// Re-alert while the price stays below the threshold after this duration (0 disables)
+	AlertCooldown time.Duration
+	// Write an audit log of price inserts, updates, skips, and deletes
+	Audit bool
+	// Keep prices whose insert failed in the dead-letter table
+	DeadLetter bool
+	// Product types to store per provider (empty stores all)
+	ProductTypes ProductTypes
+	// Transformers applied to prices before storing, in order (see transform.Parse)
+	Transformers []string
+	// Sinks every scraped price is written to in addition to the database (see sink.Parse)
+	Sinks []string
+	// Providers of which only the newest price per series of a scrape is stored
+	StoreLatestOnly []string
 	// Backfill settings
 	Backfill BackfillConfig
+	// Mock provider settings (for tests and demos)
+	MockProvider MockProviderConfig
+}
+
+// MockProviderConfig holds configuration for the built-in mock provider.
+type MockProviderConfig struct {
+	// Enable the "mock" provider
+	Enabled bool
+	// Delay of every request
+	Delay time.Duration
+	// Failure mode (transport, http_status, rate_limit, parse; empty returns prices)
+	Failure string
 }
 
 // BackfillConfig holds configuration for backfilling historical data.
@@ -57,7 +164,16 @@ func DefaultConfig() *Config {
 		ZipCode:          "",
 		OrderAmount:      3000,
 		ScrapeHour:       6,
+		PriceTimezone:    "UTC",
 		Providers:        []string{"heizoel24", "hoyer"},
+		RequestTimeout:   30 * time.Second,
+		ProxyRotation:    "round-robin",
+		PushJob:          "oilscraper",
+		ProviderGroups:   ProviderGroups{},
+		ProviderHeaders:  ProviderValues{},
+		ProviderCookies:  ProviderValues{},
+		ProviderQuery:    ProviderValues{},
+		ProductTypes:     ProductTypes{},
 		Backfill: BackfillConfig{
 			Provider: "heizoel24",
 			MinDelay: 1,
@@ -67,10 +183,22 @@ func DefaultConfig() *Config {
 }
 
 // LoadFromEnv loads configuration from environment variables.
-func (c *Config) LoadFromEnv() {
+// Invalid values are not applied; their errors are returned together.
+func (c *Config) LoadFromEnv() error {
+	var errs []error
 	if v := os.Getenv("POSTGRES_DSN"); v != "" {
 		c.PostgresDSN = v
 	}
+	if v := os.Getenv("TENANT"); v != "" {
+		c.Tenant = v
+	}
+	if v := os.Getenv("TENANTS"); v != "" {
+		if values, err := parseKeyValues(v); err == nil {
+			c.Tenants = values
+		} else {
+			errs = append(errs, fmt.Errorf("invalid TENANTS: %w", err))
+		}
+	}
 	if v := os.Getenv("LOG_LEVEL"); v != "" {
 		c.LogLevel = v
 	}
@@ -78,25 +206,480 @@ func (c *Config) LoadFromEnv() {
 		c.LogFormat = v
 	}
 	if v := os.Getenv("STORE_RAW_RESPONSE"); v != "" {
-		c.StoreRawResponse = strings.ToLower(v) == "true"
+		if b, err := strconv.ParseBool(v); err == nil {
+			c.StoreRawResponse = b
+		} else {
+			errs = append(errs, envError("STORE_RAW_RESPONSE", v, err))
+		}
+	}
+	if v := os.Getenv("STORE_RESPONSE_HEADERS"); v != "" {
+		if b, err := strconv.ParseBool(v); err == nil {
+			c.StoreResponseHeaders = b
+		} else {
+			errs = append(errs, envError("STORE_RESPONSE_HEADERS", v, err))
+		}
+	}
+	if v := os.Getenv("RECORD_RESPONSES"); v != "" {
+		c.RecordResponses = v
 	}
-	if v := os.Getenv("HTTP_ADDR"); v != "" {
+	if v := os.Getenv("COMPRESS_RAW_RESPONSE"); v != "" {
+		if b, err := strconv.ParseBool(v); err == nil {
+			c.CompressRawResponse = b
+		} else {
+			errs = append(errs, envError("COMPRESS_RAW_RESPONSE", v, err))
+		}
+	}
+	if v := os.Getenv("MAX_RAW_RESPONSE_MEMORY"); v != "" {
+		if i, err := strconv.Atoi(v); err == nil {
+			c.MaxRawResponseMemory = i
+		} else {
+			errs = append(errs, envError("MAX_RAW_RESPONSE_MEMORY", v, err))
+		}
+	}
+	// An empty HTTP_ADDR disables the HTTP server
+	if v, ok := os.LookupEnv("HTTP_ADDR"); ok {
 		c.HTTPAddr = v
 	}
+	if v := os.Getenv("AUTO_MIGRATE"); v != "" {
+		if b, err := strconv.ParseBool(v); err == nil {
+			c.AutoMigrate = b
+		} else {
+			errs = append(errs, envError("AUTO_MIGRATE", v, err))
+		}
+	}
+	if v := os.Getenv("API_TOKEN"); v != "" {
+		c.APIToken = v
+	}
 	if v := os.Getenv("ZIP_CODE"); v != "" {
 		c.ZipCode = v
 	}
 	if v := os.Getenv("ORDER_AMOUNT"); v != "" {
 		if i, err := strconv.Atoi(v); err == nil {
 			c.OrderAmount = i
+		} else {
+			errs = append(errs, envError("ORDER_AMOUNT", v, err))
 		}
 	}
+	if v := os.Getenv("PRICE_TIMEZONE"); v != "" {
+		c.PriceTimezone = v
+	}
 	if v := os.Getenv("SCRAPE_HOUR"); v != "" {
-		if i, err := strconv.Atoi(v); err == nil && i >= 0 && i <= 23 {
+		i, err := strconv.Atoi(v)
+		if err == nil && (i < 0 || i > 23) {
+			err = errors.New("must be between 0 and 23")
+		}
+		if err == nil {
 			c.ScrapeHour = i
+		} else {
+			errs = append(errs, envError("SCRAPE_HOUR", v, err))
 		}
 	}
 	if v := os.Getenv("PROVIDERS"); v != "" {
 		c.Providers = strings.Split(v, ",")
 	}
+	if v := os.Getenv("STRICT_PROVIDERS"); v != "" {
+		if b, err := strconv.ParseBool(v); err == nil {
+			c.StrictProviders = b
+		} else {
+			errs = append(errs, envError("STRICT_PROVIDERS", v, err))
+		}
+	}
+	if v := os.Getenv("LAZY_PROVIDERS"); v != "" {
+		if b, err := strconv.ParseBool(v); err == nil {
+			c.LazyProviders = b
+		} else {
+			errs = append(errs, envError("LAZY_PROVIDERS", v, err))
+		}
+	}
+	if v := os.Getenv("PROVIDER_GROUPS"); v != "" {
+		for _, entry := range strings.Split(v, ";") {
+			if strings.TrimSpace(entry) == "" {
+				continue
+			}
+			if err := c.ProviderGroups.Set(entry); err != nil {
+				errs = append(errs, fmt.Errorf("invalid PROVIDER_GROUPS: %w", err))
+			}
+		}
+	}
+	if v := os.Getenv("REQUEST_TIMEOUT"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil {
+			c.RequestTimeout = d
+		} else {
+			errs = append(errs, envError("REQUEST_TIMEOUT", v, err))
+		}
+	}
+	if v := os.Getenv("CONDITIONAL_REQUESTS"); v != "" {
+		if b, err := strconv.ParseBool(v); err == nil {
+			c.ConditionalRequests = b
+		} else {
+			errs = append(errs, envError("CONDITIONAL_REQUESTS", v, err))
+		}
+	}
+	if v := os.Getenv("PROVIDER_TIMEOUTS"); v != "" {
+		if values, err := parseKeyValues(v); err == nil {
+			c.ProviderTimeouts = values
+		} else {
+			errs = append(errs, fmt.Errorf("invalid PROVIDER_TIMEOUTS: %w", err))
+		}
+	}
+	if v := os.Getenv("PROVIDER_CREDENTIALS"); v != "" {
+		if values, err := parseKeyValues(v); err == nil {
+			c.ProviderCredentials = values
+		} else {
+			errs = append(errs, fmt.Errorf("invalid PROVIDER_CREDENTIALS: %w", err))
+		}
+	}
+	if v := os.Getenv("PROXIES"); v != "" {
+		c.Proxies = strings.Split(v, ",")
+	}
+	if v := os.Getenv("PROXY_ROTATION"); v != "" {
+		c.ProxyRotation = v
+	}
+	if v := os.Getenv("CA_FILE"); v != "" {
+		c.CAFile = v
+	}
+	if v := os.Getenv("INSECURE_SKIP_VERIFY"); v != "" {
+		if b, err := strconv.ParseBool(v); err == nil {
+			c.InsecureSkipVerify = b
+		} else {
+			errs = append(errs, envError("INSECURE_SKIP_VERIFY", v, err))
+		}
+	}
+	for _, entry := range envEntries("PROVIDER_HEADERS") {
+		if err := c.ProviderHeaders.Set(entry); err != nil {
+			errs = append(errs, fmt.Errorf("invalid PROVIDER_HEADERS: %w", err))
+		}
+	}
+	for _, entry := range envEntries("PROVIDER_COOKIES") {
+		if err := c.ProviderCookies.Set(entry); err != nil {
+			errs = append(errs, fmt.Errorf("invalid PROVIDER_COOKIES: %w", err))
+		}
+	}
+	if v := os.Getenv("PUSHGATEWAY_URL"); v != "" {
+		c.PushgatewayURL = v
+	}
+	if v := os.Getenv("PUSH_JOB"); v != "" {
+		c.PushJob = v
+	}
+	if v := os.Getenv("OTEL_ENDPOINT"); v != "" {
+		c.OTelEndpoint = v
+	}
+	if v := os.Getenv("FAILURE_WEBHOOK_URL"); v != "" {
+		c.FailureWebhookURL = v
+	}
+	if v := os.Getenv("SUMMARY_WEBHOOK_URL"); v != "" {
+		c.SummaryWebhookURL = v
+	}
+	if v := os.Getenv("ALERT_WEBHOOK_URL"); v != "" {
+		c.AlertWebhookURL = v
+	}
+	if v := os.Getenv("ALERT_THRESHOLD"); v != "" {
+		if f, err := strconv.ParseFloat(v, 64); err == nil {
+			c.AlertThreshold = f
+		} else {
+			errs = append(errs, envError("ALERT_THRESHOLD", v, err))
+		}
+	}
+	if v := os.Getenv("ALERT_HYSTERESIS"); v != "" {
+		if f, err := strconv.ParseFloat(v, 64); err == nil {
+			c.AlertHysteresis = f
+		} else {
+			errs = append(errs, envError("ALERT_HYSTERESIS", v, err))
+		}
+	}
+	if v := os.Getenv("ALERT_COOLDOWN"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil {
+			c.AlertCooldown = d
+		} else {
+			errs = append(errs, envError("ALERT_COOLDOWN", v, err))
+		}
+	}
+	if v := os.Getenv("AUDIT"); v != "" {
+		if b, err := strconv.ParseBool(v); err == nil {
+			c.Audit = b
+		} else {
+			errs = append(errs, envError("AUDIT", v, err))
+		}
+	}
+	if v := os.Getenv("DEAD_LETTER"); v != "" {
+		if b, err := strconv.ParseBool(v); err == nil {
+			c.DeadLetter = b
+		} else {
+			errs = append(errs, envError("DEAD_LETTER", v, err))
+		}
+	}
+	if v := os.Getenv("PRODUCT_TYPES"); v != "" {
+		for _, entry := range strings.Split(v, ";") {
+			if strings.TrimSpace(entry) == "" {
+				continue
+			}
+			if err := c.ProductTypes.Set(entry); err != nil {
+				errs = append(errs, fmt.Errorf("invalid PRODUCT_TYPES: %w", err))
+			}
+		}
+	}
+	if v := os.Getenv("TRANSFORMERS"); v != "" {
+		c.Transformers = strings.Split(v, ",")
+	}
+	if v := os.Getenv("SINKS"); v != "" {
+		c.Sinks = strings.Split(v, ",")
+	}
+	if v := os.Getenv("STORE_LATEST_ONLY"); v != "" {
+		c.StoreLatestOnly = strings.Split(v, ",")
+	}
+	if v := os.Getenv("PROVIDER_REGIONS"); v != "" {
+		if values, err := parseKeyValues(v); err == nil {
+			c.ProviderRegions = values
+		} else {
+			errs = append(errs, fmt.Errorf("invalid PROVIDER_REGIONS: %w", err))
+		}
+	}
+	if v := os.Getenv("PROVIDER_ACCEPT_LANGUAGES"); v != "" {
+		if values, err := parseKeyValues(v); err == nil {
+			c.ProviderAcceptLanguages = values
+		} else {
+			errs = append(errs, fmt.Errorf("invalid PROVIDER_ACCEPT_LANGUAGES: %w", err))
+		}
+	}
+	if v := os.Getenv("PROVIDER_BASE_URLS"); v != "" {
+		if values, err := parseKeyValues(v); err == nil {
+			c.ProviderBaseURLs = values
+		} else {
+			errs = append(errs, fmt.Errorf("invalid PROVIDER_BASE_URLS: %w", err))
+		}
+	}
+	if v := os.Getenv("PROVIDER_FALLBACK_URLS"); v != "" {
+		if values, err := parseKeyValues(v); err == nil {
+			c.ProviderFallbackURLs = values
+		} else {
+			errs = append(errs, fmt.Errorf("invalid PROVIDER_FALLBACK_URLS: %w", err))
+		}
+	}
+	if v := os.Getenv("CURRENT_PRICE_POLICIES"); v != "" {
+		if values, err := parseKeyValues(v); err == nil {
+			c.CurrentPricePolicies = values
+		} else {
+			errs = append(errs, fmt.Errorf("invalid CURRENT_PRICE_POLICIES: %w", err))
+		}
+	}
+	if v := os.Getenv("HEADLINE_PRODUCTS"); v != "" {
+		if values, err := parseKeyValues(v); err == nil {
+			c.HeadlineProducts = values
+		} else {
+			errs = append(errs, fmt.Errorf("invalid HEADLINE_PRODUCTS: %w", err))
+		}
+	}
+	if v := os.Getenv("PRIMARY_PROVIDER"); v != "" {
+		c.PrimaryProvider = v
+	}
+	for _, entry := range envEntries("PROVIDER_QUERY") {
+		if err := c.ProviderQuery.Set(entry); err != nil {
+			errs = append(errs, fmt.Errorf("invalid PROVIDER_QUERY: %w", err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// ProviderTimeout returns the per-request budget of a provider:
+// its --provider-timeout if set and valid, RequestTimeout otherwise.
+func (c *Config) ProviderTimeout(provider string) time.Duration {
+	if value, ok := c.ProviderTimeouts[provider]; ok {
+		if d, err := time.ParseDuration(value); err == nil && d > 0 {
+			return d
+		}
+	}
+	return c.RequestTimeout
+}
+
+// PriceLocation returns the time zone whose calendar day is the price date.
+// An invalid time zone (rejected by Validate) falls back to UTC.
+func (c *Config) PriceLocation() *time.Location {
+	loc, err := time.LoadLocation(c.PriceTimezone)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// envError returns the error of an invalid value of an environment variable.
+func envError(name, value string, err error) error {
+	// The value is part of the message already
+	var numErr *strconv.NumError
+	if errors.As(err, &numErr) {
+		err = numErr.Err
+	}
+	return fmt.Errorf("invalid %s %q: %w", name, value, err)
+}
+
+// envEntries returns the entries of a repeatable environment variable: the lines of name,
+// followed by the values of name_1, name_2, ... up to the first one that is not set.
+// Entries are never split on commas or semicolons, as values may contain them
+// (e.g., "hoyer:Accept=text/html, application/json;q=0.9"). Empty entries are ignored.
+func envEntries(name string) []string {
+	var entries []string
+	add := func(value string) {
+		for entry := range strings.SplitSeq(value, "\n") {
+			if entry = strings.TrimSpace(entry); entry != "" {
+				entries = append(entries, entry)
+			}
+		}
+	}
+
+	add(os.Getenv(name))
+	for i := 1; ; i++ {
+		value, ok := os.LookupEnv(fmt.Sprintf("%s_%d", name, i))
+		if !ok {
+			break
+		}
+		add(value)
+	}
+	return entries
+}
+
+// parseKeyValues parses entries in the format "key=value" separated by ";".
+// Empty entries are ignored. Invalid entries are reported by position only,
+// as values may be secrets (e.g., credentials).
+func parseKeyValues(s string) (map[string]string, error) {
+	values := make(map[string]string)
+	for i, entry := range strings.Split(s, ";") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(entry, "=")
+		if !ok || key == "" {
+			return nil, fmt.Errorf("entry %d is not in the format key=value", i+1)
+		}
+		values[key] = value
+	}
+	return values, nil
+}
+
+// ProviderValues holds key/value pairs per provider (provider -> key -> value).
+// It implements the pflag.Value interface, so it can be used as a repeatable flag
+// in the format "provider:key=value".
+type ProviderValues map[string]map[string]string
+
+// Set parses and adds an entry in the format "provider:key=value".
+func (v ProviderValues) Set(entry string) error {
+	provider, pair, ok := strings.Cut(strings.TrimSpace(entry), ":")
+	if !ok || provider == "" {
+		return fmt.Errorf("invalid entry %q, expected provider:key=value", entry)
+	}
+	key, value, ok := strings.Cut(pair, "=")
+	if !ok || key == "" {
+		return fmt.Errorf("invalid entry %q, expected provider:key=value", entry)
+	}
+
+	if v[provider] == nil {
+		v[provider] = make(map[string]string)
+	}
+	v[provider][key] = value
+	return nil
+}
+
+// String returns the entries in the format accepted by Set.
+func (v ProviderValues) String() string {
+	entries := make([]string, 0)
+	for provider, values := range v {
+		for key, value := range values {
+			entries = append(entries, provider+":"+key+"="+value)
+		}
+	}
+	sort.Strings(entries)
+	return strings.Join(entries, ",")
+}
+
+// Type returns the flag type name.
+func (v ProviderValues) Type() string {
+	return "provider:key=value"
+}
+
+// For returns the key/value pairs configured for a provider.
+func (v ProviderValues) For(provider string) map[string]string {
+	return v[provider]
+}
+
+// ProviderGroups holds named groups of providers (group -> provider names).
+// It implements the pflag.Value interface, so it can be used as a repeatable flag
+// in the format "group=provider1,provider2".
+type ProviderGroups map[string][]string
+
+// Set parses and adds a group in the format "group=provider1,provider2".
+func (g ProviderGroups) Set(entry string) error {
+	name, members, ok := strings.Cut(strings.TrimSpace(entry), "=")
+	if !ok || name == "" {
+		return fmt.Errorf("invalid group %q, expected group=provider1,provider2", entry)
+	}
+
+	var providers []string
+	for _, p := range strings.Split(members, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			providers = append(providers, p)
+		}
+	}
+	if len(providers) == 0 {
+		return fmt.Errorf("invalid group %q, no providers given", entry)
+	}
+
+	g[name] = providers
+	return nil
+}
+
+// String returns the groups in the format accepted by Set.
+func (g ProviderGroups) String() string {
+	entries := make([]string, 0, len(g))
+	for name, providers := range g {
+		entries = append(entries, name+"="+strings.Join(providers, ","))
+	}
+	sort.Strings(entries)
+	return strings.Join(entries, ";")
+}
+
+// Type returns the flag type name.
+func (g ProviderGroups) Type() string {
+	return "group=providers"
+}
+
+// ProductTypes holds the product types to store per provider (provider -> product types).
+// It implements the pflag.Value interface, so it can be used as a repeatable flag
+// in the format "provider=type1,type2".
+type ProductTypes map[string][]string
+
+// Set parses and adds an entry in the format "provider=type1,type2".
+// Product types are matched against the normalized (lowercase) product type.
+func (t ProductTypes) Set(entry string) error {
+	provider, list, ok := strings.Cut(strings.TrimSpace(entry), "=")
+	if !ok || provider == "" {
+		return fmt.Errorf("invalid entry %q, expected provider=type1,type2", entry)
+	}
+
+	var types []string
+	for _, productType := range strings.Split(list, ",") {
+		if productType = strings.ToLower(strings.TrimSpace(productType)); productType != "" {
+			types = append(types, productType)
+		}
+	}
+	if len(types) == 0 {
+		return fmt.Errorf("invalid entry %q, no product types given", entry)
+	}
+
+	t[provider] = types
+	return nil
+}
+
+// String returns the entries in the format accepted by Set.
+func (t ProductTypes) String() string {
+	entries := make([]string, 0, len(t))
+	for provider, types := range t {
+		entries = append(entries, provider+"="+strings.Join(types, ","))
+	}
+	sort.Strings(entries)
+	return strings.Join(entries, ";")
+}
+
+// Type returns the flag type name.
+func (t ProductTypes) Type() string {
+	return "provider=types"
 }
@@ -0,0 +1,112 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/rs/zerolog"
+
+	"github.com/andygrunwald/oil-price-scraper/internal/api"
+	"github.com/andygrunwald/oil-price-scraper/internal/database"
+	"github.com/andygrunwald/oil-price-scraper/internal/transform"
+)
+
+// zipCodePattern matches German (5 digits) and Austrian/Swiss (4 digits) zip codes.
+var zipCodePattern = regexp.MustCompile(`^[0-9]{4,5}$`)
+
+// Validate checks the configuration and returns every problem found,
+// so all of them can be reported at once. Required settings depend on
+// the command and are not checked here.
+func (c *Config) Validate() []error {
+	var errs []error
+
+	if c.PostgresDSN != "" {
+		if _, err := pgconn.ParseConfig(c.PostgresDSN); err != nil {
+			// The parse error may contain the password, so it is not included
+			errs = append(errs, fmt.Errorf("--postgres-dsn: invalid connection string"))
+		}
+	}
+	if err := database.ValidateTenant(c.Tenant); err != nil {
+		errs = append(errs, fmt.Errorf("--tenant: %w", err))
+	}
+	if c.Tenant != "" && len(c.Tenants) > 0 {
+		errs = append(errs, fmt.Errorf("--tenant and --tenants cannot be combined"))
+	}
+	for tenant, zipCode := range c.Tenants {
+		if err := database.ValidateTenant(tenant); err != nil {
+			errs = append(errs, fmt.Errorf("--tenants: %w", err))
+		}
+		if !zipCodePattern.MatchString(zipCode) {
+			errs = append(errs, fmt.Errorf("--tenants: zip code of %s must consist of 4 or 5 digits, got %q", tenant, zipCode))
+		}
+	}
+	if _, err := zerolog.ParseLevel(c.LogLevel); err != nil {
+		errs = append(errs, fmt.Errorf("--log-level: unknown level %q", c.LogLevel))
+	}
+	if c.LogFormat != "json" && c.LogFormat != "console" {
+		errs = append(errs, fmt.Errorf("--log-format: must be json or console, got %q", c.LogFormat))
+	}
+	if c.ZipCode != "" && !zipCodePattern.MatchString(c.ZipCode) {
+		errs = append(errs, fmt.Errorf("--zip-code: must consist of 4 or 5 digits, got %q", c.ZipCode))
+	}
+	if c.OrderAmount <= 0 {
+		errs = append(errs, fmt.Errorf("--order-amount: must be positive, got %d", c.OrderAmount))
+	}
+	if _, err := time.LoadLocation(c.PriceTimezone); err != nil {
+		errs = append(errs, fmt.Errorf("--price-timezone: unknown time zone %q", c.PriceTimezone))
+	}
+	if c.MaxRawResponseMemory < 0 {
+		errs = append(errs, fmt.Errorf("--max-raw-response-memory: must not be negative, got %d", c.MaxRawResponseMemory))
+	}
+	if c.RequestTimeout < 0 {
+		errs = append(errs, fmt.Errorf("--request-timeout: must not be negative, got %s", c.RequestTimeout))
+	}
+	for provider, value := range c.ProviderTimeouts {
+		if d, err := time.ParseDuration(value); err != nil || d <= 0 {
+			errs = append(errs, fmt.Errorf("--provider-timeout: invalid timeout %q for %s (expected a positive duration, e.g. 10s)", value, provider))
+		}
+	}
+	for provider, value := range c.ProviderCredentials {
+		// The value contains the key, so it is not included
+		if _, err := api.ParseCredentials(value); err != nil {
+			errs = append(errs, fmt.Errorf("--provider-credentials: %s: %w", provider, err))
+		}
+	}
+	if len(c.Proxies) > 0 {
+		if _, err := api.NewProxyRotator(c.Proxies, c.ProxyRotation, zerolog.Nop()); err != nil {
+			errs = append(errs, fmt.Errorf("--proxies: %w", err))
+		}
+	}
+	if c.CAFile != "" {
+		if _, err := api.NewTLSConfig(c.CAFile, false); err != nil {
+			errs = append(errs, fmt.Errorf("--ca-file: %w", err))
+		}
+	}
+	for provider, policy := range c.CurrentPricePolicies {
+		if policy != "latest" && policy != "today" {
+			errs = append(errs, fmt.Errorf("--current-price-policy: unknown policy %q for %s (expected latest or today)", policy, provider))
+		}
+	}
+	if _, err := transform.ParsePipeline(c.Transformers); err != nil {
+		errs = append(errs, fmt.Errorf("--transform: %w", err))
+	}
+	if c.AlertThreshold < 0 {
+		errs = append(errs, fmt.Errorf("--alert-below: must not be negative, got %v", c.AlertThreshold))
+	}
+	if c.AlertHysteresis < 0 {
+		errs = append(errs, fmt.Errorf("--alert-hysteresis: must not be negative, got %v", c.AlertHysteresis))
+	}
+	if c.AlertThreshold > 0 && c.AlertWebhookURL == "" {
+		errs = append(errs, fmt.Errorf("--alert-below: requires --alert-webhook-url"))
+	}
+
+	switch c.MockProvider.Failure {
+	case "", "transport", "timeout", "http_status", "rate_limit", "parse":
+	default:
+		errs = append(errs, fmt.Errorf("--mock-failure: unknown failure mode %q", c.MockProvider.Failure))
+	}
+
+	return errs
+}
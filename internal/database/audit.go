@@ -0,0 +1,319 @@
+package database
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/andygrunwald/oil-price-scraper/internal/models"
+)
+
+// Audit log actions.
+const (
+	// AuditActionInsert is a newly inserted price.
+	AuditActionInsert = "insert"
+	// AuditActionUpdate is a changed price of an existing record.
+	AuditActionUpdate = "update"
+	// AuditActionSkip is a price that was not written because it was already stored.
+	AuditActionSkip = "skip"
+	// AuditActionDelete is a deleted price record (e.g., by the delete command or the retention).
+	AuditActionDelete = "delete"
+)
+
+// auditLockID is the advisory lock serializing audit log writes,
+// so concurrent writers cannot fork the hash chain.
+const auditLockID = 0x6f696c5f61756474
+
+// emptyHash is the previous hash of the first audit log entry.
+var emptyHash = strings.Repeat("0", sha256.Size*2)
+
+// auditEntry is a single entry of the audit log.
+type auditEntry struct {
+	priceID     *int64
+	provider    string
+	productType string
+	priceDate   time.Time
+	zipCode     *string
+	region      string
+	action      string
+	oldPrice    *float64
+	newPrice    *float64
+	recordedAt  time.Time
+}
+
+// hash returns the SHA-256 hash of the entry chained to prevHash.
+// Prices are formatted with the precision of the price column.
+func (e auditEntry) hash(prevHash string) string {
+	formatPrice := func(p *float64) string {
+		if p == nil {
+			return ""
+		}
+		return fmt.Sprintf("%.4f", *p)
+	}
+	priceID := ""
+	if e.priceID != nil {
+		priceID = strconv.FormatInt(*e.priceID, 10)
+	}
+	zipCode := ""
+	if e.zipCode != nil {
+		zipCode = *e.zipCode
+	}
+
+	fields := []string{
+		prevHash,
+		priceID,
+		e.provider,
+		e.productType,
+		e.priceDate.Format("2006-01-02"),
+		zipCode,
+		e.region,
+		e.action,
+		formatPrice(e.oldPrice),
+		formatPrice(e.newPrice),
+		e.recordedAt.Format(time.RFC3339Nano),
+	}
+	sum := sha256.Sum256([]byte(strings.Join(fields, "|")))
+	return hex.EncodeToString(sum[:])
+}
+
+// normalize sets the recording time and rounds the entry to the precision it is
+// stored with, so its hash can be recomputed from the stored entry.
+func (e *auditEntry) normalize(recordedAt time.Time) {
+	// PostgreSQL stores timestamps with microsecond precision
+	e.recordedAt = recordedAt.UTC().Truncate(time.Microsecond)
+	e.oldPrice = roundPrice(e.oldPrice)
+	e.newPrice = roundPrice(e.newPrice)
+}
+
+// roundPrice rounds a price to the precision of the price columns,
+// so the hash of an entry can be recomputed from the stored prices.
+func roundPrice(p *float64) *float64 {
+	if p == nil {
+		return nil
+	}
+	rounded := math.Round(*p*10000) / 10000
+	return &rounded
+}
+
+// SamePrice returns true if a and b are equal at the scale of the price columns (DECIMAL(10, 4)),
+// so a price read back from the database equals the price it was written from.
+func SamePrice(a, b float64) bool {
+	return *roundPrice(&a) == *roundPrice(&b)
+}
+
+// SetAudit enables writing an append-only audit log of price inserts,
+// updates, skips, and deletes to the audit_log table.
+func (d *DB) SetAudit(enabled bool) {
+	d.audit = enabled
+}
+
+// writeAudit appends an entry to the audit log within tx.
+func (d *DB) writeAudit(ctx context.Context, tx *sql.Tx, e auditEntry) error {
+	if _, err := tx.ExecContext(ctx, "SELECT pg_advisory_xact_lock($1)", int64(auditLockID)); err != nil {
+		return fmt.Errorf("locking audit log: %w", err)
+	}
+
+	prevHash := emptyHash
+	err := tx.QueryRowContext(ctx, "SELECT hash FROM audit_log ORDER BY id DESC LIMIT 1").Scan(&prevHash)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("querying previous audit hash: %w", err)
+	}
+
+	e.normalize(time.Now())
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO audit_log (price_id, provider, product_type, price_date, zip_code, region, action, old_price, new_price, recorded_at, prev_hash, hash)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+	`,
+		e.priceID,
+		e.provider,
+		e.productType,
+		e.priceDate.Format("2006-01-02"),
+		e.zipCode,
+		e.region,
+		e.action,
+		e.oldPrice,
+		e.newPrice,
+		e.recordedAt,
+		prevHash,
+		e.hash(prevHash),
+	)
+	if err != nil {
+		return fmt.Errorf("inserting audit log entry: %w", err)
+	}
+	return nil
+}
+
+// inTx runs fn within a transaction that is committed if fn succeeds.
+func (d *DB) inTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	tx, err := d.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer func() {
+		// Rollback after a successful commit is a no-op
+		_ = tx.Rollback()
+	}()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("committing transaction: %w", err)
+	}
+	return nil
+}
+
+// AuditSkip records in the audit log that a price was not written because it
+// was already stored. It is a no-op if the audit log is disabled.
+func (d *DB) AuditSkip(ctx context.Context, price models.PriceResult) error {
+	if !d.audit {
+		return nil
+	}
+
+	entry := auditEntry{
+		provider:    price.Provider,
+		productType: price.ProductType,
+		priceDate:   price.Date,
+		zipCode:     nullableString(price.ZipCode),
+		region:      regionOrDefault(price.Region),
+		action:      AuditActionSkip,
+		newPrice:    &price.PricePer100L,
+	}
+
+	err := d.withWriteReconnect(ctx, func() error {
+		return d.inTx(ctx, func(tx *sql.Tx) error {
+			return d.writeAudit(ctx, tx, entry)
+		})
+	})
+	if err != nil {
+		return fmt.Errorf("auditing skipped price: %w", err)
+	}
+	return nil
+}
+
+// deletedPrice is a deleted price record as returned by DELETE ... RETURNING deletedPriceColumns.
+type deletedPrice struct {
+	id          int64
+	provider    string
+	productType string
+	priceDate   time.Time
+	zipCode     *string
+	region      string
+	price       float64
+}
+
+// deletedPriceColumns are the columns of a deleted price record written to the audit log.
+const deletedPriceColumns = "id, provider, product_type, price_date, zip_code, region, price_per_100l"
+
+// auditDeletes runs a DELETE ... RETURNING deletedPriceColumns within tx and records
+// every deleted record in the audit log. It returns the number of deleted records.
+func (d *DB) auditDeletes(ctx context.Context, tx *sql.Tx, query string, args ...any) (int64, error) {
+	rows, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		return 0, err
+	}
+	// The rows must be read completely before the audit entries are written on the same connection
+	var deleted []deletedPrice
+	for rows.Next() {
+		var p deletedPrice
+		if err := rows.Scan(&p.id, &p.provider, &p.productType, &p.priceDate, &p.zipCode, &p.region, &p.price); err != nil {
+			_ = rows.Close()
+			return 0, fmt.Errorf("scanning deleted price: %w", err)
+		}
+		deleted = append(deleted, p)
+	}
+	if err := rows.Close(); err != nil {
+		return 0, err
+	}
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	for _, p := range deleted {
+		err := d.writeAudit(ctx, tx, auditEntry{
+			priceID:     &p.id,
+			provider:    p.provider,
+			productType: p.productType,
+			priceDate:   p.priceDate,
+			zipCode:     p.zipCode,
+			region:      p.region,
+			action:      AuditActionDelete,
+			oldPrice:    &p.price,
+		})
+		if err != nil {
+			return 0, err
+		}
+	}
+	return int64(len(deleted)), nil
+}
+
+// AuditVerification is the result of verifying the hash chain of the audit log.
+type AuditVerification struct {
+	// Entries is the number of entries verified.
+	Entries int64
+	// BrokenID is the ID of the first entry that breaks the chain, or 0 if the chain is intact.
+	BrokenID int64
+	// Problem describes how the entry BrokenID breaks the chain.
+	Problem string
+}
+
+// VerifyAuditLog recomputes the hash chain of the audit log in the order of the entries
+// and stops at the first entry that breaks it: an entry whose prev_hash is not the hash
+// of the entry before (an entry was removed or inserted) or whose hash does not match
+// its fields (the entry was modified). Removing the newest entries cannot be detected.
+func (d *DB) VerifyAuditLog(ctx context.Context) (AuditVerification, error) {
+	var v AuditVerification
+
+	rows, err := d.queryContext(ctx, `
+		SELECT id, price_id, provider, product_type, price_date, zip_code, region, action,
+			old_price, new_price, recorded_at, prev_hash, hash
+		FROM audit_log
+		ORDER BY id
+	`)
+	if err != nil {
+		return v, fmt.Errorf("querying audit log: %w", err)
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			panic(err)
+		}
+	}()
+
+	expectedPrevHash := emptyHash
+	for rows.Next() {
+		var id int64
+		var e auditEntry
+		var prevHash, hash string
+		if err := rows.Scan(&id, &e.priceID, &e.provider, &e.productType, &e.priceDate, &e.zipCode, &e.region, &e.action,
+			&e.oldPrice, &e.newPrice, &e.recordedAt, &prevHash, &hash); err != nil {
+			return v, fmt.Errorf("scanning audit log entry: %w", err)
+		}
+		e.recordedAt = e.recordedAt.UTC()
+
+		switch {
+		case prevHash != expectedPrevHash:
+			v.BrokenID = id
+			v.Problem = "prev_hash does not match the hash of the previous entry (an entry was removed or inserted)"
+			return v, nil
+		case hash != e.hash(prevHash):
+			v.BrokenID = id
+			v.Problem = "hash does not match the entry (the entry was modified)"
+			return v, nil
+		}
+		expectedPrevHash = hash
+		v.Entries++
+	}
+	if err := rows.Err(); err != nil {
+		return v, fmt.Errorf("iterating audit log: %w", err)
+	}
+	return v, nil
+}
@@ -0,0 +1,133 @@
+package database
+
+import (
+	"strconv"
+	"testing"
+	"time"
+)
+
+// storedAuditEntry returns e as read back from the audit_log table: prices are parsed
+// from their DECIMAL(10, 4) text, and recorded_at from its TIMESTAMP text.
+func storedAuditEntry(t *testing.T, e auditEntry) auditEntry {
+	t.Helper()
+	parsePrice := func(p *float64) *float64 {
+		if p == nil {
+			return nil
+		}
+		v, err := strconv.ParseFloat(strconv.FormatFloat(*p, 'f', 4, 64), 64)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return &v
+	}
+
+	recordedAt, err := time.Parse("2006-01-02 15:04:05.999999", e.recordedAt.Format("2006-01-02 15:04:05.999999"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	e.oldPrice = parsePrice(e.oldPrice)
+	e.newPrice = parsePrice(e.newPrice)
+	e.recordedAt = recordedAt
+	return e
+}
+
+func TestAuditEntryHashRoundTrip(t *testing.T) {
+	ptr := func(v float64) *float64 { return &v }
+	id := int64(42)
+	zipCode := "12345"
+	// Nanoseconds and a time zone other than UTC, as returned by time.Now
+	recordedAt := time.Date(2026, 1, 12, 7, 30, 15, 123456789, time.FixedZone("CET", 3600))
+
+	tests := []struct {
+		name  string
+		entry auditEntry
+	}{
+		{
+			name:  "insert",
+			entry: auditEntry{priceID: &id, provider: "heizoel24", productType: "standard", zipCode: &zipCode, region: "DE", action: AuditActionInsert, newPrice: ptr(97.81)},
+		},
+		{
+			name:  "update rounded to four decimals",
+			entry: auditEntry{priceID: &id, provider: "hoyer", productType: "bestpreis", zipCode: &zipCode, region: "DE", action: AuditActionUpdate, oldPrice: ptr(97.812349), newPrice: ptr(99.123456789)},
+		},
+		{
+			name:  "skip without zip code, price ID, and prices",
+			entry: auditEntry{provider: "heizoel24", productType: "standard", region: "AT", action: AuditActionSkip},
+		},
+		{
+			name:  "delete",
+			entry: auditEntry{priceID: &id, provider: "heizoel24", productType: "standard", region: "DE", action: AuditActionDelete, oldPrice: ptr(101.5)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			written := tt.entry
+			written.priceDate = time.Date(2026, 1, 12, 0, 0, 0, 0, time.UTC)
+			written.normalize(recordedAt)
+
+			stored := storedAuditEntry(t, written)
+			if got, want := stored.hash(emptyHash), written.hash(emptyHash); got != want {
+				t.Errorf("hash of the stored entry = %s, want %s", got, want)
+			}
+		})
+	}
+}
+
+func TestAuditEntryNormalize(t *testing.T) {
+	price := 97.812349
+	e := auditEntry{newPrice: &price}
+	e.normalize(time.Date(2026, 1, 12, 7, 30, 15, 123456789, time.FixedZone("CET", 3600)))
+
+	if want := time.Date(2026, 1, 12, 6, 30, 15, 123456000, time.UTC); !e.recordedAt.Equal(want) || e.recordedAt.Location() != time.UTC {
+		t.Errorf("recordedAt = %v, want %v", e.recordedAt, want)
+	}
+	if *e.newPrice != 97.8123 {
+		t.Errorf("newPrice = %v, want 97.8123", *e.newPrice)
+	}
+}
+
+func TestAuditEntryHashFields(t *testing.T) {
+	id := int64(1)
+	otherID := int64(2)
+	zipCode := "12345"
+	price := 97.81
+	base := auditEntry{
+		priceID:     &id,
+		provider:    "heizoel24",
+		productType: "standard",
+		priceDate:   time.Date(2026, 1, 12, 0, 0, 0, 0, time.UTC),
+		zipCode:     &zipCode,
+		region:      "DE",
+		action:      AuditActionInsert,
+		newPrice:    &price,
+		recordedAt:  time.Date(2026, 1, 12, 6, 30, 15, 123456000, time.UTC),
+	}
+	hash := base.hash(emptyHash)
+
+	tests := []struct {
+		name   string
+		modify func(e *auditEntry)
+	}{
+		{name: "price ID", modify: func(e *auditEntry) { e.priceID = &otherID }},
+		{name: "no price ID", modify: func(e *auditEntry) { e.priceID = nil }},
+		{name: "no zip code", modify: func(e *auditEntry) { e.zipCode = nil }},
+		{name: "region", modify: func(e *auditEntry) { e.region = "AT" }},
+		{name: "price", modify: func(e *auditEntry) { p := 97.8101; e.newPrice = &p }},
+		{name: "recorded at", modify: func(e *auditEntry) { e.recordedAt = e.recordedAt.Add(time.Microsecond) }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := base
+			tt.modify(&e)
+			if e.hash(emptyHash) == hash {
+				t.Errorf("changing the %s does not change the hash", tt.name)
+			}
+		})
+	}
+
+	if base.hash(hash) == hash {
+		t.Error("the hash does not depend on the previous hash")
+	}
+}
@@ -0,0 +1,67 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/andygrunwald/oil-price-scraper/internal/models"
+)
+
+// GetProviderCounters returns the stored lifetime counters of all providers.
+func (d *DB) GetProviderCounters(ctx context.Context) (map[string]models.ProviderCounters, error) {
+	rows, err := d.db.QueryContext(ctx, `
+		SELECT provider, total_requests, total_errors, transport_errors, timeout_errors,
+			http_status_errors, rate_limit_errors, parse_errors
+		FROM provider_counters
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("querying provider counters: %w", err)
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			panic(err)
+		}
+	}()
+
+	counters := make(map[string]models.ProviderCounters)
+	for rows.Next() {
+		var provider string
+		var c models.ProviderCounters
+		if err := rows.Scan(&provider, &c.TotalRequests, &c.TotalErrors, &c.TransportErrors, &c.TimeoutErrors,
+			&c.StatusErrors, &c.RateLimitErrors, &c.ParseErrors); err != nil {
+			return nil, fmt.Errorf("scanning provider counters: %w", err)
+		}
+		counters[provider] = c
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating provider counters: %w", err)
+	}
+	return counters, nil
+}
+
+// SaveProviderCounters adds increments to the lifetime counters of a provider.
+// Adding instead of overwriting keeps the counts of all processes writing
+// the counters (e.g., run and a manual scrape) and of delayed writes.
+func (d *DB) SaveProviderCounters(ctx context.Context, provider string, c models.ProviderCounters) error {
+	_, err := d.db.ExecContext(ctx, `
+		INSERT INTO provider_counters (provider, total_requests, total_errors, transport_errors, timeout_errors,
+			http_status_errors, rate_limit_errors, parse_errors, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		ON CONFLICT (provider)
+		DO UPDATE SET
+			total_requests = provider_counters.total_requests + EXCLUDED.total_requests,
+			total_errors = provider_counters.total_errors + EXCLUDED.total_errors,
+			transport_errors = provider_counters.transport_errors + EXCLUDED.transport_errors,
+			timeout_errors = provider_counters.timeout_errors + EXCLUDED.timeout_errors,
+			http_status_errors = provider_counters.http_status_errors + EXCLUDED.http_status_errors,
+			rate_limit_errors = provider_counters.rate_limit_errors + EXCLUDED.rate_limit_errors,
+			parse_errors = provider_counters.parse_errors + EXCLUDED.parse_errors,
+			updated_at = EXCLUDED.updated_at
+	`, provider, c.TotalRequests, c.TotalErrors, c.TransportErrors, c.TimeoutErrors,
+		c.StatusErrors, c.RateLimitErrors, c.ParseErrors, time.Now().UTC())
+	if err != nil {
+		return fmt.Errorf("saving provider counters: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,115 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/andygrunwald/oil-price-scraper/internal/models"
+)
+
+// SetDeadLetter enables keeping prices whose insert failed in the failed_inserts
+// table, so they can be retried later instead of being lost.
+func (d *DB) SetDeadLetter(enabled bool) {
+	d.deadLetter = enabled
+}
+
+// SaveFailedInsert keeps a price whose insert failed with insertErr in the
+// failed_inserts table. It is a no-op if the dead-letter table is disabled.
+func (d *DB) SaveFailedInsert(ctx context.Context, price models.PriceResult, insertErr error) error {
+	if !d.deadLetter {
+		return nil
+	}
+
+	priceJSON, err := json.Marshal(price)
+	if err != nil {
+		return fmt.Errorf("encoding price: %w", err)
+	}
+	var snapshot []byte
+	if price.ResponseSnapshot != nil {
+		snapshot, err = json.Marshal(price.ResponseSnapshot)
+		if err != nil {
+			return fmt.Errorf("encoding response snapshot: %w", err)
+		}
+	}
+	var rawResponse []byte
+	if len(price.RawResponse) > 0 {
+		rawResponse = price.RawResponse
+	}
+
+	err = d.withWriteReconnect(ctx, func() error {
+		_, err := d.db.ExecContext(ctx, `
+			INSERT INTO failed_inserts (provider, price, raw_response, response_snapshot, error, failed_at)
+			VALUES ($1, $2, $3, $4, $5, $6)
+		`, price.Provider, priceJSON, rawResponse, snapshot, insertErr.Error(), time.Now().UTC())
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("saving failed insert: %w", err)
+	}
+	return nil
+}
+
+// GetFailedInserts returns the kept prices whose insert failed, oldest first.
+// If provider is not empty, only prices of this provider are returned.
+func (d *DB) GetFailedInserts(ctx context.Context, provider string) ([]models.FailedInsert, error) {
+	rows, err := d.db.QueryContext(ctx, `
+		SELECT id, price, raw_response, response_snapshot, error, attempts, failed_at
+		FROM failed_inserts
+		WHERE ($1::text = '' OR provider = $1)
+		ORDER BY id
+	`, provider)
+	if err != nil {
+		return nil, fmt.Errorf("querying failed inserts: %w", err)
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			panic(err)
+		}
+	}()
+
+	var failed []models.FailedInsert
+	for rows.Next() {
+		var f models.FailedInsert
+		var priceJSON, snapshot []byte
+		if err := rows.Scan(&f.ID, &priceJSON, &f.Price.RawResponse, &snapshot, &f.Error, &f.Attempts, &f.FailedAt); err != nil {
+			return nil, fmt.Errorf("scanning failed insert: %w", err)
+		}
+		rawResponse := f.Price.RawResponse
+		if err := json.Unmarshal(priceJSON, &f.Price); err != nil {
+			return nil, fmt.Errorf("decoding price of failed insert %d: %w", f.ID, err)
+		}
+		f.Price.RawResponse = rawResponse
+		if snapshot != nil {
+			f.Price.ResponseSnapshot = &models.ResponseSnapshot{}
+			if err := json.Unmarshal(snapshot, f.Price.ResponseSnapshot); err != nil {
+				return nil, fmt.Errorf("decoding response snapshot of failed insert %d: %w", f.ID, err)
+			}
+		}
+		failed = append(failed, f)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating failed inserts: %w", err)
+	}
+	return failed, nil
+}
+
+// DeleteFailedInsert removes a kept price, e.g., after it was stored.
+func (d *DB) DeleteFailedInsert(ctx context.Context, id int64) error {
+	if _, err := d.db.ExecContext(ctx, `DELETE FROM failed_inserts WHERE id = $1`, id); err != nil {
+		return fmt.Errorf("deleting failed insert: %w", err)
+	}
+	return nil
+}
+
+// RecordFailedRetry counts another failed insert of a kept price and stores its error.
+func (d *DB) RecordFailedRetry(ctx context.Context, id int64, retryErr error) error {
+	_, err := d.db.ExecContext(ctx, `
+		UPDATE failed_inserts SET attempts = attempts + 1, error = $2 WHERE id = $1
+	`, id, retryErr.Error())
+	if err != nil {
+		return fmt.Errorf("recording failed retry: %w", err)
+	}
+	return nil
+}
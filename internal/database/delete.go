@@ -0,0 +1,131 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// PriceFilter selects price records. Zero values are not filtered on.
+type PriceFilter struct {
+	// Provider restricts records to a provider.
+	Provider string
+	// From restricts records to price dates on or after From.
+	From time.Time
+	// To restricts records to price dates on or before To.
+	To time.Time
+	// ZipCode restricts records to a zip code.
+	ZipCode string
+	// Label restricts records to a label.
+	Label string
+}
+
+// ErrEmptyFilter is returned if a destructive operation is called without any filter.
+var ErrEmptyFilter = errors.New("filter must not be empty")
+
+// IsEmpty returns true if no filter is set.
+func (f PriceFilter) IsEmpty() bool {
+	return f.Provider == "" && f.From.IsZero() && f.To.IsZero() && f.ZipCode == "" && f.Label == ""
+}
+
+// where returns the WHERE clause and arguments matching the filter.
+func (f PriceFilter) where() (string, []any) {
+	var conditions []string
+	var args []any
+	add := func(condition string, arg any) {
+		args = append(args, arg)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+
+	if f.Provider != "" {
+		add("provider = $%d", f.Provider)
+	}
+	if !f.From.IsZero() {
+		add("price_date >= $%d", f.From.Format("2006-01-02"))
+	}
+	if !f.To.IsZero() {
+		add("price_date <= $%d", f.To.Format("2006-01-02"))
+	}
+	if f.ZipCode != "" {
+		add("zip_code = $%d", f.ZipCode)
+	}
+	if f.Label != "" {
+		add("label = $%d", f.Label)
+	}
+
+	return strings.Join(conditions, " AND "), args
+}
+
+// TrimPricesBefore deletes all price records with a price date before cutoff
+// and returns the number of deleted records.
+func (d *DB) TrimPricesBefore(ctx context.Context, cutoff time.Time) (int64, error) {
+	// The filter is inclusive, so it ends on the day before the cutoff
+	return d.DeletePrices(ctx, PriceFilter{To: cutoff.AddDate(0, 0, -1)})
+}
+
+// CountPrices returns the number of price records matching the filter.
+func (d *DB) CountPrices(ctx context.Context, filter PriceFilter) (int64, error) {
+	if filter.IsEmpty() {
+		return 0, ErrEmptyFilter
+	}
+
+	where, args := filter.where()
+
+	var count int64
+	err := d.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM oil_prices WHERE "+where, args...).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("counting prices: %w", err)
+	}
+	return count, nil
+}
+
+// DeletePrices deletes all price records matching the filter in a single transaction
+// and returns the number of deleted records. An empty filter is rejected.
+// With the audit log enabled, every deleted record is recorded.
+func (d *DB) DeletePrices(ctx context.Context, filter PriceFilter) (int64, error) {
+	if filter.IsEmpty() {
+		return 0, ErrEmptyFilter
+	}
+
+	where, args := filter.where()
+
+	tx, err := d.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer func() {
+		// Rollback after a successful commit is a no-op
+		_ = tx.Rollback()
+	}()
+
+	var deleted int64
+	if d.audit {
+		deleted, err = d.auditDeletes(ctx, tx, "DELETE FROM oil_prices WHERE "+where+" RETURNING "+deletedPriceColumns, args...)
+		if err != nil {
+			return 0, fmt.Errorf("deleting prices: %w", err)
+		}
+	} else {
+		result, err := tx.ExecContext(ctx, "DELETE FROM oil_prices WHERE "+where, args...)
+		if err != nil {
+			return 0, fmt.Errorf("deleting prices: %w", err)
+		}
+		if deleted, err = result.RowsAffected(); err != nil {
+			return 0, fmt.Errorf("getting affected rows: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("committing transaction: %w", err)
+	}
+
+	d.logger.Info().
+		Str("provider", filter.Provider).
+		Str("zipCode", filter.ZipCode).
+		Str("label", filter.Label).
+		Int64("deleted", deleted).
+		Msg("deleted price records")
+
+	return deleted, nil
+}
@@ -0,0 +1,63 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/andygrunwald/oil-price-scraper/internal/models"
+)
+
+// GetProviderMetadata returns the stored metadata of all providers. It implements scraper.MetadataStore.
+func (d *DB) GetProviderMetadata(ctx context.Context) (map[string]models.ProviderMetadata, error) {
+	rows, err := d.db.QueryContext(ctx, `
+		SELECT provider, unit, product_name, chart_name, currency, changed_at
+		FROM provider_metadata
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("querying provider metadata: %w", err)
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			panic(err)
+		}
+	}()
+
+	metadata := make(map[string]models.ProviderMetadata)
+	for rows.Next() {
+		var provider string
+		var unit, productName, chartName, currency sql.NullString
+		var m models.ProviderMetadata
+		if err := rows.Scan(&provider, &unit, &productName, &chartName, &currency, &m.ChangedAt); err != nil {
+			return nil, fmt.Errorf("scanning provider metadata: %w", err)
+		}
+		m.Unit = unit.String
+		m.ProductName = productName.String
+		m.ChartName = chartName.String
+		m.Currency = currency.String
+		metadata[provider] = m
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating provider metadata: %w", err)
+	}
+	return metadata, nil
+}
+
+// SaveProviderMetadata replaces the stored metadata of a provider. It implements scraper.MetadataStore.
+func (d *DB) SaveProviderMetadata(ctx context.Context, provider string, m models.ProviderMetadata) error {
+	_, err := d.db.ExecContext(ctx, `
+		INSERT INTO provider_metadata (provider, unit, product_name, chart_name, currency, changed_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (provider)
+		DO UPDATE SET
+			unit = EXCLUDED.unit,
+			product_name = EXCLUDED.product_name,
+			chart_name = EXCLUDED.chart_name,
+			currency = EXCLUDED.currency,
+			changed_at = EXCLUDED.changed_at
+	`, provider, nullableString(m.Unit), nullableString(m.ProductName), nullableString(m.ChartName), nullableString(m.Currency), m.ChangedAt)
+	if err != nil {
+		return fmt.Errorf("saving provider metadata: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,267 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// migrateLockID is the advisory lock serializing migration runs,
+// so several instances starting at the same time do not migrate concurrently.
+const migrateLockID = 0x6f696c5f6d696772
+
+// Migration is a versioned schema change.
+type Migration struct {
+	Version int
+	Name    string
+	// Up applies the change.
+	Up string
+	// Down reverts the change. Empty if the migration cannot be reverted.
+	Down string
+}
+
+// AppliedMigration is a migration recorded in the schema_migrations table.
+type AppliedMigration struct {
+	Version   int
+	Name      string
+	AppliedAt time.Time
+}
+
+// LoadMigrations reads the migrations from fsys, ordered by version. Up migrations are
+// named like 001_initial_schema.sql; their down migrations have the same name in down/.
+func LoadMigrations(fsys fs.FS) ([]Migration, error) {
+	files, err := fs.Glob(fsys, "*.sql")
+	if err != nil {
+		return nil, fmt.Errorf("listing migrations: %w", err)
+	}
+
+	migrations := make([]Migration, 0, len(files))
+	seen := make(map[int]string, len(files))
+	for _, file := range files {
+		prefix, _, ok := strings.Cut(file, "_")
+		version, err := strconv.Atoi(prefix)
+		if !ok || err != nil || version <= 0 {
+			return nil, fmt.Errorf("migration %s: name must start with a version number (e.g. 001_)", file)
+		}
+		if other, ok := seen[version]; ok {
+			return nil, fmt.Errorf("migrations %s and %s have the same version", other, file)
+		}
+		seen[version] = file
+
+		up, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			return nil, fmt.Errorf("reading migration %s: %w", file, err)
+		}
+		down, err := fs.ReadFile(fsys, path.Join("down", file))
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf("reading down migration %s: %w", file, err)
+		}
+
+		migrations = append(migrations, Migration{
+			Version: version,
+			Name:    strings.TrimSuffix(file, ".sql"),
+			Up:      string(up),
+			Down:    string(down),
+		})
+	}
+
+	slices.SortFunc(migrations, func(a, b Migration) int { return a.Version - b.Version })
+	return migrations, nil
+}
+
+// MigrateUp applies all migrations that are not recorded in the schema_migrations table yet,
+// in order and each in its own transaction. It returns the applied migrations.
+// It is safe to run on every start: concurrent runs are serialized, and the migrations
+// are idempotent, so databases created before the tracking table are adopted.
+func (d *DB) MigrateUp(ctx context.Context, migrations []Migration) ([]Migration, error) {
+	var applied []Migration
+	err := d.withMigrationLock(ctx, func(conn *sql.Conn) error {
+		done, err := appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for _, m := range migrations {
+			if done[m.Version] {
+				continue
+			}
+			err := inConnTx(ctx, conn, func(tx *sql.Tx) error {
+				if _, err := tx.ExecContext(ctx, m.Up); err != nil {
+					return err
+				}
+				_, err := tx.ExecContext(ctx, `INSERT INTO schema_migrations (version, name, applied_at) VALUES ($1, $2, $3)`,
+					m.Version, m.Name, time.Now().UTC())
+				return err
+			})
+			if err != nil {
+				return fmt.Errorf("applying migration %s: %w", m.Name, err)
+			}
+			d.logger.Info().Int("version", m.Version).Str("name", m.Name).Msg("applied migration")
+			applied = append(applied, m)
+		}
+		return nil
+	})
+	return applied, err
+}
+
+// MigrateDown reverts the latest steps applied migrations, newest first and each in its
+// own transaction. It returns the reverted migrations.
+func (d *DB) MigrateDown(ctx context.Context, migrations []Migration, steps int) ([]Migration, error) {
+	byVersion := make(map[int]Migration, len(migrations))
+	for _, m := range migrations {
+		byVersion[m.Version] = m
+	}
+
+	var reverted []Migration
+	err := d.withMigrationLock(ctx, func(conn *sql.Conn) error {
+		done, err := appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+		versions := make([]int, 0, len(done))
+		for version := range done {
+			versions = append(versions, version)
+		}
+		slices.Sort(versions)
+		slices.Reverse(versions)
+
+		for _, version := range versions[:min(steps, len(versions))] {
+			m, ok := byVersion[version]
+			if !ok {
+				return fmt.Errorf("migration %d is applied but unknown to this version", version)
+			}
+			if m.Down == "" {
+				return fmt.Errorf("migration %s cannot be reverted (no down migration)", m.Name)
+			}
+			err := inConnTx(ctx, conn, func(tx *sql.Tx) error {
+				if _, err := tx.ExecContext(ctx, m.Down); err != nil {
+					return err
+				}
+				_, err := tx.ExecContext(ctx, `DELETE FROM schema_migrations WHERE version = $1`, m.Version)
+				return err
+			})
+			if err != nil {
+				return fmt.Errorf("reverting migration %s: %w", m.Name, err)
+			}
+			d.logger.Info().Int("version", m.Version).Str("name", m.Name).Msg("reverted migration")
+			reverted = append(reverted, m)
+		}
+		return nil
+	})
+	return reverted, err
+}
+
+// AppliedMigrations returns the migrations recorded in the schema_migrations table, ordered by version.
+func (d *DB) AppliedMigrations(ctx context.Context) ([]AppliedMigration, error) {
+	var applied []AppliedMigration
+	err := d.withMigrationLock(ctx, func(conn *sql.Conn) error {
+		rows, err := conn.QueryContext(ctx, `SELECT version, name, applied_at FROM schema_migrations ORDER BY version`)
+		if err != nil {
+			return fmt.Errorf("querying migrations: %w", err)
+		}
+		defer func() {
+			if err := rows.Close(); err != nil {
+				panic(err)
+			}
+		}()
+
+		for rows.Next() {
+			var m AppliedMigration
+			if err := rows.Scan(&m.Version, &m.Name, &m.AppliedAt); err != nil {
+				return fmt.Errorf("scanning migration: %w", err)
+			}
+			applied = append(applied, m)
+		}
+		return rows.Err()
+	})
+	return applied, err
+}
+
+// withMigrationLock runs fn on a single connection holding the migration lock,
+// after making sure the schema_migrations table exists.
+func (d *DB) withMigrationLock(ctx context.Context, fn func(conn *sql.Conn) error) error {
+	conn, err := d.db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("getting connection: %w", err)
+	}
+	defer func() {
+		if err := conn.Close(); err != nil {
+			panic(err)
+		}
+	}()
+
+	if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, migrateLockID); err != nil {
+		return fmt.Errorf("acquiring migration lock: %w", err)
+	}
+	defer func() {
+		// The lock is released with the session anyway if unlocking fails
+		_, _ = conn.ExecContext(context.WithoutCancel(ctx), `SELECT pg_advisory_unlock($1)`, migrateLockID)
+	}()
+
+	_, err = conn.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version    INTEGER PRIMARY KEY,
+			name       VARCHAR(255) NOT NULL,
+			applied_at TIMESTAMP NOT NULL
+		)
+	`)
+	if err != nil {
+		return fmt.Errorf("creating schema_migrations table: %w", err)
+	}
+
+	return fn(conn)
+}
+
+// appliedVersions returns the versions recorded in the schema_migrations table.
+func appliedVersions(ctx context.Context, conn *sql.Conn) (map[int]bool, error) {
+	rows, err := conn.QueryContext(ctx, `SELECT version FROM schema_migrations`)
+	if err != nil {
+		return nil, fmt.Errorf("querying applied migrations: %w", err)
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			panic(err)
+		}
+	}()
+
+	versions := make(map[int]bool)
+	for rows.Next() {
+		var version int
+		if err := rows.Scan(&version); err != nil {
+			return nil, fmt.Errorf("scanning applied migration: %w", err)
+		}
+		versions[version] = true
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating applied migrations: %w", err)
+	}
+	return versions, nil
+}
+
+// inConnTx runs fn in a transaction on conn, committing if fn succeeds.
+func inConnTx(ctx context.Context, conn *sql.Conn, fn func(tx *sql.Tx) error) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer func() {
+		// Rollback after a successful commit is a no-op
+		_ = tx.Rollback()
+	}()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("committing transaction: %w", err)
+	}
+	return nil
+}
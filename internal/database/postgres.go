@@ -4,27 +4,85 @@ package database
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"regexp"
+	"sync"
+	"sync/atomic"
 	"time"
 
-	_ "github.com/jackc/pgx/v5/stdlib"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/stdlib"
 	"github.com/rs/zerolog"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 
+	"github.com/andygrunwald/oil-price-scraper/internal/api"
 	"github.com/andygrunwald/oil-price-scraper/internal/models"
 )
 
+// tracer creates the spans of database writes (a no-op unless tracing is set up).
+var tracer = otel.Tracer("github.com/andygrunwald/oil-price-scraper/internal/database")
+
 // DB wraps the PostgreSQL database connection and provides operations for oil prices.
+// Writes survive database restarts: if the connection is lost, they wait with
+// backoff until the database is reachable again (see withReconnect).
 type DB struct {
-	db     *sql.DB
-	logger zerolog.Logger
+	db          *sql.DB
+	logger      zerolog.Logger
+	connected   atomic.Bool
+	reconnectMu sync.Mutex
+
+	// tenant is the PostgreSQL schema holding the tables (empty uses the default schema)
+	tenant string
+
+	// compressRawResponse stores raw responses gzip-compressed
+	compressRawResponse bool
+	// audit writes inserts, updates, and skips to the audit log
+	audit bool
+	// deadLetter keeps prices whose insert failed in the failed_inserts table
+	deadLetter bool
+}
+
+// tenantPattern matches valid tenant names. They are used as unquoted-style schema
+// names, so they stay readable in psql and within PostgreSQL's 63 byte identifier limit.
+var tenantPattern = regexp.MustCompile(`^[a-z][a-z0-9_]{0,62}$`)
+
+// ValidateTenant returns an error if tenant is not a valid tenant name.
+// The empty tenant is valid and means the default schema.
+func ValidateTenant(tenant string) error {
+	if tenant != "" && !tenantPattern.MatchString(tenant) {
+		return fmt.Errorf("invalid tenant %q: must start with a lowercase letter and contain only lowercase letters, digits, and underscores (at most 63 characters)", tenant)
+	}
+	return nil
 }
 
-// New creates a new database connection.
+// New creates a new database connection using the default schema.
 func New(dsn string, logger zerolog.Logger) (*DB, error) {
-	db, err := sql.Open("pgx", dsn)
+	return NewTenant(dsn, "", logger)
+}
+
+// NewTenant creates a new database connection whose tables live in the PostgreSQL
+// schema named tenant, so several datasets (e.g., households with different zip codes)
+// can share one database in isolation. The schema is created if it does not exist.
+// Every connection uses it as its only search_path, so tables of other tenants or the
+// default schema are never read or written. An empty tenant uses the default schema.
+func NewTenant(dsn, tenant string, logger zerolog.Logger) (*DB, error) {
+	if err := ValidateTenant(tenant); err != nil {
+		return nil, err
+	}
+
+	connConfig, err := pgx.ParseConfig(dsn)
 	if err != nil {
 		return nil, fmt.Errorf("opening database connection: %w", err)
 	}
+	if tenant != "" {
+		connConfig.RuntimeParams["search_path"] = pgx.Identifier{tenant}.Sanitize()
+	}
+	db := stdlib.OpenDB(*connConfig)
 
 	// Configure connection pool
 	db.SetMaxOpenConns(10)
@@ -36,10 +94,35 @@ func New(dsn string, logger zerolog.Logger) (*DB, error) {
 		return nil, fmt.Errorf("pinging database: %w", err)
 	}
 
-	return &DB{
+	logContext := logger.With().Str("component", "database")
+	if tenant != "" {
+		logContext = logContext.Str("tenant", tenant)
+		if _, err := db.Exec("CREATE SCHEMA IF NOT EXISTS " + pgx.Identifier{tenant}.Sanitize()); err != nil {
+			_ = db.Close()
+			return nil, fmt.Errorf("creating schema of tenant %s: %w", tenant, err)
+		}
+	}
+
+	d := &DB{
 		db:     db,
-		logger: logger.With().Str("component", "database").Logger(),
-	}, nil
+		logger: logContext.Logger(),
+		tenant: tenant,
+	}
+	d.connected.Store(true)
+
+	return d, nil
+}
+
+// Tenant returns the tenant whose schema holds the tables, or "" for the default schema.
+func (d *DB) Tenant() string {
+	return d.tenant
+}
+
+// SetCompressRawResponse enables storing raw responses gzip-compressed in the
+// raw_response_gzip column instead of the raw_response JSONB column.
+// Reading decompresses transparently, so both encodings can coexist.
+func (d *DB) SetCompressRawResponse(compress bool) {
+	d.compressRawResponse = compress
 }
 
 // Close closes the database connection.
@@ -53,28 +136,59 @@ func (d *DB) Ping() error {
 }
 
 // InsertPrice inserts a new oil price record into the database.
-func (d *DB) InsertPrice(ctx context.Context, price models.PriceResult, storeRawResponse bool) error {
+// The response header snapshot is only stored together with the raw response.
+func (d *DB) InsertPrice(ctx context.Context, price models.PriceResult, storeRawResponse, storeResponseHeaders bool) error {
+	ctx, span := tracer.Start(ctx, "db.insert_price", trace.WithAttributes(
+		attribute.String("provider", price.Provider),
+		attribute.String("product_type", price.ProductType),
+	))
+	defer span.End()
+
 	query := `
-		INSERT INTO oil_prices (provider, product_type, price_date, price_per_100l, currency, scope, zip_code, raw_response, fetched_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
-		ON CONFLICT (provider, product_type, price_date, zip_code)
+		INSERT INTO oil_prices (provider, product_type, price_date, price_per_100l, currency, scope, zip_code, region, source_url, label, published_at, delivery_days, delivery_time_type, is_premium, is_climate_neutral, raw_response, raw_response_gzip, raw_response_headers, fetched_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19)
+		ON CONFLICT (provider, product_type, price_date, zip_code, region)
 		DO UPDATE SET
 			price_per_100l = EXCLUDED.price_per_100l,
+			source_url = EXCLUDED.source_url,
+			label = EXCLUDED.label,
+			published_at = EXCLUDED.published_at,
+			delivery_days = EXCLUDED.delivery_days,
+			delivery_time_type = EXCLUDED.delivery_time_type,
+			is_premium = EXCLUDED.is_premium,
+			is_climate_neutral = EXCLUDED.is_climate_neutral,
 			raw_response = EXCLUDED.raw_response,
+			raw_response_gzip = EXCLUDED.raw_response_gzip,
+			raw_response_headers = EXCLUDED.raw_response_headers,
 			fetched_at = EXCLUDED.fetched_at
 	`
 
 	var rawResponse []byte
+	var rawResponseGzip []byte
+	var rawResponseHeaders []byte
 	if storeRawResponse {
-		rawResponse = price.RawResponse
-	}
+		if d.compressRawResponse && len(price.RawResponse) > 0 {
+			compressed, err := gzipCompress(price.RawResponse)
+			if err != nil {
+				return fmt.Errorf("compressing raw response: %w", err)
+			}
+			rawResponseGzip = compressed
+		} else {
+			rawResponse = price.RawResponse
+		}
 
-	var zipCode *string
-	if price.ZipCode != "" {
-		zipCode = &price.ZipCode
+		if storeResponseHeaders && price.ResponseSnapshot != nil {
+			headers, err := json.Marshal(price.ResponseSnapshot)
+			if err != nil {
+				return fmt.Errorf("encoding response headers: %w", err)
+			}
+			rawResponseHeaders = headers
+		}
 	}
 
-	_, err := d.db.ExecContext(ctx, query,
+	zipCode := nullableString(price.ZipCode)
+	region := regionOrDefault(price.Region)
+	args := []any{
 		price.Provider,
 		price.ProductType,
 		price.Date.Format("2006-01-02"),
@@ -82,10 +196,77 @@ func (d *DB) InsertPrice(ctx context.Context, price models.PriceResult, storeRaw
 		price.Currency,
 		string(price.Scope),
 		zipCode,
+		region,
+		nullableString(price.SourceURL),
+		nullableString(price.Label),
+		price.PublishedAt,
+		price.DeliveryDays,
+		nullableString(price.DeliveryTimeType),
+		price.IsPremium,
+		price.IsClimateNeutral,
 		rawResponse,
+		rawResponseGzip,
+		rawResponseHeaders,
 		price.FetchedAt,
-	)
+	}
+
+	// The upsert is idempotent and may be retried like a read, but an audited
+	// write retried after it was applied would be logged twice
+	withReconnect := d.withWriteReconnect
+	if !d.audit {
+		withReconnect = d.withReconnect
+	}
+	err := withReconnect(ctx, func() error {
+		if !d.audit {
+			_, err := d.db.ExecContext(ctx, query, args...)
+			return err
+		}
+
+		return d.inTx(ctx, func(tx *sql.Tx) error {
+			// The previous price decides between an insert and an update
+			var oldPrice *float64
+			err := tx.QueryRowContext(ctx, `
+				SELECT price_per_100l FROM oil_prices
+				WHERE provider = $1 AND product_type = $2 AND price_date = $3
+				AND zip_code IS NOT DISTINCT FROM $4 AND region = $5
+				FOR UPDATE
+			`, price.Provider, price.ProductType, price.Date.Format("2006-01-02"), zipCode, region).Scan(&oldPrice)
+			if err != nil && !errors.Is(err, sql.ErrNoRows) {
+				return fmt.Errorf("querying previous price: %w", err)
+			}
+
+			var id int64
+			if err := tx.QueryRowContext(ctx, query+" RETURNING id", args...).Scan(&id); err != nil {
+				return err
+			}
+
+			// Rewriting an unchanged price is no change
+			auditID := &id
+			action := AuditActionInsert
+			switch {
+			case oldPrice != nil && SamePrice(*oldPrice, price.PricePer100L):
+				action = AuditActionSkip
+				auditID = nil
+				oldPrice = nil
+			case oldPrice != nil:
+				action = AuditActionUpdate
+			}
+			return d.writeAudit(ctx, tx, auditEntry{
+				priceID:     auditID,
+				provider:    price.Provider,
+				productType: price.ProductType,
+				priceDate:   price.Date,
+				zipCode:     zipCode,
+				region:      region,
+				action:      action,
+				oldPrice:    oldPrice,
+				newPrice:    &price.PricePer100L,
+			})
+		})
+	})
 	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "inserting price")
 		return fmt.Errorf("inserting price: %w", err)
 	}
 
@@ -99,12 +280,14 @@ func (d *DB) InsertPrice(ctx context.Context, price models.PriceResult, storeRaw
 	return nil
 }
 
-// ExistsForDate checks if a price record exists for the given provider, product type, date, and zip code.
-func (d *DB) ExistsForDate(ctx context.Context, provider, productType string, date time.Time, zipCode string) (bool, error) {
+// ExistsForDate checks if a price record exists for the given provider, product type, date, zip code, and region.
+// An empty region matches records of all regions.
+func (d *DB) ExistsForDate(ctx context.Context, provider, productType string, date time.Time, zipCode, region string) (bool, error) {
 	query := `
 		SELECT COUNT(*) FROM oil_prices
 		WHERE provider = $1 AND product_type = $2 AND price_date = $3
 		AND (zip_code = $4 OR (zip_code IS NULL AND $4 IS NULL))
+		AND ($5::text = '' OR region = $5)
 	`
 
 	var zipCodePtr *string
@@ -113,12 +296,15 @@ func (d *DB) ExistsForDate(ctx context.Context, provider, productType string, da
 	}
 
 	var count int
-	err := d.db.QueryRowContext(ctx, query,
-		provider,
-		productType,
-		date.Format("2006-01-02"),
-		zipCodePtr,
-	).Scan(&count)
+	err := d.withReconnect(ctx, func() error {
+		return d.db.QueryRowContext(ctx, query,
+			provider,
+			productType,
+			date.Format("2006-01-02"),
+			zipCodePtr,
+			region,
+		).Scan(&count)
+	})
 	if err != nil {
 		return false, fmt.Errorf("checking existence: %w", err)
 	}
@@ -129,9 +315,761 @@ func (d *DB) ExistsForDate(ctx context.Context, provider, productType string, da
 // GetTotalPricesCount returns the total number of price records in the database.
 func (d *DB) GetTotalPricesCount(ctx context.Context) (int64, error) {
 	var count int64
-	err := d.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM oil_prices").Scan(&count)
+	err := d.withReconnect(ctx, func() error {
+		return d.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM oil_prices").Scan(&count)
+	})
 	if err != nil {
 		return 0, fmt.Errorf("counting prices: %w", err)
 	}
 	return count, nil
 }
+
+// listedPriceColumns is the column list used when listing oil price records.
+// It omits the raw responses, which are large and never part of a listing.
+const listedPriceColumns = `id, provider, product_type, price_date, price_per_100l, currency, scope, zip_code, region, source_url, label, published_at, delivery_days, delivery_time_type, is_premium, is_climate_neutral, fetched_at, created_at`
+
+// priceColumns is the column list used when reading complete oil price records, including the raw responses.
+const priceColumns = listedPriceColumns + `, raw_response, raw_response_gzip, raw_response_headers`
+
+// rowScanner is implemented by *sql.Row and *sql.Rows.
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+// scanListedPrice scans a single oil price record selected with listedPriceColumns.
+func scanListedPrice(row rowScanner) (models.OilPrice, error) {
+	return scanPriceColumns(row, false)
+}
+
+// scanPrice scans a single oil price record selected with priceColumns.
+func scanPrice(row rowScanner) (models.OilPrice, error) {
+	return scanPriceColumns(row, true)
+}
+
+// scanPriceColumns scans a single oil price record selected with priceColumns
+// if raw is true, or with listedPriceColumns otherwise.
+func scanPriceColumns(row rowScanner, raw bool) (models.OilPrice, error) {
+	var p models.OilPrice
+	var scope string
+	var rawResponseGzip []byte
+	dest := []any{
+		&p.ID,
+		&p.Provider,
+		&p.ProductType,
+		&p.PriceDate,
+		&p.PricePer100L,
+		&p.Currency,
+		&scope,
+		&p.ZipCode,
+		&p.Region,
+		&p.SourceURL,
+		&p.Label,
+		&p.PublishedAt,
+		&p.DeliveryDays,
+		&p.DeliveryTimeType,
+		&p.IsPremium,
+		&p.IsClimateNeutral,
+		&p.FetchedAt,
+		&p.CreatedAt,
+	}
+	if raw {
+		dest = append(dest, &p.RawResponse, &rawResponseGzip, &p.RawResponseHeaders)
+	}
+	err := row.Scan(dest...)
+	if err != nil {
+		return p, err
+	}
+	p.Scope = models.PriceScope(scope)
+
+	if len(rawResponseGzip) > 0 {
+		p.RawResponse, err = gzipDecompress(rawResponseGzip)
+		if err != nil {
+			return p, fmt.Errorf("decompressing raw response: %w", err)
+		}
+	}
+
+	return p, nil
+}
+
+// GetPricesForDateRange returns all price records between from and to (inclusive), ordered by date,
+// including their raw responses. If provider is empty, records of all providers are returned.
+// If label is not empty, only records with this label are returned.
+func (d *DB) GetPricesForDateRange(ctx context.Context, provider string, from, to time.Time, label string) ([]models.OilPrice, error) {
+	return d.queryPricesForDateRange(ctx, true, provider, from, to, label, 0, 0, 0)
+}
+
+// GetPricesPage returns up to limit price records between from and to (inclusive), ordered by date,
+// skipping the first offset records. The order is stable, so consecutive offsets page through the range.
+// If maxDeliveryDays is greater than 0, only records with a delivery time of at most maxDeliveryDays are returned.
+// The raw responses are not read.
+func (d *DB) GetPricesPage(ctx context.Context, provider string, from, to time.Time, label string, maxDeliveryDays, limit, offset int) ([]models.OilPrice, error) {
+	return d.queryPricesForDateRange(ctx, false, provider, from, to, label, maxDeliveryDays, limit, offset)
+}
+
+// EachPriceForDateRange calls fn for every price record between from and to (inclusive), ordered by date,
+// while the records are read, so a range is never held in memory at once. The raw responses are not read.
+// If provider is empty, records of all providers are passed. An error returned by fn stops the
+// iteration and is returned as is.
+func (d *DB) EachPriceForDateRange(ctx context.Context, provider string, from, to time.Time, fn func(models.OilPrice) error) error {
+	return d.eachPriceForDateRange(ctx, false, provider, from, to, "", 0, 0, 0, fn)
+}
+
+// queryPricesForDateRange returns the price records between from and to (inclusive)
+// (see eachPriceForDateRange).
+func (d *DB) queryPricesForDateRange(ctx context.Context, raw bool, provider string, from, to time.Time, label string, maxDeliveryDays, limit, offset int) ([]models.OilPrice, error) {
+	var prices []models.OilPrice
+	err := d.eachPriceForDateRange(ctx, raw, provider, from, to, label, maxDeliveryDays, limit, offset, func(p models.OilPrice) error {
+		prices = append(prices, p)
+		return nil
+	})
+	return prices, err
+}
+
+// eachPriceForDateRange calls fn for every price record between from and to (inclusive).
+// The raw responses are only read if raw is true. A maxDeliveryDays of 0 does not filter
+// by delivery time, a limit of 0 returns all records.
+func (d *DB) eachPriceForDateRange(ctx context.Context, raw bool, provider string, from, to time.Time, label string, maxDeliveryDays, limit, offset int, fn func(models.OilPrice) error) error {
+	columns, scan := listedPriceColumns, scanListedPrice
+	if raw {
+		columns, scan = priceColumns, scanPrice
+	}
+
+	query := `
+		SELECT ` + columns + `
+		FROM oil_prices
+		WHERE ($1::text = '' OR provider = $1)
+		AND price_date >= $2 AND price_date <= $3
+		AND ($4::text = '' OR label = $4)
+		AND ($7::int = 0 OR delivery_days <= $7)
+		ORDER BY price_date ASC, provider, product_type, id
+		LIMIT $5 OFFSET $6
+	`
+
+	// LIMIT NULL is the same as omitting the limit
+	var limitArg *int
+	if limit > 0 {
+		limitArg = &limit
+	}
+
+	rows, err := d.queryContext(ctx, query,
+		provider,
+		from.Format("2006-01-02"),
+		to.Format("2006-01-02"),
+		label,
+		limitArg,
+		offset,
+		maxDeliveryDays,
+	)
+	if err != nil {
+		return fmt.Errorf("querying prices: %w", err)
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			panic(err)
+		}
+	}()
+
+	for rows.Next() {
+		p, err := scan(rows)
+		if err != nil {
+			return fmt.Errorf("scanning price: %w", err)
+		}
+		if err := fn(p); err != nil {
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("iterating prices: %w", err)
+	}
+
+	return nil
+}
+
+// FindDuplicates returns all groups of price records that share the same provider,
+// product type, date, and zip code. NULL zip codes are treated as equal.
+func (d *DB) FindDuplicates(ctx context.Context) ([]models.DuplicateGroup, error) {
+	query := `
+		SELECT provider, product_type, price_date, zip_code, region,
+			COUNT(*), COUNT(DISTINCT price_per_100l), MIN(price_per_100l), MAX(price_per_100l)
+		FROM oil_prices
+		GROUP BY provider, product_type, price_date, zip_code, region
+		HAVING COUNT(*) > 1
+		ORDER BY price_date, provider, product_type
+	`
+
+	rows, err := d.queryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("querying duplicates: %w", err)
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			panic(err)
+		}
+	}()
+
+	var groups []models.DuplicateGroup
+	for rows.Next() {
+		var g models.DuplicateGroup
+		if err := rows.Scan(
+			&g.Provider,
+			&g.ProductType,
+			&g.PriceDate,
+			&g.ZipCode,
+			&g.Region,
+			&g.Count,
+			&g.DistinctPrices,
+			&g.MinPrice,
+			&g.MaxPrice,
+		); err != nil {
+			return nil, fmt.Errorf("scanning duplicate group: %w", err)
+		}
+		groups = append(groups, g)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating duplicate groups: %w", err)
+	}
+
+	return groups, nil
+}
+
+// RemoveDuplicates collapses duplicate price records, keeping the most recently fetched
+// record of each group. It returns the number of deleted records.
+// With the audit log enabled, every deleted record is recorded.
+func (d *DB) RemoveDuplicates(ctx context.Context) (int64, error) {
+	query := `
+		DELETE FROM oil_prices a
+		USING oil_prices b
+		WHERE a.provider = b.provider
+		AND a.product_type = b.product_type
+		AND a.price_date = b.price_date
+		AND a.zip_code IS NOT DISTINCT FROM b.zip_code
+		AND a.region = b.region
+		AND (a.fetched_at < b.fetched_at OR (a.fetched_at = b.fetched_at AND a.id < b.id))
+	`
+
+	var deleted int64
+	var err error
+	if d.audit {
+		err = d.inTx(ctx, func(tx *sql.Tx) error {
+			var err error
+			deleted, err = d.auditDeletes(ctx, tx, query+" RETURNING a.id, a.provider, a.product_type, a.price_date, a.zip_code, a.region, a.price_per_100l")
+			return err
+		})
+	} else {
+		var result sql.Result
+		result, err = d.db.ExecContext(ctx, query)
+		if err == nil {
+			deleted, err = result.RowsAffected()
+		}
+	}
+	if err != nil {
+		return 0, fmt.Errorf("removing duplicates: %w", err)
+	}
+
+	d.logger.Info().Int64("deleted", deleted).Msg("removed duplicate price records")
+
+	return deleted, nil
+}
+
+// GetLatestRawResponse returns the most recently fetched price record of a provider
+// that has a stored raw response, or nil if there is none.
+func (d *DB) GetLatestRawResponse(ctx context.Context, provider string) (*models.OilPrice, error) {
+	query := `
+		SELECT ` + priceColumns + `
+		FROM oil_prices
+		WHERE provider = $1 AND (raw_response IS NOT NULL OR raw_response_gzip IS NOT NULL)
+		ORDER BY fetched_at DESC, id DESC
+		LIMIT 1
+	`
+
+	p, err := d.queryPrice(ctx, scanPrice, query, provider)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("querying raw response: %w", err)
+	}
+	return &p, nil
+}
+
+// GetLatestPrices returns the latest price record of every series, i.e. one record
+// per provider, product type, and zip code. This covers national and local prices.
+func (d *DB) GetLatestPrices(ctx context.Context) ([]models.OilPrice, error) {
+	query := `
+		SELECT DISTINCT ON (provider, product_type, zip_code, region) ` + listedPriceColumns + `
+		FROM oil_prices
+		ORDER BY provider, product_type, zip_code, region, price_date DESC, created_at DESC
+	`
+
+	rows, err := d.queryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("querying latest prices: %w", err)
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			panic(err)
+		}
+	}()
+
+	var prices []models.OilPrice
+	for rows.Next() {
+		p, err := scanListedPrice(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scanning price: %w", err)
+		}
+		prices = append(prices, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating prices: %w", err)
+	}
+
+	return prices, nil
+}
+
+// GetLatestPrice returns the most recent price record of a provider.
+// It returns nil if no record exists.
+func (d *DB) GetLatestPrice(ctx context.Context, provider string) (*models.OilPrice, error) {
+	query := `
+		SELECT ` + listedPriceColumns + `
+		FROM oil_prices
+		WHERE provider = $1
+		ORDER BY price_date DESC, created_at DESC
+		LIMIT 1
+	`
+
+	p, err := d.queryPrice(ctx, scanListedPrice, query, provider)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("querying latest price: %w", err)
+	}
+
+	return &p, nil
+}
+
+// GetLatestPriceForDate returns the most recent price record of a provider for a date.
+// It returns nil if no record exists.
+func (d *DB) GetLatestPriceForDate(ctx context.Context, provider string, date time.Time) (*models.OilPrice, error) {
+	query := `
+		SELECT ` + listedPriceColumns + `
+		FROM oil_prices
+		WHERE provider = $1 AND price_date = $2
+		ORDER BY created_at DESC
+		LIMIT 1
+	`
+
+	p, err := d.queryPrice(ctx, scanListedPrice, query, provider, date.Format("2006-01-02"))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("querying latest price for date: %w", err)
+	}
+
+	return &p, nil
+}
+
+// GetLatestProductPrice returns the most recent price record of a product type of a provider.
+// An empty productType, zipCode, or region matches prices of all product types, zip codes,
+// or regions. It returns nil if no record exists.
+func (d *DB) GetLatestProductPrice(ctx context.Context, provider, productType, zipCode, region string) (*models.OilPrice, error) {
+	query := `
+		SELECT ` + listedPriceColumns + `
+		FROM oil_prices
+		WHERE provider = $1
+		AND ($2::text = '' OR product_type = $2)
+		AND ($3::text = '' OR zip_code = $3)
+		AND ($4::text = '' OR region = $4)
+		ORDER BY price_date DESC, created_at DESC
+		LIMIT 1
+	`
+
+	p, err := d.queryPrice(ctx, scanListedPrice, query, provider, productType, zipCode, region)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("querying latest product price: %w", err)
+	}
+
+	return &p, nil
+}
+
+// GetRecentSeriesPrices returns the prices of the series (provider, product type,
+// zip code, region) of price up to its date, newest first, at most limit.
+func (d *DB) GetRecentSeriesPrices(ctx context.Context, price models.PriceResult, limit int) ([]float64, error) {
+	query := `
+		SELECT price_per_100l
+		FROM oil_prices
+		WHERE provider = $1 AND product_type = $2
+		AND zip_code IS NOT DISTINCT FROM $3 AND region = $4
+		AND price_date <= $5
+		ORDER BY price_date DESC, created_at DESC
+		LIMIT $6
+	`
+
+	rows, err := d.queryContext(ctx, query,
+		price.Provider,
+		price.ProductType,
+		nullableString(price.ZipCode),
+		regionOrDefault(price.Region),
+		price.Date.Format("2006-01-02"),
+		limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("querying recent prices: %w", err)
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			panic(err)
+		}
+	}()
+
+	var prices []float64
+	for rows.Next() {
+		var p float64
+		if err := rows.Scan(&p); err != nil {
+			return nil, fmt.Errorf("scanning price: %w", err)
+		}
+		prices = append(prices, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating prices: %w", err)
+	}
+
+	return prices, nil
+}
+
+// GetCurrentPrices returns the current price record of every product type of a provider
+// according to policy, ordered by product type. Prices of different product types are
+// never compared, so every product type has its own current price. Today is the
+// calendar day of now in its location. Product types without a current price are omitted.
+func (d *DB) GetCurrentPrices(ctx context.Context, provider string, policy api.CurrentPricePolicy, now time.Time) ([]models.OilPrice, error) {
+	// A NULL date (latest policy) matches every date
+	var date sql.NullString
+	if policy == api.CurrentPriceToday {
+		date = sql.NullString{String: api.PriceDate(now, now.Location()).Format("2006-01-02"), Valid: true}
+	}
+
+	query := `
+		SELECT DISTINCT ON (product_type) ` + listedPriceColumns + `
+		FROM oil_prices
+		WHERE provider = $1
+		AND ($2::date IS NULL OR price_date = $2::date)
+		ORDER BY product_type, price_date DESC, created_at DESC
+	`
+
+	rows, err := d.queryContext(ctx, query, provider, date)
+	if err != nil {
+		return nil, fmt.Errorf("querying current prices: %w", err)
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			panic(err)
+		}
+	}()
+
+	var prices []models.OilPrice
+	for rows.Next() {
+		p, err := scanListedPrice(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scanning price: %w", err)
+		}
+		prices = append(prices, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating prices: %w", err)
+	}
+
+	return prices, nil
+}
+
+// GetProductTypes returns the product types of a provider with prices between from and to
+// (inclusive), in alphabetical order. If zipCode is empty, prices of all zip codes are considered;
+// an empty region matches prices of all regions.
+func (d *DB) GetProductTypes(ctx context.Context, provider string, from, to time.Time, zipCode, region string) ([]string, error) {
+	query := `
+		SELECT DISTINCT product_type
+		FROM oil_prices
+		WHERE provider = $1
+		AND price_date >= $2 AND price_date <= $3
+		AND ($4::text = '' OR zip_code = $4)
+		AND ($5::text = '' OR region = $5)
+		ORDER BY product_type
+	`
+
+	rows, err := d.queryContext(ctx, query,
+		provider,
+		from.Format("2006-01-02"),
+		to.Format("2006-01-02"),
+		zipCode,
+		region,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("querying product types: %w", err)
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			panic(err)
+		}
+	}()
+
+	var productTypes []string
+	for rows.Next() {
+		var productType string
+		if err := rows.Scan(&productType); err != nil {
+			return nil, fmt.Errorf("scanning product type: %w", err)
+		}
+		productTypes = append(productTypes, productType)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating product types: %w", err)
+	}
+
+	return productTypes, nil
+}
+
+// GetAveragePriceByWeekday returns the average price of a product type of a provider per
+// day of the week between from and to (inclusive), ordered by weekday (Sunday first).
+// Weekdays without prices are omitted. If zipCode is empty, prices of all zip codes are considered.
+func (d *DB) GetAveragePriceByWeekday(ctx context.Context, provider, productType string, from, to time.Time, zipCode string) ([]models.WeekdayAverage, error) {
+	query := `
+		SELECT EXTRACT(DOW FROM price_date)::int AS weekday, AVG(price_per_100l)::float8, COUNT(*)
+		FROM oil_prices
+		WHERE provider = $1 AND product_type = $2
+		AND price_date >= $3 AND price_date <= $4
+		AND ($5::text = '' OR zip_code = $5)
+		GROUP BY weekday
+		ORDER BY weekday
+	`
+
+	rows, err := d.queryContext(ctx, query,
+		provider,
+		productType,
+		from.Format("2006-01-02"),
+		to.Format("2006-01-02"),
+		zipCode,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("querying average price by weekday: %w", err)
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			panic(err)
+		}
+	}()
+
+	var averages []models.WeekdayAverage
+	for rows.Next() {
+		var a models.WeekdayAverage
+		if err := rows.Scan(&a.Weekday, &a.AveragePrice, &a.Count); err != nil {
+			return nil, fmt.Errorf("scanning weekday average: %w", err)
+		}
+		a.Name = time.Weekday(a.Weekday).String()
+		averages = append(averages, a)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating weekday averages: %w", err)
+	}
+
+	return averages, nil
+}
+
+// GetPricePercentiles returns the percentiles ps (each 0 <= p <= 1) of the prices of a product type
+// of a provider between from and to (inclusive), in the order of ps, computed in a single query.
+// If zipCode is empty, prices of all zip codes are considered; an empty region matches prices of
+// all regions. It returns nil if no prices exist in the window.
+func (d *DB) GetPricePercentiles(ctx context.Context, provider, productType string, from, to time.Time, ps []float64, zipCode, region string) ([]float64, error) {
+	for _, p := range ps {
+		if p < 0 || p > 1 {
+			return nil, fmt.Errorf("percentile must be between 0 and 1, got %v", p)
+		}
+	}
+
+	query := `
+		SELECT value
+		FROM unnest((
+			SELECT PERCENTILE_CONT($5::float8[]) WITHIN GROUP (ORDER BY price_per_100l::float8)
+			FROM oil_prices
+			WHERE provider = $1 AND product_type = $2
+			AND price_date >= $3 AND price_date <= $4
+			AND ($6::text = '' OR zip_code = $6)
+			AND ($7::text = '' OR region = $7)
+		)) WITH ORDINALITY AS percentiles(value, n)
+		ORDER BY n
+	`
+
+	rows, err := d.queryContext(ctx, query,
+		provider,
+		productType,
+		from.Format("2006-01-02"),
+		to.Format("2006-01-02"),
+		ps,
+		zipCode,
+		region,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("querying price percentiles: %w", err)
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			panic(err)
+		}
+	}()
+
+	// Without prices in the window, the percentiles are NULL and unnest returns no rows
+	var percentiles []float64
+	for rows.Next() {
+		var value float64
+		if err := rows.Scan(&value); err != nil {
+			return nil, fmt.Errorf("scanning price percentile: %w", err)
+		}
+		percentiles = append(percentiles, value)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating price percentiles: %w", err)
+	}
+
+	return percentiles, nil
+}
+
+// GetPricePercentRank returns the fraction (0 to 1) of prices of a product type of a provider
+// between from and to (inclusive) that are lower than price. If zipCode is empty, prices of all
+// zip codes are considered; an empty region matches prices of all regions.
+// It returns nil if no prices exist in the window.
+func (d *DB) GetPricePercentRank(ctx context.Context, provider, productType string, from, to time.Time, price float64, zipCode, region string) (*float64, error) {
+	query := `
+		SELECT COUNT(*), PERCENT_RANK($5::float8) WITHIN GROUP (ORDER BY price_per_100l::float8)
+		FROM oil_prices
+		WHERE provider = $1 AND product_type = $2
+		AND price_date >= $3 AND price_date <= $4
+		AND ($6::text = '' OR zip_code = $6)
+		AND ($7::text = '' OR region = $7)
+	`
+
+	var count int64
+	var rank float64
+	err := d.withReconnect(ctx, func() error {
+		return d.db.QueryRowContext(ctx, query,
+			provider,
+			productType,
+			from.Format("2006-01-02"),
+			to.Format("2006-01-02"),
+			price,
+			zipCode,
+			region,
+		).Scan(&count, &rank)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("querying price percent rank: %w", err)
+	}
+	if count == 0 {
+		return nil, nil
+	}
+
+	return &rank, nil
+}
+
+// UpdatePriceValue updates the price of an existing price record.
+func (d *DB) UpdatePriceValue(ctx context.Context, id uint64, pricePer100L float64) error {
+	var err error
+	if d.audit {
+		err = d.inTx(ctx, func(tx *sql.Tx) error {
+			var entry auditEntry
+			var priceDate time.Time
+			err := tx.QueryRowContext(ctx, `
+				SELECT provider, product_type, price_date, zip_code, region, price_per_100l
+				FROM oil_prices WHERE id = $1
+				FOR UPDATE
+			`, id).Scan(&entry.provider, &entry.productType, &priceDate, &entry.zipCode, &entry.region, &entry.oldPrice)
+			if err != nil {
+				return fmt.Errorf("querying previous price: %w", err)
+			}
+
+			if _, err := tx.ExecContext(ctx, "UPDATE oil_prices SET price_per_100l = $1 WHERE id = $2", pricePer100L, id); err != nil {
+				return err
+			}
+			// Rewriting an unchanged price is no change
+			if entry.oldPrice != nil && SamePrice(*entry.oldPrice, pricePer100L) {
+				return nil
+			}
+
+			priceID := int64(id)
+			entry.priceID = &priceID
+			entry.priceDate = priceDate
+			entry.action = AuditActionUpdate
+			entry.newPrice = &pricePer100L
+			return d.writeAudit(ctx, tx, entry)
+		})
+	} else {
+		_, err = d.db.ExecContext(ctx, "UPDATE oil_prices SET price_per_100l = $1 WHERE id = $2", pricePer100L, id)
+	}
+	if err != nil {
+		return fmt.Errorf("updating price: %w", err)
+	}
+
+	d.logger.Debug().
+		Uint64("id", id).
+		Float64("price", pricePer100L).
+		Msg("updated price record")
+
+	return nil
+}
+
+// nullableString returns nil for an empty string, so it is stored as NULL.
+func nullableString(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+// regionOrDefault returns the region or api.DefaultRegion if it is empty.
+func regionOrDefault(region string) string {
+	if region == "" {
+		return api.DefaultRegion
+	}
+	return region
+}
+
+// GetPriceAggregate returns the average and minimum of the prices of a product type of a provider
+// between from and to (inclusive). If productType is empty, prices of all product types are considered,
+// if zipCode is empty, prices of all zip codes.
+// It returns nil if no prices exist in the window.
+func (d *DB) GetPriceAggregate(ctx context.Context, provider, productType string, from, to time.Time, zipCode string) (*models.PriceAggregate, error) {
+	query := `
+		SELECT COUNT(*), COALESCE(AVG(price_per_100l), 0)::float8, COALESCE(MIN(price_per_100l), 0)::float8,
+			MAX(price_date) FILTER (WHERE price_per_100l = (
+				SELECT MIN(price_per_100l) FROM oil_prices
+				WHERE provider = $1 AND ($2::text = '' OR product_type = $2)
+				AND price_date >= $3 AND price_date <= $4
+				AND ($5::text = '' OR zip_code = $5)
+			))
+		FROM oil_prices
+		WHERE provider = $1 AND ($2::text = '' OR product_type = $2)
+		AND price_date >= $3 AND price_date <= $4
+		AND ($5::text = '' OR zip_code = $5)
+	`
+
+	var a models.PriceAggregate
+	var minDate sql.NullTime
+	err := d.withReconnect(ctx, func() error {
+		return d.db.QueryRowContext(ctx, query,
+			provider,
+			productType,
+			from.Format("2006-01-02"),
+			to.Format("2006-01-02"),
+			zipCode,
+		).Scan(&a.Count, &a.Average, &a.Min, &minDate)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("querying price aggregate: %w", err)
+	}
+	if a.Count == 0 {
+		return nil, nil
+	}
+	a.MinDate = minDate.Time
+
+	return &a, nil
+}
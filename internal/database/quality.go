@@ -0,0 +1,85 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/andygrunwald/oil-price-scraper/internal/models"
+)
+
+// GetDataQuality returns a quality summary of the prices of every provider between from and
+// to (inclusive), ordered by provider. A price is counted as anomaly if it differs by more than
+// anomalyThreshold percent from the previous price of the same series (product type, zip code,
+// and region). Providers without prices in the window are omitted.
+func (d *DB) GetDataQuality(ctx context.Context, from, to time.Time, anomalyThreshold float64) ([]models.ProviderQuality, error) {
+	query := `
+		WITH series AS (
+			SELECT provider, price_date, price_per_100l::float8 AS price,
+				LAG(price_per_100l::float8) OVER w AS prev_price
+			FROM oil_prices
+			WHERE price_date >= $1 AND price_date <= $2
+			WINDOW w AS (PARTITION BY provider, product_type, zip_code, region ORDER BY price_date)
+		), trends AS (
+			SELECT provider, REGR_SLOPE(price_per_100l::float8, (price_date - DATE '1970-01-01')::float8) AS slope
+			FROM oil_prices
+			WHERE price_date >= $1 AND price_date <= $2
+			GROUP BY provider, product_type, zip_code, region
+		), days AS (
+			SELECT provider, price_date,
+				price_date - LAG(price_date) OVER (PARTITION BY provider ORDER BY price_date) - 1 AS missing
+			FROM oil_prices
+			WHERE price_date >= $1 AND price_date <= $2
+			GROUP BY provider, price_date
+		)
+		SELECT s.provider, s.prices, s.first_date, s.last_date, s.last_fetched_at, s.average_price,
+			(SELECT COUNT(*) FROM days WHERE days.provider = s.provider),
+			(SELECT COUNT(*) FROM days WHERE days.provider = s.provider AND missing > 0),
+			(SELECT COALESCE(SUM(missing), 0) FROM days WHERE days.provider = s.provider AND missing > 0),
+			(SELECT COUNT(*) FROM series WHERE series.provider = s.provider
+				AND prev_price > 0 AND ABS(price - prev_price) / prev_price * 100 > $3),
+			(SELECT AVG(slope) FROM trends WHERE trends.provider = s.provider)
+		FROM (
+			SELECT provider, COUNT(*) AS prices, MIN(price_date) AS first_date, MAX(price_date) AS last_date,
+				MAX(fetched_at) AS last_fetched_at, AVG(price_per_100l)::float8 AS average_price
+			FROM oil_prices
+			WHERE price_date >= $1 AND price_date <= $2
+			GROUP BY provider
+		) s
+		ORDER BY s.provider
+	`
+
+	rows, err := d.queryContext(ctx, query,
+		from.Format("2006-01-02"),
+		to.Format("2006-01-02"),
+		anomalyThreshold,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("querying data quality: %w", err)
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			panic(err)
+		}
+	}()
+
+	var report []models.ProviderQuality
+	for rows.Next() {
+		var q models.ProviderQuality
+		var trend sql.NullFloat64
+		if err := rows.Scan(&q.Provider, &q.Prices, &q.FirstDate, &q.LastDate, &q.LastFetchedAt, &q.AveragePrice,
+			&q.DaysCovered, &q.Gaps, &q.MissingDays, &q.Anomalies, &trend); err != nil {
+			return nil, fmt.Errorf("scanning data quality: %w", err)
+		}
+		if trend.Valid {
+			q.Trend = &trend.Float64
+		}
+		report = append(report, q)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating data quality: %w", err)
+	}
+
+	return report, nil
+}
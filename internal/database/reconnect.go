@@ -0,0 +1,188 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+
+	"github.com/andygrunwald/oil-price-scraper/internal/models"
+)
+
+const (
+	// reconnectInitialBackoff is the delay before the first reconnect attempt.
+	reconnectInitialBackoff = time.Second
+	// reconnectMaxBackoff is the maximum delay between reconnect attempts.
+	reconnectMaxBackoff = 30 * time.Second
+	// reconnectTimeout bounds how long an operation waits for the database to come back.
+	reconnectTimeout = 2 * time.Minute
+)
+
+// isConnectionError returns true if err indicates a dead or unreachable database connection,
+// as opposed to errors of the statement itself.
+func isConnectionError(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	// A cancelled or timed out operation (e.g., an HTTP client that went away) says
+	// nothing about the connection, even if it surfaces as a net.Error
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	if errors.Is(err, driver.ErrBadConn) ||
+		errors.Is(err, io.EOF) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, syscall.ECONNRESET) {
+		return true
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+
+	var connectErr *pgconn.ConnectError
+	if errors.As(err, &connectErr) {
+		return true
+	}
+
+	// Class 57P: operator intervention (e.g., admin_shutdown, cannot_connect_now)
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && strings.HasPrefix(pgErr.Code, "57P") {
+		return true
+	}
+
+	return false
+}
+
+// Connected returns false if the database was detected to be unreachable
+// and no reconnect succeeded since.
+func (d *DB) Connected() bool {
+	return d.connected.Load()
+}
+
+// Ready pings the database and updates the connection state accordingly.
+func (d *DB) Ready(ctx context.Context) error {
+	if err := d.db.PingContext(ctx); err != nil {
+		d.connected.Store(false)
+		return fmt.Errorf("pinging database: %w", err)
+	}
+	d.connected.Store(true)
+	return nil
+}
+
+// safeToRetry returns true if err guarantees that the failed statement never
+// reached the database, so running it again cannot apply it twice.
+func safeToRetry(err error) bool {
+	return pgconn.SafeToRetry(err) || errors.Is(err, driver.ErrBadConn)
+}
+
+// withReconnect runs op. If op fails because the database connection is dead,
+// it waits with backoff until the database is reachable again and retries op once.
+// op must be safe to run twice (e.g., a read); writes use withWriteReconnect.
+func (d *DB) withReconnect(ctx context.Context, op func() error) error {
+	return d.retryReconnect(ctx, op, isConnectionError)
+}
+
+// withWriteReconnect is withReconnect for writes that must not be applied twice.
+// A connection lost after the write was sent (e.g., EOF or a connection reset) leaves
+// open whether the database applied it, so op is only retried if the write surely
+// never reached the database.
+func (d *DB) withWriteReconnect(ctx context.Context, op func() error) error {
+	return d.retryReconnect(ctx, op, func(err error) bool {
+		return isConnectionError(err) && safeToRetry(err)
+	})
+}
+
+// retryReconnect runs op and, if it fails with an error for which retry returns true,
+// waits with backoff until the database is reachable again and retries op once.
+func (d *DB) retryReconnect(ctx context.Context, op func() error, retry func(error) bool) error {
+	err := op()
+	if !retry(err) {
+		return err
+	}
+
+	d.connected.Store(false)
+	d.logger.Warn().Err(err).Msg("database connection lost, reconnecting")
+
+	if rerr := d.reconnect(ctx); rerr != nil {
+		return fmt.Errorf("%w (%v)", err, rerr)
+	}
+
+	return op()
+}
+
+// queryContext runs a query that returns rows. If the database connection is dead,
+// it reconnects and runs the query again (see withReconnect).
+func (d *DB) queryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	var rows *sql.Rows
+	err := d.withReconnect(ctx, func() error {
+		var err error
+		rows, err = d.db.QueryContext(ctx, query, args...)
+		return err
+	})
+	return rows, err
+}
+
+// queryPrice runs a query that returns a single price record, scanned with scan. If the
+// database connection is dead, it reconnects and runs the query again (see withReconnect).
+func (d *DB) queryPrice(ctx context.Context, scan func(rowScanner) (models.OilPrice, error), query string, args ...any) (models.OilPrice, error) {
+	var p models.OilPrice
+	err := d.withReconnect(ctx, func() error {
+		var err error
+		p, err = scan(d.db.QueryRowContext(ctx, query, args...))
+		return err
+	})
+	return p, err
+}
+
+// reconnect pings the database with exponential backoff until it is reachable,
+// the context ends, or reconnectTimeout passes. Concurrent callers wait for
+// the same reconnect instead of starting their own.
+func (d *DB) reconnect(ctx context.Context) error {
+	d.reconnectMu.Lock()
+	defer d.reconnectMu.Unlock()
+
+	// Another caller may have reconnected in the meantime
+	if d.connected.Load() {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, reconnectTimeout)
+	defer cancel()
+
+	backoff := reconnectInitialBackoff
+	for attempt := 1; ; attempt++ {
+		err := d.db.PingContext(ctx)
+		if err == nil {
+			d.connected.Store(true)
+			d.logger.Info().Int("attempt", attempt).Msg("database connection re-established")
+			return nil
+		}
+
+		d.logger.Warn().
+			Err(err).
+			Int("attempt", attempt).
+			Dur("backoff", backoff).
+			Msg("database reconnect failed")
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("reconnecting to database: %w", ctx.Err())
+		case <-time.After(backoff):
+		}
+
+		backoff = min(backoff*2, reconnectMaxBackoff)
+	}
+}
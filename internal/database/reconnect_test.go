@@ -0,0 +1,50 @@
+package database
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"syscall"
+	"testing"
+)
+
+func TestSafeToRetry(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "bad connection", err: driver.ErrBadConn, want: true},
+		{name: "wrapped bad connection", err: fmt.Errorf("inserting: %w", driver.ErrBadConn), want: true},
+		{name: "EOF", err: io.EOF, want: false},
+		{name: "connection reset", err: syscall.ECONNRESET, want: false},
+		{name: "statement error", err: errors.New("duplicate key"), want: false},
+	}
+
+	for _, tt := range tests {
+		if got := safeToRetry(tt.err); got != tt.want {
+			t.Errorf("safeToRetry(%s) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestWithWriteReconnectDoesNotRetryAppliedWrites(t *testing.T) {
+	// The connection was lost after the write was sent, so it may have been applied
+	for _, opErr := range []error{io.EOF, io.ErrUnexpectedEOF, syscall.ECONNRESET} {
+		d := &DB{}
+
+		calls := 0
+		err := d.withWriteReconnect(context.Background(), func() error {
+			calls++
+			return opErr
+		})
+		if !errors.Is(err, opErr) {
+			t.Errorf("withWriteReconnect() error = %v, want %v", err, opErr)
+		}
+		if calls != 1 {
+			t.Errorf("op ran %d times after %v, want 1", calls, opErr)
+		}
+	}
+}
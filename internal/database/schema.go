@@ -0,0 +1,159 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// expectedColumns are the columns of the oil_prices table after all migrations,
+// with their data type as reported by information_schema.columns.
+var expectedColumns = map[string]string{
+	"id":                   "bigint",
+	"provider":             "character varying",
+	"product_type":         "character varying",
+	"price_date":           "date",
+	"price_per_100l":       "numeric",
+	"currency":             "character varying",
+	"scope":                "character varying",
+	"zip_code":             "character varying",
+	"region":               "character varying",
+	"source_url":           "text",
+	"label":                "character varying",
+	"published_at":         "timestamp without time zone",
+	"delivery_days":        "integer",
+	"delivery_time_type":   "character varying",
+	"is_premium":           "boolean",
+	"is_climate_neutral":   "boolean",
+	"raw_response":         "jsonb",
+	"raw_response_gzip":    "bytea",
+	"raw_response_headers": "jsonb",
+	"fetched_at":           "timestamp without time zone",
+	"created_at":           "timestamp without time zone",
+}
+
+// expectedUniqueColumns are the columns of the unique constraint InsertPrice upserts on.
+var expectedUniqueColumns = []string{"price_date", "product_type", "provider", "region", "zip_code"}
+
+// SchemaError lists the differences between the oil_prices table and the expected schema.
+type SchemaError struct {
+	Problems []string
+}
+
+// Error implements the error interface.
+func (e *SchemaError) Error() string {
+	return fmt.Sprintf("oil_prices table does not match the expected schema (apply the SQL files in migrations/): %s", strings.Join(e.Problems, "; "))
+}
+
+// VerifySchema checks that the oil_prices table exists with the expected columns and
+// the unique constraint of (provider, product_type, price_date, zip_code, region).
+// Discrepancies are returned as a *SchemaError listing all of them, so a hand-created
+// or outdated table is detected at startup instead of failing on the first insert.
+func (d *DB) VerifySchema(ctx context.Context) error {
+	var table sql.NullString
+	if err := d.db.QueryRowContext(ctx, `SELECT to_regclass('oil_prices')::text`).Scan(&table); err != nil {
+		return fmt.Errorf("looking up oil_prices table: %w", err)
+	}
+	if !table.Valid {
+		return &SchemaError{Problems: []string{"table oil_prices does not exist"}}
+	}
+
+	var problems []string
+
+	columns, err := d.tableColumns(ctx)
+	if err != nil {
+		return err
+	}
+	names := make([]string, 0, len(expectedColumns))
+	for name := range expectedColumns {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		dataType, ok := columns[name]
+		if !ok {
+			problems = append(problems, fmt.Sprintf("missing column %s", name))
+			continue
+		}
+		if dataType != expectedColumns[name] {
+			problems = append(problems, fmt.Sprintf("column %s has type %s, expected %s", name, dataType, expectedColumns[name]))
+		}
+	}
+
+	uniqueProblem, err := d.checkUniqueConstraint(ctx)
+	if err != nil {
+		return err
+	}
+	if uniqueProblem != "" {
+		problems = append(problems, uniqueProblem)
+	}
+
+	if len(problems) > 0 {
+		return &SchemaError{Problems: problems}
+	}
+
+	d.logger.Debug().Msg("database schema verified")
+	return nil
+}
+
+// tableColumns returns the data type of every column of the oil_prices table.
+func (d *DB) tableColumns(ctx context.Context) (map[string]string, error) {
+	rows, err := d.db.QueryContext(ctx, `
+		SELECT column_name, data_type
+		FROM information_schema.columns
+		WHERE table_schema = current_schema() AND table_name = 'oil_prices'
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("querying columns: %w", err)
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			panic(err)
+		}
+	}()
+
+	columns := make(map[string]string)
+	for rows.Next() {
+		var name, dataType string
+		if err := rows.Scan(&name, &dataType); err != nil {
+			return nil, fmt.Errorf("scanning column: %w", err)
+		}
+		columns[name] = dataType
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating columns: %w", err)
+	}
+
+	return columns, nil
+}
+
+// checkUniqueConstraint returns a description of the problem if oil_prices has no unique
+// index on exactly the expected columns treating NULLs as equal, or an empty string if it has.
+func (d *DB) checkUniqueConstraint(ctx context.Context) (string, error) {
+	var nullsNotDistinct bool
+	err := d.db.QueryRowContext(ctx, `
+		SELECT i.indnullsnotdistinct
+		FROM pg_index i
+		WHERE i.indrelid = 'oil_prices'::regclass AND i.indisunique
+		AND (
+			SELECT string_agg(a.attname, ',' ORDER BY a.attname)
+			FROM pg_attribute a
+			WHERE a.attrelid = i.indrelid AND a.attnum = ANY(i.indkey)
+		) = $1
+		ORDER BY i.indnullsnotdistinct DESC
+		LIMIT 1
+	`, strings.Join(expectedUniqueColumns, ",")).Scan(&nullsNotDistinct)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "missing unique constraint on (provider, product_type, price_date, zip_code, region)", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("querying unique constraint: %w", err)
+	}
+	if !nullsNotDistinct {
+		return "unique constraint on (provider, product_type, price_date, zip_code, region) must be NULLS NOT DISTINCT", nil
+	}
+	return "", nil
+}
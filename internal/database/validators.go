@@ -0,0 +1,50 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/andygrunwald/oil-price-scraper/internal/api"
+)
+
+// GetValidators returns the cache validators of the last response of a provider,
+// or nil if none are stored. It implements api.ValidatorStore.
+func (d *DB) GetValidators(ctx context.Context, provider string) (*api.Validators, error) {
+	var v api.Validators
+	var etag, lastModified sql.NullString
+	err := d.db.QueryRowContext(ctx, `
+		SELECT url, etag, last_modified, saved_at
+		FROM provider_validators
+		WHERE provider = $1
+	`, provider).Scan(&v.URL, &etag, &lastModified, &v.SavedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("querying validators: %w", err)
+	}
+	v.ETag = etag.String
+	v.LastModified = lastModified.String
+	return &v, nil
+}
+
+// SaveValidators replaces the cache validators of a provider. It implements api.ValidatorStore.
+// Unlike price writes, it does not wait for a lost connection, as it runs within a provider request.
+func (d *DB) SaveValidators(ctx context.Context, provider string, v api.Validators) error {
+	_, err := d.db.ExecContext(ctx, `
+		INSERT INTO provider_validators (provider, url, etag, last_modified, saved_at)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (provider)
+		DO UPDATE SET
+			url = EXCLUDED.url,
+			etag = EXCLUDED.etag,
+			last_modified = EXCLUDED.last_modified,
+			saved_at = EXCLUDED.saved_at
+	`, provider, v.URL, nullableString(v.ETag), nullableString(v.LastModified), v.SavedAt)
+	if err != nil {
+		return fmt.Errorf("saving validators: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,162 @@
+// Package export converts stored prices into formats of other time series databases.
+package export
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/andygrunwald/oil-price-scraper/internal/models"
+)
+
+// DefaultMeasurement is the measurement name of exported InfluxDB points.
+const DefaultMeasurement = "oil_price"
+
+// Tags that can be attached to exported InfluxDB points.
+const (
+	TagProvider    = "provider"
+	TagScope       = "scope"
+	TagProductType = "product_type"
+	TagZipCode     = "zip_code"
+	TagRegion      = "region"
+	TagLabel       = "label"
+)
+
+// DefaultTags are the tags attached to exported InfluxDB points by default.
+var DefaultTags = []string{TagProvider, TagScope, TagProductType, TagZipCode, TagRegion}
+
+// measurementEscaper escapes measurement names according to the InfluxDB line protocol.
+var measurementEscaper = strings.NewReplacer(`,`, `\,`, ` `, `\ `)
+
+// tagEscaper escapes tag keys and values according to the InfluxDB line protocol.
+var tagEscaper = strings.NewReplacer(`,`, `\,`, `=`, `\=`, ` `, `\ `)
+
+// stringFieldEscaper escapes string field values according to the InfluxDB line protocol.
+var stringFieldEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+
+// Influx writes prices as InfluxDB line protocol, one point per price:
+//
+//	oil_price,provider=hoyer,scope=local,product_type=bestpreis value=97.81,source_url="https://..." 1736640000000000000
+//
+// The timestamp is the price date in nanoseconds. Tags with empty values are omitted.
+// The source URL is a field, not a tag, so it does not create a series per request,
+// and is omitted if the price has none.
+type Influx struct {
+	// Measurement is the measurement name. Empty uses DefaultMeasurement.
+	Measurement string
+	// Tags are the tags attached to every point, in this order. Nil uses DefaultTags.
+	Tags []string
+}
+
+// ParseTags parses a comma-separated list of tag names.
+func ParseTags(value string) ([]string, error) {
+	var tags []string
+	for tag := range strings.SplitSeq(value, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "" {
+			continue
+		}
+		switch tag {
+		case TagProvider, TagScope, TagProductType, TagZipCode, TagRegion, TagLabel:
+			tags = append(tags, tag)
+		default:
+			return nil, fmt.Errorf("unknown tag %q (expected %s)", tag, strings.Join([]string{TagProvider, TagScope, TagProductType, TagZipCode, TagRegion, TagLabel}, ", "))
+		}
+	}
+	return tags, nil
+}
+
+// PointWriter writes prices as line protocol while they are read, so an export is never held in memory at once.
+type PointWriter struct {
+	influx Influx
+	w      *bufio.Writer
+	line   []byte
+	points int
+}
+
+// NewWriter returns a PointWriter that writes to w. Flush must be called after the last price.
+func (e Influx) NewWriter(w io.Writer) *PointWriter {
+	return &PointWriter{
+		influx: e,
+		w:      bufio.NewWriter(w),
+	}
+}
+
+// WritePrice writes the point of a price.
+func (pw *PointWriter) WritePrice(p models.OilPrice) error {
+	pw.line = pw.influx.appendPoint(pw.line[:0], p)
+	if _, err := pw.w.Write(pw.line); err != nil {
+		return err
+	}
+	pw.points++
+	return nil
+}
+
+// Flush writes any buffered points to the underlying writer.
+func (pw *PointWriter) Flush() error {
+	return pw.w.Flush()
+}
+
+// Points returns the number of points written.
+func (pw *PointWriter) Points() int {
+	return pw.points
+}
+
+// appendPoint appends the line protocol point of a price, including the trailing newline, to line.
+func (e Influx) appendPoint(line []byte, p models.OilPrice) []byte {
+	measurement := e.Measurement
+	if measurement == "" {
+		measurement = DefaultMeasurement
+	}
+	tags := e.Tags
+	if tags == nil {
+		tags = DefaultTags
+	}
+
+	line = append(line, measurementEscaper.Replace(measurement)...)
+	for _, tag := range tags {
+		value := tagValue(p, tag)
+		if value == "" {
+			continue
+		}
+		line = append(line, ',')
+		line = append(line, tag...)
+		line = append(line, '=')
+		line = append(line, tagEscaper.Replace(value)...)
+	}
+	line = append(line, " value="...)
+	line = strconv.AppendFloat(line, p.PricePer100L, 'f', -1, 64)
+	if p.SourceURL != nil && *p.SourceURL != "" {
+		line = append(line, `,source_url="`...)
+		line = append(line, stringFieldEscaper.Replace(*p.SourceURL)...)
+		line = append(line, '"')
+	}
+	line = append(line, ' ')
+	line = strconv.AppendInt(line, p.PriceDate.UnixNano(), 10)
+	return append(line, '\n')
+}
+
+// tagValue returns the value of a tag of a price.
+func tagValue(p models.OilPrice, tag string) string {
+	switch tag {
+	case TagProvider:
+		return p.Provider
+	case TagScope:
+		return string(p.Scope)
+	case TagProductType:
+		return p.ProductType
+	case TagZipCode:
+		if p.ZipCode != nil {
+			return *p.ZipCode
+		}
+	case TagRegion:
+		return p.Region
+	case TagLabel:
+		if p.Label != nil {
+			return *p.Label
+		}
+	}
+	return ""
+}
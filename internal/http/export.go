@@ -0,0 +1,187 @@
+package http
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/andygrunwald/oil-price-scraper/internal/database"
+	"github.com/andygrunwald/oil-price-scraper/internal/export"
+	"github.com/andygrunwald/oil-price-scraper/internal/models"
+)
+
+// exportMetricName is the metric name used for exported price samples.
+const exportMetricName = "oilscraper_price_eur"
+
+// exportWriteTimeout is the time allowed for writing every chunk of a streamed export.
+// It replaces the write timeout of the server, which would cut off long exports.
+const exportWriteTimeout = 10 * time.Second
+
+// labelValueEscaper escapes label values according to the Prometheus text format.
+var labelValueEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// PrometheusExportHandler handles the /export/prometheus endpoint.
+// It emits stored price history in the Prometheus text format with explicit
+// timestamps per sample, suitable for importing into Prometheus or VictoriaMetrics.
+type PrometheusExportHandler struct {
+	db *database.DB
+}
+
+// NewPrometheusExportHandler creates a new PrometheusExportHandler.
+func NewPrometheusExportHandler(db *database.DB) *PrometheusExportHandler {
+	return &PrometheusExportHandler{
+		db: db,
+	}
+}
+
+// ServeHTTP implements the http.Handler interface.
+func (h *PrometheusExportHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	provider := query.Get("provider")
+
+	from, err := parseDateParam(query.Get("from"), time.Time{})
+	if err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid from parameter: %v", err))
+		return
+	}
+	to, err := parseDateParam(query.Get("to"), time.Now())
+	if err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid to parameter: %v", err))
+		return
+	}
+
+	// The source URL is opt-in, as it creates a new series per request
+	withSourceURL := query.Get("source_url") == "true"
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+
+	sw := newStartedWriter(w)
+	bw := bufio.NewWriter(sw)
+	fmt.Fprintf(bw, "# HELP %s Oil price in EUR per 100L\n", exportMetricName)
+	fmt.Fprintf(bw, "# TYPE %s gauge\n", exportMetricName)
+
+	err = h.db.EachPriceForDateRange(r.Context(), provider, from, to, func(p models.OilPrice) error {
+		zipCode := ""
+		if p.ZipCode != nil {
+			zipCode = *p.ZipCode
+		}
+		sourceURL := ""
+		if withSourceURL && p.SourceURL != nil {
+			sourceURL = fmt.Sprintf(",source_url=\"%s\"", labelValueEscaper.Replace(*p.SourceURL))
+		}
+
+		_, err := fmt.Fprintf(bw, "%s{provider=\"%s\",scope=\"%s\",product_type=\"%s\",zip_code=\"%s\",region=\"%s\"%s} %s %d\n",
+			exportMetricName,
+			labelValueEscaper.Replace(p.Provider),
+			labelValueEscaper.Replace(string(p.Scope)),
+			labelValueEscaper.Replace(p.ProductType),
+			labelValueEscaper.Replace(zipCode),
+			labelValueEscaper.Replace(p.Region),
+			sourceURL,
+			strconv.FormatFloat(p.PricePer100L, 'f', -1, 64),
+			p.PriceDate.UnixMilli(),
+		)
+		return err
+	})
+	if err == nil {
+		err = bw.Flush()
+	}
+	finishExport(w, sw, err)
+}
+
+// InfluxExportHandler handles the /export/influx endpoint.
+// It emits stored price history as InfluxDB line protocol.
+type InfluxExportHandler struct {
+	db *database.DB
+}
+
+// NewInfluxExportHandler creates a new InfluxExportHandler.
+func NewInfluxExportHandler(db *database.DB) *InfluxExportHandler {
+	return &InfluxExportHandler{
+		db: db,
+	}
+}
+
+// ServeHTTP implements the http.Handler interface.
+func (h *InfluxExportHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	provider := query.Get("provider")
+
+	from, err := parseDateParam(query.Get("from"), time.Time{})
+	if err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid from parameter: %v", err))
+		return
+	}
+	to, err := parseDateParam(query.Get("to"), time.Now())
+	if err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid to parameter: %v", err))
+		return
+	}
+
+	exporter := export.Influx{Measurement: query.Get("measurement")}
+	if query.Has("tags") {
+		exporter.Tags, err = export.ParseTags(query.Get("tags"))
+		if err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid tags parameter: %v", err))
+			return
+		}
+		if exporter.Tags == nil {
+			exporter.Tags = []string{}
+		}
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+
+	sw := newStartedWriter(w)
+	pw := exporter.NewWriter(sw)
+	err = h.db.EachPriceForDateRange(r.Context(), provider, from, to, pw.WritePrice)
+	if err == nil {
+		err = pw.Flush()
+	}
+	finishExport(w, sw, err)
+}
+
+// startedWriter records whether anything was written to the response,
+// after which its status can no longer be changed. It extends the write
+// deadline of the response before every write, so exports are not limited
+// by the write timeout of the server.
+type startedWriter struct {
+	w       http.ResponseWriter
+	rc      *http.ResponseController
+	started bool
+}
+
+// newStartedWriter creates a startedWriter writing to w.
+func newStartedWriter(w http.ResponseWriter) *startedWriter {
+	return &startedWriter{w: w, rc: http.NewResponseController(w)}
+}
+
+// Write implements the io.Writer interface.
+func (sw *startedWriter) Write(p []byte) (int, error) {
+	if err := sw.rc.SetWriteDeadline(time.Now().Add(exportWriteTimeout)); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		return 0, fmt.Errorf("extending write deadline: %w", err)
+	}
+	sw.started = true
+	return sw.w.Write(p)
+}
+
+// finishExport answers a streamed export that failed with err before anything was written
+// with an error response. Once written, the response can only end early: the client may be
+// gone, so there is nobody left to report the error to.
+func finishExport(w http.ResponseWriter, sw *startedWriter, err error) {
+	if err != nil && !sw.started {
+		writeError(w, http.StatusInternalServerError, "failed to query prices")
+	}
+}
+
+// parseDateParam parses a YYYY-MM-DD query parameter, returning def if the value is empty.
+func parseDateParam(value string, def time.Time) (time.Time, error) {
+	if value == "" {
+		return def, nil
+	}
+	return time.Parse("2006-01-02", value)
+}
@@ -0,0 +1,106 @@
+package http
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/andygrunwald/oil-price-scraper/internal/database"
+	"github.com/andygrunwald/oil-price-scraper/internal/models"
+	"github.com/andygrunwald/oil-price-scraper/internal/scraper"
+)
+
+const (
+	// defaultMaxFailures is the number of consecutive failed scrapes after which a provider is unhealthy.
+	defaultMaxFailures = 3
+	// defaultMaxDataAge is the age of the newest stored price after which a provider is unhealthy.
+	// It spans a weekend, as providers do not publish national prices every day.
+	defaultMaxDataAge = 72 * time.Hour
+)
+
+// ProvidersHealthHandler handles the /providers/health endpoint.
+// It is a focused health view of every provider, combining scrape failures
+// and the freshness of the stored data.
+type ProvidersHealthHandler struct {
+	scraper *scraper.Scraper
+	db      *database.DB
+}
+
+// NewProvidersHealthHandler creates a new ProvidersHealthHandler.
+func NewProvidersHealthHandler(s *scraper.Scraper, db *database.DB) *ProvidersHealthHandler {
+	return &ProvidersHealthHandler{
+		scraper: s,
+		db:      db,
+	}
+}
+
+// ServeHTTP implements the http.Handler interface.
+func (h *ProvidersHealthHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	maxFailures := int64(defaultMaxFailures)
+	if v := query.Get("max_failures"); v != "" {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || n < 1 {
+			writeError(w, http.StatusBadRequest, "invalid max_failures parameter: must be a positive integer")
+			return
+		}
+		maxFailures = n
+	}
+
+	maxDataAge := defaultMaxDataAge
+	if v := query.Get("max_data_age"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid max_data_age parameter: %v", err))
+			return
+		}
+		maxDataAge = d
+	}
+
+	strict := query.Get("strict") == "true"
+
+	response := models.ProvidersHealthResponse{
+		Healthy:   true,
+		Providers: make(map[string]models.ProviderHealth),
+	}
+
+	for _, provider := range h.scraper.GetProviders() {
+		metrics := h.scraper.GetMetrics(provider.Name())
+		if metrics == nil {
+			continue
+		}
+
+		snapshot := metrics.GetSnapshot()
+		health := models.ProviderHealth{
+			Enabled:             true,
+			Problems:            []string{},
+			LastScrapeAt:        snapshot.LastScrapeAt,
+			LastSuccessAt:       snapshot.LastSuccessAt,
+			ConsecutiveFailures: snapshot.ConsecutiveFailures,
+			LastError:           snapshot.LastError,
+		}
+		health.LastDataDate, health.DataAgeSeconds = dataFreshness(r.Context(), h.db, provider.Name())
+
+		if snapshot.ConsecutiveFailures >= maxFailures {
+			health.Problems = append(health.Problems, fmt.Sprintf("%d consecutive failed scrapes", snapshot.ConsecutiveFailures))
+		}
+		if health.DataAgeSeconds != nil && maxDataAge > 0 && time.Duration(*health.DataAgeSeconds)*time.Second > maxDataAge {
+			health.Problems = append(health.Problems, fmt.Sprintf("newest price is older than %s", maxDataAge))
+		}
+
+		health.Healthy = len(health.Problems) == 0
+		if !health.Healthy {
+			response.Healthy = false
+		}
+		response.Providers[provider.Name()] = health
+	}
+
+	status := http.StatusOK
+	if strict && !response.Healthy {
+		status = http.StatusServiceUnavailable
+	}
+
+	writeJSON(w, status, response)
+}
@@ -11,27 +11,51 @@ type Metrics struct {
 	// API request metrics
 	APIRequestsTotal   *prometheus.CounterVec
 	APIRequestDuration *prometheus.HistogramVec
+	APIErrorsTotal     *prometheus.CounterVec
+	APIResponseSize    *prometheus.HistogramVec
+	APINotModified     *prometheus.CounterVec
 
 	// Scrape metrics
+	ScrapeCycleDuration prometheus.Histogram
+	ScrapeCycleRetries  prometheus.Counter
 	LastScrapeTimestamp *prometheus.GaugeVec
 	CurrentPriceEUR     *prometheus.GaugeVec
+	HeadlinePriceEUR    *prometheus.GaugeVec
+	PrimaryPriceEUR     prometheus.Gauge
 
 	// Database metrics
 	DBOperationsTotal *prometheus.CounterVec
 	PricesStoredTotal *prometheus.GaugeVec
+
+	// Provider health metrics
+	ProvidersHealthy prometheus.Gauge
+	ProvidersTotal   prometheus.Gauge
 }
 
 // NewMetrics creates and registers Prometheus metrics.
 func NewMetrics() *Metrics {
+	return newMetrics(promauto.With(prometheus.DefaultRegisterer))
+}
+
+// NewTenantMetrics creates and registers the Prometheus metrics of a tenant.
+// They carry a constant tenant label, so the metrics of several tenants served
+// by one process can be told apart. A process must not mix them with NewMetrics.
+func NewTenantMetrics(tenant string) *Metrics {
+	registerer := prometheus.WrapRegistererWith(prometheus.Labels{"tenant": tenant}, prometheus.DefaultRegisterer)
+	return newMetrics(promauto.With(registerer))
+}
+
+// newMetrics creates the Prometheus metrics and registers them with factory.
+func newMetrics(factory promauto.Factory) *Metrics {
 	return &Metrics{
-		APIRequestsTotal: promauto.NewCounterVec(
+		APIRequestsTotal: factory.NewCounterVec(
 			prometheus.CounterOpts{
 				Name: "oilscraper_api_requests_total",
 				Help: "Total number of API requests by provider and status",
 			},
 			[]string{"provider", "status"},
 		),
-		APIRequestDuration: promauto.NewHistogramVec(
+		APIRequestDuration: factory.NewHistogramVec(
 			prometheus.HistogramOpts{
 				Name:    "oilscraper_api_request_duration_seconds",
 				Help:    "API request duration in seconds",
@@ -39,34 +63,96 @@ func NewMetrics() *Metrics {
 			},
 			[]string{"provider"},
 		),
-		LastScrapeTimestamp: promauto.NewGaugeVec(
+		APIErrorsTotal: factory.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "oilscraper_api_errors_total",
+				Help: "Total number of failed API requests by provider and reason (transport, timeout, http_status, rate_limit, parse, other)",
+			},
+			[]string{"provider", "reason"},
+		),
+		APIResponseSize: factory.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Name: "oilscraper_api_response_size_bytes",
+				Help: "Size of API response bodies in bytes",
+				// 512 B up to 8 MiB
+				Buckets: prometheus.ExponentialBuckets(512, 4, 8),
+			},
+			[]string{"provider"},
+		),
+		APINotModified: factory.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "oilscraper_api_not_modified_total",
+				Help: "Total number of conditional API requests answered with 304 Not Modified by provider",
+			},
+			[]string{"provider"},
+		),
+		ScrapeCycleDuration: factory.NewHistogram(
+			prometheus.HistogramOpts{
+				Name: "oilscraper_scrape_cycle_duration_seconds",
+				Help: "Duration of scheduled scrape cycles including retries in seconds",
+				// 1s up to ~68m, as cycles with retries may take long
+				Buckets: prometheus.ExponentialBuckets(1, 2, 13),
+			},
+		),
+		ScrapeCycleRetries: factory.NewCounter(
+			prometheus.CounterOpts{
+				Name: "oilscraper_scrape_cycle_retries_total",
+				Help: "Total number of scrape cycles retried because every provider failed",
+			},
+		),
+		LastScrapeTimestamp: factory.NewGaugeVec(
 			prometheus.GaugeOpts{
 				Name: "oilscraper_last_scrape_timestamp",
 				Help: "Timestamp of the last successful scrape",
 			},
 			[]string{"provider"},
 		),
-		CurrentPriceEUR: promauto.NewGaugeVec(
+		CurrentPriceEUR: factory.NewGaugeVec(
 			prometheus.GaugeOpts{
 				Name: "oilscraper_current_price_eur",
 				Help: "Current oil price in EUR per 100L",
 			},
 			[]string{"provider", "scope", "product_type"},
 		),
-		DBOperationsTotal: promauto.NewCounterVec(
+		HeadlinePriceEUR: factory.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "oilscraper_headline_price_eur",
+				Help: "Price in EUR per 100L representing the provider in the last successful scrape (see --headline-product)",
+			},
+			[]string{"provider"},
+		),
+		PrimaryPriceEUR: factory.NewGauge(
+			prometheus.GaugeOpts{
+				Name: "oilscraper_primary_price_eur",
+				Help: "Headline price in EUR per 100L of the primary provider (see --primary-provider)",
+			},
+		),
+		DBOperationsTotal: factory.NewCounterVec(
 			prometheus.CounterOpts{
 				Name: "oilscraper_db_operations_total",
 				Help: "Total number of database operations by type and status",
 			},
 			[]string{"operation", "status"},
 		),
-		PricesStoredTotal: promauto.NewGaugeVec(
+		PricesStoredTotal: factory.NewGaugeVec(
 			prometheus.GaugeOpts{
 				Name: "oilscraper_prices_stored_total",
 				Help: "Total number of prices stored in database by provider",
 			},
 			[]string{"provider"},
 		),
+		ProvidersHealthy: factory.NewGauge(
+			prometheus.GaugeOpts{
+				Name: "oilscraper_providers_healthy",
+				Help: "Number of providers whose last scrape succeeded",
+			},
+		),
+		ProvidersTotal: factory.NewGauge(
+			prometheus.GaugeOpts{
+				Name: "oilscraper_providers_total",
+				Help: "Total number of registered providers",
+			},
+		),
 	}
 }
 
@@ -76,11 +162,46 @@ func (m *Metrics) RecordAPIRequest(provider, status string, duration float64) {
 	m.APIRequestDuration.WithLabelValues(provider).Observe(duration)
 }
 
+// RecordAPIError records a failed API request by reason.
+func (m *Metrics) RecordAPIError(provider, reason string) {
+	m.APIErrorsTotal.WithLabelValues(provider, reason).Inc()
+}
+
+// RecordResponseSize records the size of an API response body.
+func (m *Metrics) RecordResponseSize(provider string, bytes int) {
+	m.APIResponseSize.WithLabelValues(provider).Observe(float64(bytes))
+}
+
+// RecordNotModified records a conditional API request answered with 304 Not Modified.
+func (m *Metrics) RecordNotModified(provider string) {
+	m.APINotModified.WithLabelValues(provider).Inc()
+}
+
+// RecordScrapeCycle records the duration of a scheduled scrape cycle.
+func (m *Metrics) RecordScrapeCycle(duration float64) {
+	m.ScrapeCycleDuration.Observe(duration)
+}
+
+// RecordScrapeCycleRetry records the retry of a scrape cycle in which every provider failed.
+func (m *Metrics) RecordScrapeCycleRetry() {
+	m.ScrapeCycleRetries.Inc()
+}
+
 // RecordLastScrape records the last successful scrape timestamp.
 func (m *Metrics) RecordLastScrape(provider string, timestamp float64) {
 	m.LastScrapeTimestamp.WithLabelValues(provider).Set(timestamp)
 }
 
+// RecordHeadlinePrice records the price representing a provider in its last scrape.
+func (m *Metrics) RecordHeadlinePrice(provider string, price float64) {
+	m.HeadlinePriceEUR.WithLabelValues(provider).Set(price)
+}
+
+// RecordPrimaryPrice records the headline price of the primary provider.
+func (m *Metrics) RecordPrimaryPrice(price float64) {
+	m.PrimaryPriceEUR.Set(price)
+}
+
 // RecordCurrentPrice records the current oil price.
 func (m *Metrics) RecordCurrentPrice(provider, scope, productType string, price float64) {
 	m.CurrentPriceEUR.WithLabelValues(provider, scope, productType).Set(price)
@@ -95,3 +216,9 @@ func (m *Metrics) RecordDBOperation(operation, status string) {
 func (m *Metrics) RecordPricesStored(provider string, count float64) {
 	m.PricesStoredTotal.WithLabelValues(provider).Set(count)
 }
+
+// RecordProviderHealth records the number of healthy and total providers.
+func (m *Metrics) RecordProviderHealth(healthy, total int) {
+	m.ProvidersHealthy.Set(float64(healthy))
+	m.ProvidersTotal.Set(float64(total))
+}
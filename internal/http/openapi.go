@@ -0,0 +1,438 @@
+package http
+
+import (
+	"net/http"
+	"reflect"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/andygrunwald/oil-price-scraper/internal/models"
+)
+
+// openAPIVersion is the version of the OpenAPI specification the document follows.
+const openAPIVersion = "3.1.0"
+
+// openAPIDocument is the OpenAPI description of the HTTP API served at /openapi.json.
+// Paths are maintained next to the handlers they describe (see NewServer and the
+// Enable methods); response schemas are derived from the models types.
+type openAPIDocument struct {
+	OpenAPI    string                                  `json:"openapi"`
+	Info       openAPIInfo                             `json:"info"`
+	Paths      map[string]map[string]*openAPIOperation `json:"paths"`
+	Components openAPIComponents                       `json:"components"`
+	// tenants is true if the data of every tenant is served below /t/{tenant} (see NewTenantServer)
+	tenants bool
+}
+
+// sharedOpenAPIPaths are the paths served once for all tenants (see NewTenantServer).
+var sharedOpenAPIPaths = map[string]bool{
+	"/health":       true,
+	"/readyz":       true,
+	"/metrics":      true,
+	"/openapi.json": true,
+}
+
+// openAPIInfo describes the API.
+type openAPIInfo struct {
+	Title   string `json:"title"`
+	Version string `json:"version"`
+}
+
+// openAPIComponents holds the schemas referenced by the operations.
+type openAPIComponents struct {
+	Schemas         map[string]any `json:"schemas"`
+	SecuritySchemes map[string]any `json:"securitySchemes,omitempty"`
+}
+
+// openAPIOperation describes a method of a path.
+type openAPIOperation struct {
+	Summary    string                      `json:"summary"`
+	Parameters []openAPIParameter          `json:"parameters,omitempty"`
+	Responses  map[string]*openAPIResponse `json:"responses"`
+	Security   []map[string][]string       `json:"security,omitempty"`
+}
+
+// openAPIParameter describes a query or path parameter.
+type openAPIParameter struct {
+	Name        string         `json:"name"`
+	In          string         `json:"in"`
+	Description string         `json:"description,omitempty"`
+	Required    bool           `json:"required,omitempty"`
+	Schema      map[string]any `json:"schema"`
+}
+
+// openAPIResponse describes a response by its content type.
+type openAPIResponse struct {
+	Description string                    `json:"description"`
+	Content     map[string]map[string]any `json:"content,omitempty"`
+}
+
+// newOpenAPIDocument describes the endpoints registered by NewServer.
+func newOpenAPIDocument() *openAPIDocument {
+	doc := &openAPIDocument{
+		OpenAPI: openAPIVersion,
+		Info: openAPIInfo{
+			Title:   "oilscraper",
+			Version: "dev",
+		},
+		Paths: make(map[string]map[string]*openAPIOperation),
+		Components: openAPIComponents{
+			Schemas: make(map[string]any),
+		},
+	}
+
+	providerParam := queryParam("provider", "Provider name", stringSchema())
+	statsProviderParam := queryParam("provider", "Provider name", stringSchema())
+	statsProviderParam.Required = true
+	productTypeParam := queryParam("product_type", "Restrict to this product type (default every product type, each reported separately)", stringSchema())
+	zipCodeParam := queryParam("zip_code", "Restrict to the prices of this zip code", stringSchema())
+	dateRange := func(from, to string) []openAPIParameter {
+		return []openAPIParameter{
+			queryParam("from", "First price date (YYYY-MM-DD, default "+from+")", dateSchema()),
+			queryParam("to", "Last price date (YYYY-MM-DD, default "+to+")", dateSchema()),
+		}
+	}
+
+	doc.add("/health", http.MethodGet, &openAPIOperation{
+		Summary:   "Liveness check",
+		Responses: map[string]*openAPIResponse{"200": jsonResponse("The service is up", statusSchema())},
+	})
+	doc.add("/readyz", http.MethodGet, &openAPIOperation{
+		Summary: "Readiness check",
+		Responses: map[string]*openAPIResponse{
+			"200": jsonResponse("The database is reachable", statusSchema()),
+			"503": doc.errorResponse("The database is unavailable"),
+		},
+	})
+	doc.add("/metrics", http.MethodGet, &openAPIOperation{
+		Summary:   "Prometheus metrics",
+		Responses: map[string]*openAPIResponse{"200": textResponse("Metrics in the Prometheus text format")},
+	})
+	doc.add("/status", http.MethodGet, &openAPIOperation{
+		Summary:   "Status of the scheduler, the providers, and the database",
+		Responses: map[string]*openAPIResponse{"200": jsonResponse("Service status", doc.schemaFor(models.StatusResponse{}))},
+	})
+	doc.add("/providers/health", http.MethodGet, &openAPIOperation{
+		Summary: "Health of every provider",
+		Parameters: []openAPIParameter{
+			queryParam("max_failures", "Consecutive failed scrapes after which a provider is unhealthy (default 3)", integerSchema(1)),
+			queryParam("max_data_age", "Age of the newest stored price after which a provider is unhealthy (Go duration, default 72h)", stringSchema()),
+			queryParam("strict", "Respond with 503 if a provider is unhealthy", map[string]any{"type": "boolean"}),
+		},
+		Responses: map[string]*openAPIResponse{
+			"200": jsonResponse("Provider health", doc.schemaFor(models.ProvidersHealthResponse{})),
+			"400": doc.errorResponse("Invalid parameter"),
+			"503": jsonResponse("A provider is unhealthy (strict only)", doc.schemaFor(models.ProvidersHealthResponse{})),
+		},
+	})
+	doc.add("/prices", http.MethodGet, &openAPIOperation{
+		Summary: "Stored price records of a date range, paginated via the Link header",
+		Parameters: append([]openAPIParameter{
+			providerParam,
+			queryParam("label", "Restrict to the prices with this label", stringSchema()),
+			queryParam("max_delivery_days", "Restrict to the prices deliverable within this many days (0 disables)", integerSchema(0)),
+			queryParam("limit", "Maximum number of records (default and maximum 5000)", integerSchema(1)),
+			queryParam("offset", "Number of records to skip", integerSchema(0)),
+		}, dateRange("30 days before to", "today")...),
+		Responses: map[string]*openAPIResponse{
+			"200": jsonResponse("Price records", arraySchema(doc.schemaFor(models.OilPrice{}))),
+			"400": doc.errorResponse("Invalid parameter"),
+			"500": doc.errorResponse("Database error"),
+		},
+	})
+	doc.add("/prices/latest", http.MethodGet, &openAPIOperation{
+		Summary: "Latest stored price of every series",
+		Responses: map[string]*openAPIResponse{
+			"200": jsonResponse("Price records", arraySchema(doc.schemaFor(models.OilPrice{}))),
+			"500": doc.errorResponse("Database error"),
+		},
+	})
+	doc.add("/stats", http.MethodGet, &openAPIOperation{
+		Summary: "Price percentiles of a date range and the percentile of the current price per product type",
+		Parameters: append([]openAPIParameter{
+			statsProviderParam,
+			productTypeParam,
+			zipCodeParam,
+			queryParam("region", "Restrict to the prices of this region (e.g. DE)", stringSchema()),
+		}, dateRange("365 days before to", "today")...),
+		Responses: map[string]*openAPIResponse{
+			"200": jsonResponse("Price statistics", doc.schemaFor(models.PriceStats{})),
+			"400": doc.errorResponse("Invalid parameter"),
+			"500": doc.errorResponse("Database error"),
+		},
+	})
+	doc.add("/stats/weekday", http.MethodGet, &openAPIOperation{
+		Summary:    "Average price per day of the week and product type",
+		Parameters: append([]openAPIParameter{statsProviderParam, productTypeParam, zipCodeParam}, dateRange("365 days before to", "today")...),
+		Responses: map[string]*openAPIResponse{
+			"200": jsonResponse("Weekday statistics", doc.schemaFor(models.WeekdayStats{})),
+			"400": doc.errorResponse("Invalid parameter"),
+			"500": doc.errorResponse("Database error"),
+		},
+	})
+	doc.add("/export/prometheus", http.MethodGet, &openAPIOperation{
+		Summary: "Stored prices in the Prometheus text format with timestamps",
+		Parameters: append([]openAPIParameter{
+			providerParam,
+			queryParam("source_url", "Add the source URL of every price as a label (a new series per request)", map[string]any{"type": "boolean"}),
+		}, dateRange("all", "today")...),
+		Responses: map[string]*openAPIResponse{
+			"200": textResponse("Prices as Prometheus samples"),
+			"400": doc.errorResponse("Invalid parameter"),
+			"500": doc.errorResponse("Database error"),
+		},
+	})
+	doc.add("/export/influx", http.MethodGet, &openAPIOperation{
+		Summary: "Stored prices in the InfluxDB line protocol",
+		Parameters: append([]openAPIParameter{
+			providerParam,
+			queryParam("measurement", "Measurement name", stringSchema()),
+			queryParam("tags", "Comma-separated tags to write (empty writes none)", stringSchema()),
+		}, dateRange("all", "today")...),
+		Responses: map[string]*openAPIResponse{
+			"200": textResponse("Prices as InfluxDB line protocol"),
+			"400": doc.errorResponse("Invalid parameter"),
+			"500": doc.errorResponse("Database error"),
+		},
+	})
+	doc.add("/openapi.json", http.MethodGet, &openAPIOperation{
+		Summary: "This OpenAPI document",
+		Responses: map[string]*openAPIResponse{
+			"200": jsonResponse("OpenAPI document", map[string]any{"type": "object"}),
+		},
+	})
+
+	return doc
+}
+
+// addBearerAuth adds the security scheme of the endpoints requiring the API token.
+func (d *openAPIDocument) addBearerAuth() {
+	d.Components.SecuritySchemes = map[string]any{
+		"bearerAuth": map[string]any{"type": "http", "scheme": "bearer"},
+	}
+}
+
+// addScrape describes the endpoint registered by Server.EnableScrape.
+func (d *openAPIDocument) addScrape() {
+	d.addBearerAuth()
+	d.add("/scrape", http.MethodPost, &openAPIOperation{
+		Summary:    "Trigger a scrape of all providers or of one provider in the background",
+		Parameters: []openAPIParameter{queryParam("provider", "Provider to scrape (default all)", stringSchema())},
+		Responses: map[string]*openAPIResponse{
+			"202": jsonResponse("The scrape was started", statusSchema()),
+			"401": d.errorResponse("Missing or invalid bearer token"),
+			"404": d.errorResponse("Unknown provider"),
+			"405": d.errorResponse("Method other than POST"),
+			"409": d.errorResponse("A triggered scrape of the provider is still running"),
+		},
+		Security: []map[string][]string{{"bearerAuth": {}}},
+	})
+}
+
+// addRawResponses describes the endpoint registered by Server.EnableRawResponses.
+func (d *openAPIDocument) addRawResponses() {
+	d.addBearerAuth()
+	d.add("/providers/{name}/raw", http.MethodGet, &openAPIOperation{
+		Summary: "Last stored raw response of a provider",
+		Parameters: []openAPIParameter{{
+			Name:     "name",
+			In:       "path",
+			Required: true,
+			Schema:   stringSchema(),
+		}},
+		Responses: map[string]*openAPIResponse{
+			"200": jsonResponse("Raw response body as returned by the provider", map[string]any{}),
+			"401": d.errorResponse("Missing or invalid bearer token"),
+			"404": d.errorResponse("Unknown provider or no raw response stored"),
+			"500": d.errorResponse("Database error"),
+		},
+		Security: []map[string][]string{{"bearerAuth": {}}},
+	})
+}
+
+// addTenants moves the paths serving the data of a dataset below /t/{tenant}, as
+// registered by Server.AddTenant. Paths added later are moved as well.
+func (d *openAPIDocument) addTenants() {
+	paths := d.Paths
+	d.Paths = make(map[string]map[string]*openAPIOperation, len(paths))
+	d.tenants = true
+	for path, operations := range paths {
+		for method, op := range operations {
+			d.add(path, method, op)
+		}
+	}
+}
+
+// addUI describes the endpoint registered by Server.EnableUI.
+func (d *openAPIDocument) addUI() {
+	d.add("/", http.MethodGet, &openAPIOperation{
+		Summary: "Web UI charting the recent prices",
+		Responses: map[string]*openAPIResponse{
+			"200": {Description: "HTML page", Content: map[string]map[string]any{"text/html": {}}},
+		},
+	})
+}
+
+// add describes the method of a path.
+func (d *openAPIDocument) add(path, method string, op *openAPIOperation) {
+	if d.tenants && !sharedOpenAPIPaths[path] {
+		path = "/t/{tenant}" + path
+		op.Parameters = append([]openAPIParameter{{
+			Name:        "tenant",
+			In:          "path",
+			Description: "Tenant whose data is served",
+			Required:    true,
+			Schema:      stringSchema(),
+		}}, op.Parameters...)
+	}
+	if d.Paths[path] == nil {
+		d.Paths[path] = make(map[string]*openAPIOperation)
+	}
+	d.Paths[path][strings.ToLower(method)] = op
+}
+
+// errorResponse describes an error response (see writeError).
+func (d *openAPIDocument) errorResponse(description string) *openAPIResponse {
+	return jsonResponse(description, d.schemaFor(errorResponse{}))
+}
+
+// schemaFor returns the schema of the JSON encoding of v. Struct types are added to the
+// components and referenced, named after their Go type with an upper-case first letter.
+func (d *openAPIDocument) schemaFor(v any) map[string]any {
+	return d.schemaOf(reflect.TypeOf(v))
+}
+
+// schemaOf returns the schema of the JSON encoding of a value of type t.
+func (d *openAPIDocument) schemaOf(t reflect.Type) map[string]any {
+	if t == reflect.TypeFor[time.Time]() {
+		return map[string]any{"type": "string", "format": "date-time"}
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		return nullable(d.schemaOf(t.Elem()))
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return map[string]any{"type": "integer"}
+	case reflect.Int64, reflect.Uint64:
+		return map[string]any{"type": "integer", "format": "int64"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.String:
+		return stringSchema()
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return map[string]any{"type": "string", "contentEncoding": "base64"}
+		}
+		return arraySchema(d.schemaOf(t.Elem()))
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": d.schemaOf(t.Elem())}
+	case reflect.Struct:
+		return d.structRef(t)
+	default:
+		return map[string]any{}
+	}
+}
+
+// structRef adds the schema of struct type t to the components (once) and returns a reference to it.
+func (d *openAPIDocument) structRef(t reflect.Type) map[string]any {
+	first, size := utf8.DecodeRuneInString(t.Name())
+	name := string(unicode.ToUpper(first)) + t.Name()[size:]
+	ref := map[string]any{"$ref": "#/components/schemas/" + name}
+	if _, ok := d.Components.Schemas[name]; ok {
+		return ref
+	}
+
+	// Reserve the name first, so recursive types terminate
+	d.Components.Schemas[name] = nil
+	properties := make(map[string]any)
+	required := []string{}
+	for i := range t.NumField() {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if !field.IsExported() || tag == "-" {
+			continue
+		}
+		fieldName, options, _ := strings.Cut(tag, ",")
+		if fieldName == "" {
+			fieldName = field.Name
+		}
+		properties[fieldName] = d.schemaOf(field.Type)
+		if !strings.Contains(options, "omitempty") {
+			required = append(required, fieldName)
+		}
+	}
+
+	d.Components.Schemas[name] = map[string]any{
+		"type":       "object",
+		"properties": properties,
+		"required":   required,
+	}
+	return ref
+}
+
+// nullable allows null in addition to the values of schema.
+func nullable(schema map[string]any) map[string]any {
+	if typ, ok := schema["type"].(string); ok {
+		nullableSchema := make(map[string]any, len(schema))
+		for key, value := range schema {
+			nullableSchema[key] = value
+		}
+		nullableSchema["type"] = []string{typ, "null"}
+		return nullableSchema
+	}
+	return map[string]any{"anyOf": []any{schema, map[string]any{"type": "null"}}}
+}
+
+// queryParam describes an optional query parameter.
+func queryParam(name, description string, schema map[string]any) openAPIParameter {
+	return openAPIParameter{Name: name, In: "query", Description: description, Schema: schema}
+}
+
+// stringSchema returns the schema of a string.
+func stringSchema() map[string]any {
+	return map[string]any{"type": "string"}
+}
+
+// dateSchema returns the schema of a date in the form YYYY-MM-DD.
+func dateSchema() map[string]any {
+	return map[string]any{"type": "string", "format": "date"}
+}
+
+// integerSchema returns the schema of an integer of at least minimum.
+func integerSchema(minimum int) map[string]any {
+	return map[string]any{"type": "integer", "minimum": minimum}
+}
+
+// arraySchema returns the schema of an array of items.
+func arraySchema(items map[string]any) map[string]any {
+	return map[string]any{"type": "array", "items": items}
+}
+
+// statusSchema returns the schema of a {"status": "..."} response.
+func statusSchema() map[string]any {
+	return map[string]any{
+		"type":       "object",
+		"properties": map[string]any{"status": map[string]any{"type": "string"}},
+		"required":   []string{"status"},
+	}
+}
+
+// jsonResponse describes a JSON response.
+func jsonResponse(description string, schema map[string]any) *openAPIResponse {
+	return &openAPIResponse{
+		Description: description,
+		Content:     map[string]map[string]any{"application/json": {"schema": schema}},
+	}
+}
+
+// textResponse describes a plain text response.
+func textResponse(description string) *openAPIResponse {
+	return &openAPIResponse{
+		Description: description,
+		Content:     map[string]map[string]any{"text/plain": {"schema": stringSchema()}},
+	}
+}
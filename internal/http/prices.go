@@ -0,0 +1,131 @@
+package http
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/andygrunwald/oil-price-scraper/internal/database"
+	"github.com/andygrunwald/oil-price-scraper/internal/models"
+)
+
+// LatestPricesHandler handles the /prices/latest endpoint.
+type LatestPricesHandler struct {
+	db *database.DB
+}
+
+// NewLatestPricesHandler creates a new LatestPricesHandler.
+func NewLatestPricesHandler(db *database.DB) *LatestPricesHandler {
+	return &LatestPricesHandler{
+		db: db,
+	}
+}
+
+// ServeHTTP implements the http.Handler interface.
+func (h *LatestPricesHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	prices, err := h.db.GetLatestPrices(r.Context())
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to query latest prices")
+		return
+	}
+
+	if prices == nil {
+		prices = []models.OilPrice{}
+	}
+
+	writeJSON(w, http.StatusOK, prices)
+}
+
+// defaultPricesWindow is the window used by /prices if no from parameter is given.
+const defaultPricesWindow = 30 * 24 * time.Hour
+
+// maxPricesLimit is the maximum and default number of records returned by /prices per request.
+const maxPricesLimit = 5000
+
+// PricesHandler handles the /prices endpoint.
+// It returns the stored price records of a date range, including their provenance.
+type PricesHandler struct {
+	db *database.DB
+}
+
+// NewPricesHandler creates a new PricesHandler.
+func NewPricesHandler(db *database.DB) *PricesHandler {
+	return &PricesHandler{
+		db: db,
+	}
+}
+
+// ServeHTTP implements the http.Handler interface.
+func (h *PricesHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	provider := query.Get("provider")
+
+	to, err := parseDateParam(query.Get("to"), time.Now())
+	if err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid to parameter: %v", err))
+		return
+	}
+	from, err := parseDateParam(query.Get("from"), to.Add(-defaultPricesWindow))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid from parameter: %v", err))
+		return
+	}
+
+	limit, err := parseIntParam(query.Get("limit"), maxPricesLimit)
+	if err != nil || limit < 1 {
+		writeError(w, http.StatusBadRequest, "invalid limit parameter: must be a positive number")
+		return
+	}
+	limit = min(limit, maxPricesLimit)
+	offset, err := parseIntParam(query.Get("offset"), 0)
+	if err != nil || offset < 0 {
+		writeError(w, http.StatusBadRequest, "invalid offset parameter: must be a non-negative number")
+		return
+	}
+
+	maxDeliveryDays, err := parseIntParam(query.Get("max_delivery_days"), 0)
+	if err != nil || maxDeliveryDays < 0 {
+		writeError(w, http.StatusBadRequest, "invalid max_delivery_days parameter: must be a non-negative number")
+		return
+	}
+
+	// One extra record tells whether there is a next page
+	prices, err := h.db.GetPricesPage(r.Context(), provider, from, to, query.Get("label"), maxDeliveryDays, limit+1, offset)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to query prices")
+		return
+	}
+
+	if len(prices) > limit {
+		prices = prices[:limit]
+
+		next := *r.URL
+		// Below a tenant prefix, r.URL.Path is stripped; the link must point to the requested path
+		if requested, err := url.ParseRequestURI(r.RequestURI); err == nil {
+			next.Path, next.RawPath = requested.Path, requested.RawPath
+		}
+		nextQuery := next.Query()
+		nextQuery.Set("from", from.Format("2006-01-02"))
+		nextQuery.Set("to", to.Format("2006-01-02"))
+		nextQuery.Set("limit", strconv.Itoa(limit))
+		nextQuery.Set("offset", strconv.Itoa(offset+limit))
+		next.RawQuery = nextQuery.Encode()
+		w.Header().Set("Link", fmt.Sprintf("<%s>; rel=\"next\"", next.RequestURI()))
+	}
+
+	if prices == nil {
+		prices = []models.OilPrice{}
+	}
+
+	writeJSON(w, http.StatusOK, prices)
+}
+
+// parseIntParam parses an integer query parameter, returning def if the value is empty.
+func parseIntParam(value string, def int) (int, error) {
+	if value == "" {
+		return def, nil
+	}
+	return strconv.Atoi(value)
+}
@@ -0,0 +1,22 @@
+package http
+
+import (
+	"fmt"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/push"
+)
+
+// PushMetrics pushes all metrics of the default registry to a Prometheus Pushgateway.
+// This is used by short-lived commands that are never scraped. Metrics are grouped
+// by job and command, so runs of different commands do not replace each other.
+func PushMetrics(url, job, command string) error {
+	err := push.New(url, job).
+		Gatherer(prometheus.DefaultGatherer).
+		Grouping("command", command).
+		Push()
+	if err != nil {
+		return fmt.Errorf("pushing metrics to %s: %w", url, err)
+	}
+	return nil
+}
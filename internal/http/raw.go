@@ -0,0 +1,77 @@
+package http
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/andygrunwald/oil-price-scraper/internal/database"
+	"github.com/andygrunwald/oil-price-scraper/internal/scraper"
+)
+
+// RawResponseHandler handles the /providers/{name}/raw endpoint.
+// It returns the last stored raw response of a provider unchanged, for proxying
+// provider data downstream. Requests must authenticate with the API token.
+type RawResponseHandler struct {
+	scraper          *scraper.Scraper
+	db               *database.DB
+	token            string
+	storeRawResponse bool
+}
+
+// NewRawResponseHandler creates a new RawResponseHandler.
+func NewRawResponseHandler(s *scraper.Scraper, db *database.DB, token string, storeRawResponse bool) *RawResponseHandler {
+	return &RawResponseHandler{
+		scraper:          s,
+		db:               db,
+		token:            token,
+		storeRawResponse: storeRawResponse,
+	}
+}
+
+// ServeHTTP implements the http.Handler interface.
+func (h *RawResponseHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !authorized(w, r, h.token) {
+		return
+	}
+
+	name := r.PathValue("name")
+	if !h.scraper.HasProvider(name) {
+		writeError(w, http.StatusNotFound, "unknown provider")
+		return
+	}
+	if !h.storeRawResponse {
+		writeError(w, http.StatusNotFound, "raw response storage is disabled (see --store-raw-response)")
+		return
+	}
+
+	price, err := h.db.GetLatestRawResponse(r.Context(), name)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to query raw response")
+		return
+	}
+	if price == nil {
+		writeError(w, http.StatusNotFound, "no raw response stored")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Last-Modified", price.FetchedAt.UTC().Format(http.TimeFormat))
+	w.Header().Set("X-Fetched-At", price.FetchedAt.UTC().Format(time.RFC3339))
+	if _, err := w.Write(price.RawResponse); err != nil {
+		panic(err)
+	}
+}
+
+// authorized reports whether the request carries token as bearer token.
+// Otherwise, it responds with 401 Unauthorized.
+func authorized(w http.ResponseWriter, r *http.Request, token string) bool {
+	got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if ok && subtle.ConstantTimeCompare([]byte(got), []byte(token)) == 1 {
+		return true
+	}
+	w.Header().Set("WWW-Authenticate", `Bearer realm="oilscraper"`)
+	writeError(w, http.StatusUnauthorized, "unauthorized")
+	return false
+}
@@ -0,0 +1,38 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/andygrunwald/oil-price-scraper/internal/database"
+)
+
+// readyTimeout bounds the database check of the /readyz endpoint.
+const readyTimeout = 2 * time.Second
+
+// ReadyHandler handles the /readyz endpoint.
+// Unlike /health, it reports whether the service can currently reach the database.
+type ReadyHandler struct {
+	db *database.DB
+}
+
+// NewReadyHandler creates a new ReadyHandler.
+func NewReadyHandler(db *database.DB) *ReadyHandler {
+	return &ReadyHandler{
+		db: db,
+	}
+}
+
+// ServeHTTP implements the http.Handler interface.
+func (h *ReadyHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), readyTimeout)
+	defer cancel()
+
+	if err := h.db.Ready(ctx); err != nil {
+		writeError(w, http.StatusServiceUnavailable, "database unavailable")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
@@ -0,0 +1,36 @@
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+)
+
+// errorResponse is the body of all error responses.
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// writeJSON writes v as JSON with the given status code. The body is encoded
+// before anything is written, so an encoding failure results in a JSON error
+// response instead of a truncated body.
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(v); err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to encode response")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	// The client may be gone; there is nobody left to report the error to
+	_, _ = w.Write(buf.Bytes())
+}
+
+// writeError writes an error response in the form {"error": "..."} with the given status code.
+func writeError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(errorResponse{Error: message})
+}
@@ -0,0 +1,100 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"sync"
+
+	"github.com/rs/zerolog"
+
+	"github.com/andygrunwald/oil-price-scraper/internal/scraper"
+)
+
+// ScrapeHandler handles the /scrape endpoint to manually trigger a scrape.
+// Triggered scrapes reuse recently fetched prices (see scraper.SetCacheTTL)
+// to protect the provider APIs from repeated triggers. Requests must authenticate
+// with the API token, and only one triggered scrape of a provider runs at a time.
+type ScrapeHandler struct {
+	ctx     context.Context
+	scraper *scraper.Scraper
+	token   string
+	logger  zerolog.Logger
+
+	mu sync.Mutex
+	// running holds the providers with a triggered scrape in progress ("" for all providers)
+	running map[string]bool
+}
+
+// NewScrapeHandler creates a new ScrapeHandler. Triggered scrapes run with ctx,
+// which is cancelled when the server shuts down.
+func NewScrapeHandler(ctx context.Context, s *scraper.Scraper, token string, logger zerolog.Logger) *ScrapeHandler {
+	return &ScrapeHandler{
+		ctx:     ctx,
+		scraper: s,
+		token:   token,
+		logger:  logger,
+		running: make(map[string]bool),
+	}
+}
+
+// ServeHTTP implements the http.Handler interface.
+func (h *ScrapeHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	if !authorized(w, r, h.token) {
+		return
+	}
+
+	provider := r.URL.Query().Get("provider")
+	if provider != "" && !h.scraper.HasProvider(provider) {
+		writeError(w, http.StatusNotFound, "unknown provider")
+		return
+	}
+
+	if !h.start(provider) {
+		writeError(w, http.StatusConflict, "scrape already running")
+		return
+	}
+
+	// The scrape outlives the request, so it runs with the context of the server.
+	go func() {
+		defer h.finish(provider)
+		h.logger.Info().Str("provider", provider).Msg("manual scrape triggered")
+
+		var err error
+		if provider == "" {
+			err = h.scraper.ScrapeAllCached(h.ctx)
+		} else {
+			err = h.scraper.ScrapeProviderCached(h.ctx, provider)
+		}
+		if err != nil {
+			h.logger.Error().Err(err).Str("provider", provider).Msg("manual scrape failed")
+		}
+	}()
+
+	writeJSON(w, http.StatusAccepted, map[string]string{"status": "accepted"})
+}
+
+// start marks a triggered scrape of provider ("" for all providers) as running.
+// It reports false if a triggered scrape covering the same provider is still running.
+func (h *ScrapeHandler) start(provider string) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.running[""] || h.running[provider] || (provider == "" && len(h.running) > 0) {
+		return false
+	}
+	h.running[provider] = true
+	return true
+}
+
+// finish marks the triggered scrape of provider as done.
+func (h *ScrapeHandler) finish(provider string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	delete(h.running, provider)
+}
@@ -2,7 +2,14 @@ package http
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"net"
 	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus/promhttp"
@@ -13,28 +20,72 @@ import (
 	"github.com/andygrunwald/oil-price-scraper/internal/scraper"
 )
 
+// unixSocketMode is the file mode of the Unix domain socket (owner and group read/write).
+const unixSocketMode = 0o660
+
+// unixSocketDialTimeout bounds the check whether an existing Unix domain socket is still served.
+const unixSocketDialTimeout = time.Second
+
 // Server represents the HTTP server for metrics and status endpoints.
 type Server struct {
-	server  *http.Server
-	logger  zerolog.Logger
-	metrics *Metrics
+	server   *http.Server
+	mux      *http.ServeMux
+	listener net.Listener
+	// datasets are the served datasets: a single one at the root, or one per tenant
+	datasets []*dataset
+	logger   zerolog.Logger
+	metrics  *Metrics
+	openAPI  *openAPIDocument
+	// ctx is the context of background work started by requests (e.g., a triggered scrape),
+	// cancelled on shutdown
+	ctx    context.Context
+	cancel context.CancelFunc
 }
 
-// NewServer creates a new HTTP server.
+// dataset holds the routes of the endpoints serving the data of one scraper and database.
+type dataset struct {
+	mux     *http.ServeMux
+	scraper *scraper.Scraper
+	db      *database.DB
+}
+
+// NewServer creates a new HTTP server serving the data of a single scraper and database.
 func NewServer(addr string, s *scraper.Scraper, sched *scheduler.Scheduler, db *database.DB, logger zerolog.Logger) *Server {
+	server := newServer(addr, logger)
+	server.metrics = NewMetrics()
+	server.mux.Handle("/readyz", NewReadyHandler(db))
+	server.datasets = append(server.datasets, server.newDataset(server.mux, s, sched, db))
+	return server
+}
+
+// NewTenantServer creates a new HTTP server serving the data of several tenants, each
+// below /t/{tenant}/ (e.g., /t/home/prices), added with AddTenant. /metrics, /health,
+// and /openapi.json are served once for all tenants; /readyz checks every tenant's database.
+// The metrics of every tenant must be created with NewTenantMetrics.
+func NewTenantServer(addr string, logger zerolog.Logger) *Server {
+	server := newServer(addr, logger)
+	server.mux.HandleFunc("/readyz", server.serveReady)
+	server.openAPI.addTenants()
+	return server
+}
+
+// newServer creates a new HTTP server with the endpoints shared by all datasets.
+func newServer(addr string, logger zerolog.Logger) *Server {
 	mux := http.NewServeMux()
-	metrics := NewMetrics()
+	openAPI := newOpenAPIDocument()
 
-	// Register handlers
 	mux.Handle("/metrics", promhttp.Handler())
-	mux.Handle("/status", NewStatusHandler(s, sched, db))
+	mux.HandleFunc("/openapi.json", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, openAPI)
+	})
 	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
-		w.WriteHeader(http.StatusOK)
-		if _, err := w.Write([]byte("OK")); err != nil {
-			panic(err)
-		}
+		writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+	})
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		writeError(w, http.StatusNotFound, "not found")
 	})
 
+	ctx, cancel := context.WithCancel(context.Background())
 	return &Server{
 		server: &http.Server{
 			Addr:         addr,
@@ -43,15 +94,167 @@ func NewServer(addr string, s *scraper.Scraper, sched *scheduler.Scheduler, db *
 			WriteTimeout: 10 * time.Second,
 			IdleTimeout:  60 * time.Second,
 		},
+		mux:     mux,
 		logger:  logger.With().Str("component", "http").Logger(),
-		metrics: metrics,
+		openAPI: openAPI,
+		ctx:     ctx,
+		cancel:  cancel,
+	}
+}
+
+// AddTenant serves the data of a tenant below /t/{tenant}/.
+// It must be called before the server starts, and before EnableRawResponses, EnableScrape, and EnableUI.
+func (s *Server) AddTenant(tenant string, scr *scraper.Scraper, sched *scheduler.Scheduler, db *database.DB) {
+	mux := http.NewServeMux()
+	mux.Handle("/readyz", NewReadyHandler(db))
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		writeError(w, http.StatusNotFound, "not found")
+	})
+
+	prefix := "/t/" + tenant
+	s.mux.Handle(prefix+"/", http.StripPrefix(prefix, mux))
+	s.datasets = append(s.datasets, s.newDataset(mux, scr, sched, db))
+}
+
+// newDataset registers the endpoints serving the data of a scraper and database on mux.
+func (s *Server) newDataset(mux *http.ServeMux, scr *scraper.Scraper, sched *scheduler.Scheduler, db *database.DB) *dataset {
+	mux.Handle("/status", NewStatusHandler(scr, sched, db))
+	mux.Handle("/providers/health", NewProvidersHealthHandler(scr, db))
+	mux.Handle("/export/prometheus", NewPrometheusExportHandler(db))
+	mux.Handle("/export/influx", NewInfluxExportHandler(db))
+	mux.Handle("/prices", NewPricesHandler(db))
+	mux.Handle("/prices/latest", NewLatestPricesHandler(db))
+	mux.Handle("/stats", NewStatsHandler(db))
+	mux.Handle("/stats/weekday", NewWeekdayStatsHandler(db))
+	return &dataset{mux: mux, scraper: scr, db: db}
+}
+
+// serveReady reports whether the databases of all tenants are reachable.
+func (s *Server) serveReady(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), readyTimeout)
+	defer cancel()
+
+	for _, d := range s.datasets {
+		if err := d.db.Ready(ctx); err != nil {
+			writeError(w, http.StatusServiceUnavailable, "database unavailable")
+			return
+		}
+	}
+
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// SetVersion sets the API version reported in /openapi.json (e.g., the build version).
+// It must be called before the server starts.
+func (s *Server) SetVersion(version string) {
+	s.openAPI.Info.Version = version
+}
+
+// EnableRawResponses registers the /providers/{name}/raw endpoint returning the last
+// stored raw response of a provider. Requests must send the token as bearer token.
+// It must be called before the server starts.
+func (s *Server) EnableRawResponses(token string, storeRawResponse bool) {
+	for _, d := range s.datasets {
+		d.mux.Handle("GET /providers/{name}/raw", NewRawResponseHandler(d.scraper, d.db, token, storeRawResponse))
+	}
+	s.openAPI.addRawResponses()
+}
+
+// EnableScrape registers the /scrape endpoint triggering a scrape in the background.
+// Requests must send the token as bearer token. It must be called before the server starts.
+func (s *Server) EnableScrape(token string) {
+	for _, d := range s.datasets {
+		d.mux.Handle("/scrape", NewScrapeHandler(s.ctx, d.scraper, token, s.logger))
 	}
+	s.openAPI.addScrape()
 }
 
-// Start starts the HTTP server.
+// Listen binds the server address without serving yet, so problems like an
+// address already in use are detected before anything else starts.
+// Addresses in the form "unix:/path/to/sock" listen on a Unix domain socket,
+// all other addresses listen on TCP. A TCP port of 0 picks a random free port.
+func (s *Server) Listen() error {
+	socketPath, isUnix := unixSocketPath(s.server.Addr)
+	if !isUnix {
+		listener, err := net.Listen("tcp", s.server.Addr)
+		if err != nil {
+			return fmt.Errorf("listening on %s: %w", s.server.Addr, err)
+		}
+		s.listener = listener
+		s.logger.Info().Str("addr", listener.Addr().String()).Msg("HTTP server listening")
+		return nil
+	}
+
+	// Only a stale socket left over from a previous run may be replaced,
+	// so a mistyped path never deletes a regular file
+	if info, err := os.Lstat(socketPath); err == nil {
+		if info.Mode().Type() != os.ModeSocket {
+			return fmt.Errorf("%s exists and is not a unix socket", socketPath)
+		}
+		// A socket that accepts connections is served by another live instance
+		if conn, err := net.DialTimeout("unix", socketPath, unixSocketDialTimeout); err == nil {
+			_ = conn.Close()
+			return fmt.Errorf("listening on %s: %w", s.server.Addr, syscall.EADDRINUSE)
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("checking socket path: %w", err)
+	}
+
+	listener, err := listenUnix(socketPath)
+	if err != nil {
+		return err
+	}
+
+	s.listener = listener
+	s.logger.Info().Str("addr", s.server.Addr).Msg("HTTP server listening")
+	return nil
+}
+
+// listenUnix listens on a Unix domain socket at socketPath with unixSocketMode.
+// The socket is created in a private temporary directory next to socketPath,
+// restricted there, and then renamed into place (replacing a stale socket), so it
+// is never reachable with the looser permissions of the umask.
+func listenUnix(socketPath string) (net.Listener, error) {
+	tmpDir, err := os.MkdirTemp(filepath.Dir(socketPath), ".oilscraper-sock-")
+	if err != nil {
+		return nil, fmt.Errorf("creating socket directory: %w", err)
+	}
+	defer func() {
+		if err := os.RemoveAll(tmpDir); err != nil {
+			panic(err)
+		}
+	}()
+
+	tmpPath := filepath.Join(tmpDir, "http.sock")
+	listener, err := net.Listen("unix", tmpPath)
+	if err != nil {
+		return nil, fmt.Errorf("listening on unix socket: %w", err)
+	}
+	// The socket is moved away from tmpPath; Shutdown removes it at socketPath
+	listener.(*net.UnixListener).SetUnlinkOnClose(false)
+
+	if err := os.Chmod(tmpPath, unixSocketMode); err != nil {
+		_ = listener.Close()
+		return nil, fmt.Errorf("setting socket permissions: %w", err)
+	}
+	if err := os.Rename(tmpPath, socketPath); err != nil {
+		_ = listener.Close()
+		return nil, fmt.Errorf("moving socket into place: %w", err)
+	}
+	return listener, nil
+}
+
+// Start starts the HTTP server and blocks until it is shut down.
+// It binds the address first if Listen was not called.
 func (s *Server) Start() error {
-	s.logger.Info().Str("addr", s.server.Addr).Msg("starting HTTP server")
-	if err := s.server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+	if s.listener == nil {
+		if err := s.Listen(); err != nil {
+			return err
+		}
+	}
+
+	s.logger.Info().Str("addr", s.listener.Addr().String()).Msg("starting HTTP server")
+	if err := s.server.Serve(s.listener); err != nil && err != http.ErrServerClosed {
 		return err
 	}
 	return nil
@@ -60,10 +263,29 @@ func (s *Server) Start() error {
 // Shutdown gracefully shuts down the HTTP server.
 func (s *Server) Shutdown(ctx context.Context) error {
 	s.logger.Info().Msg("shutting down HTTP server")
-	return s.server.Shutdown(ctx)
+	s.cancel()
+	err := s.server.Shutdown(ctx)
+
+	if socketPath, isUnix := unixSocketPath(s.server.Addr); isUnix {
+		if rmErr := os.Remove(socketPath); rmErr != nil && !errors.Is(rmErr, os.ErrNotExist) {
+			s.logger.Warn().Err(rmErr).Str("socket", socketPath).Msg("failed to remove unix socket")
+		}
+	}
+
+	return err
+}
+
+// unixSocketPath returns the socket path if addr is in the form "unix:/path/to/sock".
+func unixSocketPath(addr string) (string, bool) {
+	path, ok := strings.CutPrefix(addr, "unix:")
+	if !ok || path == "" {
+		return "", false
+	}
+	return path, true
 }
 
-// Metrics returns the Prometheus metrics.
+// Metrics returns the Prometheus metrics, or nil for a server of several tenants
+// (see NewTenantMetrics).
 func (s *Server) Metrics() *Metrics {
 	return s.metrics
 }
@@ -0,0 +1,162 @@
+package http
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/andygrunwald/oil-price-scraper/internal/database"
+	"github.com/andygrunwald/oil-price-scraper/internal/models"
+)
+
+// defaultStatsWindow is the window used by /stats if no from parameter is given.
+const defaultStatsWindow = 365 * 24 * time.Hour
+
+// statsPercentiles are the percentiles reported by the /stats endpoint.
+var statsPercentiles = []struct {
+	name  string
+	value float64
+}{
+	{"p10", 0.10},
+	{"p25", 0.25},
+	{"p50", 0.50},
+	{"p75", 0.75},
+	{"p90", 0.90},
+}
+
+// StatsHandler handles the /stats endpoint.
+// It reports price percentiles over a window and where the current price falls within them,
+// per product type, as prices of different product types are not comparable.
+type StatsHandler struct {
+	db *database.DB
+}
+
+// NewStatsHandler creates a new StatsHandler.
+func NewStatsHandler(db *database.DB) *StatsHandler {
+	return &StatsHandler{
+		db: db,
+	}
+}
+
+// ServeHTTP implements the http.Handler interface.
+func (h *StatsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	params, ok := parseStatsParams(w, r)
+	if !ok {
+		return
+	}
+	params.region = r.URL.Query().Get("region")
+
+	productTypes, err := params.productTypes(r.Context(), h.db)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to query product types")
+		return
+	}
+
+	stats := models.PriceStats{
+		Provider:     params.provider,
+		ZipCode:      params.zipCode,
+		Region:       params.region,
+		From:         params.from.Format("2006-01-02"),
+		To:           params.to.Format("2006-01-02"),
+		ProductTypes: make([]models.ProductPriceStats, 0, len(productTypes)),
+	}
+	for _, productType := range productTypes {
+		productStats, err := h.productStats(r.Context(), params, productType)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		stats.ProductTypes = append(stats.ProductTypes, productStats)
+	}
+
+	writeJSON(w, http.StatusOK, stats)
+}
+
+// productStats computes the statistics of a product type. Errors are messages for the client.
+func (h *StatsHandler) productStats(ctx context.Context, params statsParams, productType string) (models.ProductPriceStats, error) {
+	stats := models.ProductPriceStats{
+		ProductType: productType,
+		Percentiles: make(map[string]float64, len(statsPercentiles)),
+	}
+
+	ps := make([]float64, len(statsPercentiles))
+	for i, p := range statsPercentiles {
+		ps[i] = p.value
+	}
+	values, err := h.db.GetPricePercentiles(ctx, params.provider, productType, params.from, params.to, ps, params.zipCode, params.region)
+	if err != nil {
+		return stats, fmt.Errorf("failed to query price percentiles")
+	}
+	for i, value := range values {
+		stats.Percentiles[statsPercentiles[i].name] = value
+	}
+
+	current, err := h.db.GetLatestProductPrice(ctx, params.provider, productType, params.zipCode, params.region)
+	if err != nil {
+		return stats, fmt.Errorf("failed to query current price")
+	}
+	if current == nil {
+		return stats, nil
+	}
+	stats.CurrentPrice = &current.PricePer100L
+	stats.CurrentPriceDate = &current.PriceDate
+
+	rank, err := h.db.GetPricePercentRank(ctx, params.provider, productType, params.from, params.to, current.PricePer100L, params.zipCode, params.region)
+	if err != nil {
+		return stats, fmt.Errorf("failed to query current percentile")
+	}
+	if rank != nil {
+		percentile := *rank * 100
+		stats.CurrentPercentile = &percentile
+	}
+	return stats, nil
+}
+
+// statsParams are the query parameters shared by the statistics endpoints.
+type statsParams struct {
+	provider string
+	// productType restricts the statistics to one product type; empty means every product type
+	productType string
+	zipCode     string
+	// region restricts the statistics to one region; empty means every region (only used by /stats)
+	region   string
+	from, to time.Time
+}
+
+// parseStatsParams parses the query parameters of a statistics endpoint.
+// On invalid parameters, it writes an error response and returns false.
+func parseStatsParams(w http.ResponseWriter, r *http.Request) (statsParams, bool) {
+	query := r.URL.Query()
+	params := statsParams{
+		provider:    query.Get("provider"),
+		productType: query.Get("product_type"),
+		zipCode:     query.Get("zip_code"),
+	}
+	if params.provider == "" {
+		writeError(w, http.StatusBadRequest, "provider parameter is required")
+		return params, false
+	}
+
+	var err error
+	params.to, err = parseDateParam(query.Get("to"), time.Now())
+	if err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid to parameter: %v", err))
+		return params, false
+	}
+	params.from, err = parseDateParam(query.Get("from"), params.to.Add(-defaultStatsWindow))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid from parameter: %v", err))
+		return params, false
+	}
+	return params, true
+}
+
+// productTypes returns the product types to compute statistics for: the requested
+// product type, or every product type with prices in the window.
+func (p statsParams) productTypes(ctx context.Context, db *database.DB) ([]string, error) {
+	if p.productType != "" {
+		return []string{p.productType}, nil
+	}
+	return db.GetProductTypes(ctx, p.provider, p.from, p.to, p.zipCode, p.region)
+}
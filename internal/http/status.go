@@ -2,7 +2,6 @@ package http
 
 import (
 	"context"
-	"encoding/json"
 	"net/http"
 	"time"
 
@@ -50,6 +49,19 @@ func (h *StatusHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	response.PrimaryProvider = h.scraper.PrimaryProvider()
+	if price := h.scraper.PrimaryPrice(); price != nil {
+		response.PrimaryPrice = &models.PrimaryPrice{
+			Provider:     price.Provider,
+			ProductType:  price.ProductType,
+			ZipCode:      price.ZipCode,
+			PricePer100L: price.PricePer100L,
+			Currency:     price.Currency,
+			PriceDate:    price.Date,
+			FetchedAt:    price.FetchedAt,
+		}
+	}
+
 	// Get provider statuses
 	for _, provider := range h.scraper.GetProviders() {
 		metrics := h.scraper.GetMetrics(provider.Name())
@@ -59,15 +71,34 @@ func (h *StatusHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 
 		snapshot := metrics.GetSnapshot()
 		providerStatus := models.ProviderStatus{
-			Enabled:            true,
-			LastScrapeAt:       snapshot.LastScrapeAt,
-			LastScrapeSuccess:  snapshot.LastScrapeSuccess,
-			LastResponseTimeMs: snapshot.LastResponseTime.Milliseconds(),
-			LastPrice:          snapshot.LastPrice,
-			LastError:          snapshot.LastError,
-			TotalRequests:      snapshot.TotalRequests,
-			TotalErrors:        snapshot.TotalErrors,
-			LastRawResponse:    snapshot.LastRawResponse,
+			Enabled:              true,
+			LastScrapeAt:         snapshot.LastScrapeAt,
+			LastScrapeSuccess:    snapshot.LastScrapeSuccess,
+			LastResponseTimeMs:   snapshot.LastResponseTime.Milliseconds(),
+			LastResponseBytes:    snapshot.LastResponseBytes,
+			LastPrice:            snapshot.LastPrice,
+			LastPriceProductType: snapshot.LastPriceProductType,
+			LastError:            snapshot.LastError,
+			TotalRequests:        snapshot.TotalRequests,
+			TotalErrors:          snapshot.TotalErrors,
+			TransportErrors:      snapshot.TransportErrors,
+			TimeoutErrors:        snapshot.TimeoutErrors,
+			StatusErrors:         snapshot.StatusErrors,
+			RateLimitErrors:      snapshot.RateLimitErrors,
+			ParseErrors:          snapshot.ParseErrors,
+			ConsecutiveFailures:  snapshot.ConsecutiveFailures,
+			LastRawResponse:      snapshot.LastRawResponse,
+			Metadata:             snapshot.Metadata,
+		}
+
+		providerStatus.LastDataDate, providerStatus.DataAgeSeconds = dataFreshness(ctx, h.db, provider.Name())
+		h.setCurrentPrice(ctx, provider.Name(), &providerStatus)
+
+		if h.scheduler != nil {
+			nextScrape := h.scheduler.NextScrapeAtFor(provider.Name())
+			if !nextScrape.IsZero() {
+				providerStatus.NextScrapeAt = &nextScrape
+			}
 		}
 
 		response.Providers[provider.Name()] = providerStatus
@@ -76,11 +107,7 @@ func (h *StatusHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	// Get database status
 	response.Database = h.getDatabaseStatus(ctx)
 
-	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(response); err != nil {
-		http.Error(w, "failed to encode response", http.StatusInternalServerError)
-		return
-	}
+	writeJSON(w, http.StatusOK, response)
 }
 
 func (h *StatusHandler) getDatabaseStatus(ctx context.Context) models.DatabaseStatus {
@@ -91,9 +118,10 @@ func (h *StatusHandler) getDatabaseStatus(ctx context.Context) models.DatabaseSt
 	if h.db == nil {
 		return status
 	}
+	status.Tenant = h.db.Tenant()
 
 	// Check database connection
-	if err := h.db.Ping(); err != nil {
+	if err := h.db.Ready(ctx); err != nil {
 		return status
 	}
 	status.Connected = true
@@ -106,3 +134,45 @@ func (h *StatusHandler) getDatabaseStatus(ctx context.Context) models.DatabaseSt
 
 	return status
 }
+
+// dataFreshness returns the date and age in seconds of the newest stored price of a provider,
+// or nil if there is none. This distinguishes stale upstream data from failed scrapes.
+func dataFreshness(ctx context.Context, db *database.DB, providerName string) (*time.Time, *int64) {
+	if db == nil {
+		return nil, nil
+	}
+
+	latest, err := db.GetLatestPrice(ctx, providerName)
+	if err != nil || latest == nil {
+		return nil, nil
+	}
+
+	dataAge := int64(time.Since(latest.PriceDate).Seconds())
+	return &latest.PriceDate, &dataAge
+}
+
+// setCurrentPrice sets the current prices of a provider per product type according to its
+// current price policy. National providers use the latest published price, local providers
+// only today's price.
+func (h *StatusHandler) setCurrentPrice(ctx context.Context, providerName string, status *models.ProviderStatus) {
+	policy := h.scraper.CurrentPricePolicy(providerName)
+	status.CurrentPricePolicy = string(policy)
+	status.CurrentPrices = []models.ProductPrice{}
+
+	if h.db == nil {
+		return
+	}
+
+	current, err := h.db.GetCurrentPrices(ctx, providerName, policy, time.Now().In(h.scraper.PriceLocation()))
+	if err != nil {
+		return
+	}
+
+	for _, price := range current {
+		status.CurrentPrices = append(status.CurrentPrices, models.ProductPrice{
+			ProductType:  price.ProductType,
+			PricePer100L: price.PricePer100L,
+			PriceDate:    price.PriceDate,
+		})
+	}
+}
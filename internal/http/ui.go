@@ -0,0 +1,22 @@
+package http
+
+import (
+	"embed"
+	"net/http"
+)
+
+// uiFiles contains the static web UI, a single page charting /prices.
+//
+//go:embed ui/index.html
+var uiFiles embed.FS
+
+// EnableUI registers the web UI at /, charting the recent prices of every provider.
+// It must be called before the server starts.
+func (s *Server) EnableUI() {
+	for _, d := range s.datasets {
+		d.mux.HandleFunc("GET /{$}", func(w http.ResponseWriter, r *http.Request) {
+			http.ServeFileFS(w, r, uiFiles, "ui/index.html")
+		})
+	}
+	s.openAPI.addUI()
+}
@@ -0,0 +1,68 @@
+package http
+
+import (
+	"net/http"
+
+	"github.com/andygrunwald/oil-price-scraper/internal/database"
+	"github.com/andygrunwald/oil-price-scraper/internal/models"
+)
+
+// WeekdayStatsHandler handles the /stats/weekday endpoint.
+// It reports the average price per day of the week and product type, e.g. to find the best day to order.
+type WeekdayStatsHandler struct {
+	db *database.DB
+}
+
+// NewWeekdayStatsHandler creates a new WeekdayStatsHandler.
+func NewWeekdayStatsHandler(db *database.DB) *WeekdayStatsHandler {
+	return &WeekdayStatsHandler{
+		db: db,
+	}
+}
+
+// ServeHTTP implements the http.Handler interface.
+func (h *WeekdayStatsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	params, ok := parseStatsParams(w, r)
+	if !ok {
+		return
+	}
+
+	productTypes, err := params.productTypes(r.Context(), h.db)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to query product types")
+		return
+	}
+
+	stats := models.WeekdayStats{
+		Provider:     params.provider,
+		ZipCode:      params.zipCode,
+		From:         params.from.Format("2006-01-02"),
+		To:           params.to.Format("2006-01-02"),
+		ProductTypes: make([]models.ProductWeekdayStats, 0, len(productTypes)),
+	}
+	for _, productType := range productTypes {
+		averages, err := h.db.GetAveragePriceByWeekday(r.Context(), params.provider, productType, params.from, params.to, params.zipCode)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "failed to query weekday averages")
+			return
+		}
+
+		productStats := models.ProductWeekdayStats{
+			ProductType: productType,
+			Weekdays:    make([]models.WeekdayAverage, 0, len(averages)),
+		}
+		cheapest := -1
+		for i, a := range averages {
+			productStats.Weekdays = append(productStats.Weekdays, a)
+			if cheapest < 0 || a.AveragePrice < averages[cheapest].AveragePrice {
+				cheapest = i
+			}
+		}
+		if cheapest >= 0 {
+			productStats.CheapestWeekday = &averages[cheapest].Name
+		}
+		stats.ProductTypes = append(stats.ProductTypes, productStats)
+	}
+
+	writeJSON(w, http.StatusOK, stats)
+}
@@ -18,51 +18,158 @@ const (
 // PriceResult is the unified return type for all providers.
 type PriceResult struct {
 	// Date is the date the price is valid for.
-	Date time.Time
+	Date time.Time `json:"date"`
+	// PublishedAt is when the provider published or last updated the price, if the provider reports it.
+	PublishedAt *time.Time `json:"published_at,omitempty"`
 	// PricePer100L is the price in EUR per 100 liters.
-	PricePer100L float64
+	PricePer100L float64 `json:"price_per_100l"`
 	// Currency is the currency code (EUR).
-	Currency string
+	Currency string `json:"currency"`
 	// Provider is the provider name (e.g., "heizoel24", "hoyer").
-	Provider string
+	Provider string `json:"provider"`
 	// ProductType is the product variant (e.g., "standard", "bestpreis", "eco", "express").
-	ProductType string
+	ProductType string `json:"product_type"`
 	// Scope indicates whether the price is local (zip code) or national.
-	Scope PriceScope
+	Scope PriceScope `json:"scope"`
 	// ZipCode is only set if Scope is local.
-	ZipCode string
+	ZipCode string `json:"zip_code,omitempty"`
+	// Region is the country/region the price is valid for (e.g., "DE").
+	Region string `json:"region"`
+	// SourceURL is the request URL the price was fetched from.
+	SourceURL string `json:"source_url,omitempty"`
+	// Label is a free-form note tagging the price (e.g., "source=manual-import-2024").
+	Label string `json:"label,omitempty"`
+	// DeliveryDays is the delivery time of the product in days, if the provider reports it.
+	DeliveryDays *int `json:"delivery_days,omitempty"`
+	// DeliveryTimeType is the kind of delivery time as reported by the provider (e.g., "workdays").
+	DeliveryTimeType string `json:"delivery_time_type,omitempty"`
+	// IsPremium tells whether the provider flags the product as premium, if it reports it.
+	IsPremium *bool `json:"is_premium,omitempty"`
+	// IsClimateNeutral tells whether the provider flags the product as climate-neutral, if it reports it.
+	IsClimateNeutral *bool `json:"is_climate_neutral,omitempty"`
+	// Metadata is what the provider reports about the prices of a response (e.g., their unit), if anything.
+	Metadata *ProviderMetadata `json:"-"`
 	// RawResponse is the original API response (JSON).
-	RawResponse []byte
+	RawResponse []byte `json:"-"`
+	// ResponseSnapshot holds the HTTP status and headers of the API response.
+	ResponseSnapshot *ResponseSnapshot `json:"-"`
 	// FetchedAt is when the data was fetched.
-	FetchedAt time.Time
+	FetchedAt time.Time `json:"fetched_at"`
+}
+
+// FailedInsert is a price whose insert failed, kept in the dead-letter table.
+type FailedInsert struct {
+	ID    int64
+	Price PriceResult
+	// Error is the error of the last failed insert
+	Error string
+	// Attempts is the number of failed retries
+	Attempts int
+	FailedAt time.Time
+}
+
+// ResponseSnapshot is a small snapshot of an HTTP response for debugging
+// (e.g., content negotiation or rate limiting issues).
+type ResponseSnapshot struct {
+	StatusCode int               `json:"status_code"`
+	Headers    map[string]string `json:"headers"`
 }
 
 // OilPrice represents a stored oil price record from the database.
 type OilPrice struct {
-	ID           uint64
-	Provider     string
-	ProductType  string
-	PriceDate    time.Time
-	PricePer100L float64
-	Currency     string
-	Scope        PriceScope
-	ZipCode      *string
-	RawResponse  []byte
-	FetchedAt    time.Time
-	CreatedAt    time.Time
+	ID                 uint64     `json:"id"`
+	Provider           string     `json:"provider"`
+	ProductType        string     `json:"product_type"`
+	PriceDate          time.Time  `json:"price_date"`
+	PricePer100L       float64    `json:"price_per_100l"`
+	Currency           string     `json:"currency"`
+	Scope              PriceScope `json:"scope"`
+	ZipCode            *string    `json:"zip_code"`
+	Region             string     `json:"region"`
+	SourceURL          *string    `json:"source_url"`
+	Label              *string    `json:"label"`
+	PublishedAt        *time.Time `json:"published_at"`
+	DeliveryDays       *int       `json:"delivery_days"`
+	DeliveryTimeType   *string    `json:"delivery_time_type"`
+	IsPremium          *bool      `json:"is_premium"`
+	IsClimateNeutral   *bool      `json:"is_climate_neutral"`
+	RawResponse        []byte     `json:"-"`
+	RawResponseHeaders []byte     `json:"-"`
+	FetchedAt          time.Time  `json:"fetched_at"`
+	CreatedAt          time.Time  `json:"created_at"`
+}
+
+// DuplicateGroup describes a set of price records sharing the same
+// provider, product type, date, zip code, and region.
+type DuplicateGroup struct {
+	Provider       string
+	ProductType    string
+	PriceDate      time.Time
+	ZipCode        *string
+	Region         string
+	Count          int
+	DistinctPrices int
+	MinPrice       float64
+	MaxPrice       float64
+}
+
+// ProviderCounters holds the lifetime request and error counters of a provider.
+type ProviderCounters struct {
+	TotalRequests   int64
+	TotalErrors     int64
+	TransportErrors int64
+	TimeoutErrors   int64
+	StatusErrors    int64
+	RateLimitErrors int64
+	ParseErrors     int64
+}
+
+// ProviderMetadata is what a provider reports about its prices, e.g., HeizOel24's ChartUnit.
+// It is used to confirm that prices are per 100 liters in EUR and to detect upstream changes.
+type ProviderMetadata struct {
+	Unit        string `json:"unit,omitempty"`
+	ProductName string `json:"product_name,omitempty"`
+	ChartName   string `json:"chart_name,omitempty"`
+	Currency    string `json:"currency,omitempty"`
+	// ChangedAt is when the provider first reported these values
+	ChangedAt time.Time `json:"changed_at"`
+}
+
+// SameValues returns true if both report the same values, regardless of when they changed.
+func (m ProviderMetadata) SameValues(other ProviderMetadata) bool {
+	return m.Unit == other.Unit &&
+		m.ProductName == other.ProductName &&
+		m.ChartName == other.ChartName &&
+		m.Currency == other.Currency
 }
 
 // ProviderStatus holds the operational status of a provider.
 type ProviderStatus struct {
-	Enabled            bool       `json:"enabled"`
-	LastScrapeAt       *time.Time `json:"last_scrape_at"`
-	LastScrapeSuccess  bool       `json:"last_scrape_success"`
-	LastResponseTimeMs int64      `json:"last_response_time_ms"`
-	LastPrice          *float64   `json:"last_price"`
-	LastError          *string    `json:"last_error"`
-	TotalRequests      int64      `json:"total_requests"`
-	TotalErrors        int64      `json:"total_errors"`
-	LastRawResponse    string     `json:"last_raw_response,omitempty"`
+	Enabled              bool       `json:"enabled"`
+	LastScrapeAt         *time.Time `json:"last_scrape_at"`
+	NextScrapeAt         *time.Time `json:"next_scrape_at,omitempty"`
+	LastScrapeSuccess    bool       `json:"last_scrape_success"`
+	LastResponseTimeMs   int64      `json:"last_response_time_ms"`
+	LastResponseBytes    int        `json:"last_response_bytes"`
+	LastPrice            *float64   `json:"last_price"`
+	LastPriceProductType string     `json:"last_price_product_type,omitempty"`
+	LastError            *string    `json:"last_error"`
+	TotalRequests        int64      `json:"total_requests"`
+	TotalErrors          int64      `json:"total_errors"`
+	TransportErrors      int64      `json:"transport_errors"`
+	TimeoutErrors        int64      `json:"timeout_errors"`
+	StatusErrors         int64      `json:"http_status_errors"`
+	RateLimitErrors      int64      `json:"rate_limit_errors"`
+	ParseErrors          int64      `json:"parse_errors"`
+	ConsecutiveFailures  int64      `json:"consecutive_failures"`
+	LastRawResponse      string     `json:"last_raw_response,omitempty"`
+	// CurrentPrices are the current prices per product type according to CurrentPricePolicy
+	CurrentPrices      []ProductPrice `json:"current_prices"`
+	CurrentPricePolicy string         `json:"current_price_policy"`
+	LastDataDate       *time.Time     `json:"last_data_date"`
+	DataAgeSeconds     *int64         `json:"data_age_seconds"`
+	// Metadata is the last metadata the provider reported about its prices, if it reports any
+	Metadata *ProviderMetadata `json:"metadata,omitempty"`
 }
 
 // StatusResponse is the response for the /status endpoint.
@@ -72,12 +179,131 @@ type StatusResponse struct {
 	SchedulerRunning      bool                      `json:"scheduler_running"`
 	NextScrapeAt          *time.Time                `json:"next_scrape_at,omitempty"`
 	LastScheduledScrapeAt *time.Time                `json:"last_scheduled_scrape_at,omitempty"`
+	PrimaryProvider       string                    `json:"primary_provider,omitempty"`
+	PrimaryPrice          *PrimaryPrice             `json:"primary_price"`
 	Providers             map[string]ProviderStatus `json:"providers"`
 	Database              DatabaseStatus            `json:"database"`
 }
 
+// PrimaryPrice is the headline price of the primary provider, the single price representing the service.
+type PrimaryPrice struct {
+	Provider     string    `json:"provider"`
+	ProductType  string    `json:"product_type"`
+	ZipCode      string    `json:"zip_code,omitempty"`
+	PricePer100L float64   `json:"price_per_100l"`
+	Currency     string    `json:"currency"`
+	PriceDate    time.Time `json:"price_date"`
+	FetchedAt    time.Time `json:"fetched_at"`
+}
+
+// ProviderHealth holds the health of a provider for the /providers/health endpoint.
+type ProviderHealth struct {
+	Enabled bool `json:"enabled"`
+	Healthy bool `json:"healthy"`
+	// Problems lists why the provider is unhealthy (empty if healthy).
+	Problems            []string   `json:"problems"`
+	LastScrapeAt        *time.Time `json:"last_scrape_at"`
+	LastSuccessAt       *time.Time `json:"last_success_at"`
+	ConsecutiveFailures int64      `json:"consecutive_failures"`
+	LastError           *string    `json:"last_error"`
+	LastDataDate        *time.Time `json:"last_data_date"`
+	DataAgeSeconds      *int64     `json:"data_age_seconds"`
+}
+
+// ProvidersHealthResponse is the response for the /providers/health endpoint.
+type ProvidersHealthResponse struct {
+	Healthy   bool                      `json:"healthy"`
+	Providers map[string]ProviderHealth `json:"providers"`
+}
+
+// ProductPrice is the price of a product type on a price date.
+type ProductPrice struct {
+	ProductType  string    `json:"product_type"`
+	PricePer100L float64   `json:"price_per_100l"`
+	PriceDate    time.Time `json:"price_date"`
+}
+
+// PriceStats is the response for the /stats endpoint.
+// Prices of different product types are not comparable, so statistics are per product type.
+type PriceStats struct {
+	Provider     string              `json:"provider"`
+	ZipCode      string              `json:"zip_code,omitempty"`
+	Region       string              `json:"region,omitempty"`
+	From         string              `json:"from"`
+	To           string              `json:"to"`
+	ProductTypes []ProductPriceStats `json:"product_types"`
+}
+
+// ProductPriceStats holds the price percentiles of a product type and where its current price falls within them.
+type ProductPriceStats struct {
+	ProductType       string             `json:"product_type"`
+	CurrentPrice      *float64           `json:"current_price"`
+	CurrentPriceDate  *time.Time         `json:"current_price_date"`
+	CurrentPercentile *float64           `json:"current_percentile"`
+	Percentiles       map[string]float64 `json:"percentiles"`
+}
+
+// WeekdayAverage holds the average price of a day of the week.
+type WeekdayAverage struct {
+	// Weekday is the day of the week (0 = Sunday, ..., 6 = Saturday).
+	Weekday      int     `json:"weekday"`
+	Name         string  `json:"name"`
+	AveragePrice float64 `json:"average_price"`
+	Count        int64   `json:"count"`
+}
+
+// WeekdayStats is the response for the /stats/weekday endpoint.
+// Prices of different product types are not comparable, so averages are per product type.
+type WeekdayStats struct {
+	Provider     string                `json:"provider"`
+	ZipCode      string                `json:"zip_code,omitempty"`
+	From         string                `json:"from"`
+	To           string                `json:"to"`
+	ProductTypes []ProductWeekdayStats `json:"product_types"`
+}
+
+// ProductWeekdayStats holds the average price of a product type per day of the week.
+type ProductWeekdayStats struct {
+	ProductType     string           `json:"product_type"`
+	CheapestWeekday *string          `json:"cheapest_weekday"`
+	Weekdays        []WeekdayAverage `json:"weekdays"`
+}
+
+// PriceAggregate holds the average and minimum of the prices of a series within a date range.
+type PriceAggregate struct {
+	Count   int64
+	Average float64
+	Min     float64
+	// MinDate is the most recent date the minimum price was seen.
+	MinDate time.Time
+}
+
 // DatabaseStatus holds the database connection status.
 type DatabaseStatus struct {
-	Connected         bool  `json:"connected"`
-	TotalPricesStored int64 `json:"total_prices_stored"`
+	Tenant            string `json:"tenant,omitempty"`
+	Connected         bool   `json:"connected"`
+	TotalPricesStored int64  `json:"total_prices_stored"`
+}
+
+// ProviderQuality summarizes the quality of the stored prices of a provider within a date range.
+type ProviderQuality struct {
+	Provider  string    `json:"provider"`
+	Prices    int64     `json:"prices"`
+	FirstDate time.Time `json:"first_date"`
+	LastDate  time.Time `json:"last_date"`
+	// DaysCovered is the number of distinct dates with at least one price.
+	DaysCovered int64 `json:"days_covered"`
+	// Gaps is the number of runs of missing dates between FirstDate and LastDate.
+	Gaps int64 `json:"gaps"`
+	// MissingDays is the total number of missing dates between FirstDate and LastDate.
+	MissingDays int64 `json:"missing_days"`
+	// Anomalies is the number of prices that changed by more than the anomaly threshold
+	// compared to the previous price of the same series.
+	Anomalies int64 `json:"anomalies"`
+	// LastFetchedAt is when the most recent price was fetched.
+	LastFetchedAt time.Time `json:"last_fetched_at"`
+	AveragePrice  float64   `json:"average_price"`
+	// Trend is the average change of the price per day (EUR per 100 liters), averaged over
+	// all series of the provider. It is nil if no series has prices on two dates.
+	Trend *float64 `json:"trend"`
 }
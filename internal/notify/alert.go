@@ -0,0 +1,158 @@
+package notify
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/andygrunwald/oil-price-scraper/internal/models"
+)
+
+// AlertPayload is the JSON body posted to the price alert webhook.
+type AlertPayload struct {
+	Event       string    `json:"event"`
+	Provider    string    `json:"provider"`
+	ProductType string    `json:"product_type"`
+	ZipCode     string    `json:"zip_code,omitempty"`
+	Region      string    `json:"region"`
+	Price       float64   `json:"price"`
+	Threshold   float64   `json:"threshold"`
+	PriceDate   time.Time `json:"price_date"`
+}
+
+// AlertConfig configures price alerts.
+type AlertConfig struct {
+	// Threshold is the price per 100 liters below which an alert is sent.
+	Threshold float64
+	// Hysteresis is the margin above Threshold the price must recover to before
+	// a new drop below Threshold alerts again. Zero means recovering to Threshold.
+	Hysteresis float64
+	// Cooldown re-alerts while the price stays below Threshold once this duration
+	// passed since the last alert. Zero only re-alerts after a recovery.
+	Cooldown time.Duration
+}
+
+// alertState is the alert state of a price series.
+type alertState struct {
+	alerted     bool
+	lastAlertAt time.Time
+}
+
+// alertKey identifies a price series.
+type alertKey struct {
+	provider    string
+	productType string
+	zipCode     string
+	region      string
+}
+
+// PriceAlert posts an alert to a webhook when a price drops below a threshold.
+// To keep alerts actionable, a series that alerted stays quiet until its price
+// recovers above the threshold (plus hysteresis) or the cooldown passed.
+// The state is kept in memory and reset on restart.
+type PriceAlert struct {
+	url    string
+	config AlertConfig
+	client *http.Client
+	logger zerolog.Logger
+
+	mu     sync.Mutex
+	states map[alertKey]*alertState
+}
+
+// NewPriceAlert creates a new PriceAlert posting to url.
+func NewPriceAlert(url string, config AlertConfig, logger zerolog.Logger) *PriceAlert {
+	return &PriceAlert{
+		url:    url,
+		config: config,
+		client: &http.Client{Timeout: webhookTimeout},
+		logger: logger.With().Str("component", "price-alert").Logger(),
+		states: make(map[alertKey]*alertState),
+	}
+}
+
+// Check evaluates the current prices of a provider and sends alerts for
+// series that dropped below the threshold.
+func (a *PriceAlert) Check(ctx context.Context, providerName string, prices []models.PriceResult) {
+	for _, price := range prices {
+		if !a.shouldAlert(price) {
+			continue
+		}
+
+		payload := AlertPayload{
+			Event:       "price_below_threshold",
+			Provider:    price.Provider,
+			ProductType: price.ProductType,
+			ZipCode:     price.ZipCode,
+			Region:      price.Region,
+			Price:       price.PricePer100L,
+			Threshold:   a.config.Threshold,
+			PriceDate:   price.Date,
+		}
+		if err := postJSON(ctx, a.client, a.url, payload); err != nil {
+			a.logger.Error().
+				Err(err).
+				Str("provider", providerName).
+				Str("product_type", price.ProductType).
+				Msg("failed to send price alert")
+			// Retry with the next scrape instead of staying quiet
+			a.resetState(price)
+			continue
+		}
+
+		a.logger.Info().
+			Str("provider", providerName).
+			Str("product_type", price.ProductType).
+			Float64("price", price.PricePer100L).
+			Float64("threshold", a.config.Threshold).
+			Msg("sent price alert")
+	}
+}
+
+// shouldAlert updates the alert state of the price's series and reports
+// whether an alert must be sent.
+func (a *PriceAlert) shouldAlert(price models.PriceResult) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	key := keyOf(price)
+	state, ok := a.states[key]
+	if !ok {
+		state = &alertState{}
+		a.states[key] = state
+	}
+
+	if price.PricePer100L >= a.config.Threshold {
+		// Recovered: the next drop alerts again
+		if price.PricePer100L >= a.config.Threshold+a.config.Hysteresis {
+			state.alerted = false
+		}
+		return false
+	}
+
+	now := time.Now()
+	if state.alerted && (a.config.Cooldown <= 0 || now.Sub(state.lastAlertAt) < a.config.Cooldown) {
+		return false
+	}
+
+	state.alerted = true
+	state.lastAlertAt = now
+	return true
+}
+
+// resetState marks the series of price as not alerted.
+func (a *PriceAlert) resetState(price models.PriceResult) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if state, ok := a.states[keyOf(price)]; ok {
+		state.alerted = false
+	}
+}
+
+// keyOf returns the series key of a price.
+func keyOf(price models.PriceResult) alertKey {
+	return alertKey{price.Provider, price.ProductType, price.ZipCode, price.Region}
+}
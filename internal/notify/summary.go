@@ -0,0 +1,82 @@
+package notify
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/andygrunwald/oil-price-scraper/internal/scraper"
+)
+
+// SummaryPayload is the JSON body posted to the summary webhook.
+type SummaryPayload struct {
+	Event    string         `json:"event"`
+	Provider string         `json:"provider"`
+	Prices   []SummaryPrice `json:"prices"`
+}
+
+// SummaryPrice is a current price of the summary with its trend.
+type SummaryPrice struct {
+	ProductType string    `json:"product_type"`
+	ZipCode     string    `json:"zip_code,omitempty"`
+	Region      string    `json:"region"`
+	Price       float64   `json:"price"`
+	PriceDate   time.Time `json:"price_date"`
+	// Trend is the direction, streak, and change to the previous stored price.
+	Trend scraper.Trend `json:"trend"`
+	// TrendText is a short human-readable trend (e.g., "↓ 3 days in a row").
+	TrendText string `json:"trend_text"`
+}
+
+// SummaryWebhook posts a summary of the current prices and their trends to a
+// webhook URL after every scrape of a provider (daily in the run command).
+type SummaryWebhook struct {
+	url    string
+	client *http.Client
+	logger zerolog.Logger
+}
+
+// NewSummaryWebhook creates a new SummaryWebhook posting to url.
+func NewSummaryWebhook(url string, logger zerolog.Logger) *SummaryWebhook {
+	return &SummaryWebhook{
+		url:    url,
+		client: &http.Client{Timeout: webhookTimeout},
+		logger: logger.With().Str("component", "summary-webhook").Logger(),
+	}
+}
+
+// Notify sends the summary to the webhook. Errors are logged, not returned,
+// so a broken webhook never fails a scrape.
+func (w *SummaryWebhook) Notify(ctx context.Context, summary scraper.Summary) {
+	payload := SummaryPayload{
+		Event:    "price_summary",
+		Provider: summary.Provider,
+		Prices:   make([]SummaryPrice, 0, len(summary.Prices)),
+	}
+	for _, p := range summary.Prices {
+		payload.Prices = append(payload.Prices, SummaryPrice{
+			ProductType: p.Price.ProductType,
+			ZipCode:     p.Price.ZipCode,
+			Region:      p.Price.Region,
+			Price:       p.Price.PricePer100L,
+			PriceDate:   p.Price.Date,
+			Trend:       p.Trend,
+			TrendText:   p.Trend.String(),
+		})
+	}
+
+	if err := postJSON(ctx, w.client, w.url, payload); err != nil {
+		w.logger.Error().
+			Err(err).
+			Str("provider", summary.Provider).
+			Msg("failed to send summary webhook")
+		return
+	}
+
+	w.logger.Debug().
+		Str("provider", summary.Provider).
+		Int("prices", len(payload.Prices)).
+		Msg("sent summary webhook")
+}
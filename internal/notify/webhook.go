@@ -0,0 +1,109 @@
+// Package notify sends notifications about scrape results to external services.
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/andygrunwald/oil-price-scraper/internal/scraper"
+)
+
+// webhookTimeout is the maximum duration of a webhook request.
+const webhookTimeout = 10 * time.Second
+
+// FailurePayload is the JSON body posted to the failure webhook.
+type FailurePayload struct {
+	Event               string    `json:"event"`
+	Provider            string    `json:"provider"`
+	Reason              string    `json:"reason"`
+	Error               string    `json:"error"`
+	StatusCode          int       `json:"status_code,omitempty"`
+	ConsecutiveFailures int64     `json:"consecutive_failures"`
+	FailedAt            time.Time `json:"failed_at"`
+}
+
+// FailureWebhook posts provider failures to a webhook URL.
+type FailureWebhook struct {
+	url    string
+	client *http.Client
+	logger zerolog.Logger
+}
+
+// NewFailureWebhook creates a new FailureWebhook posting to url.
+func NewFailureWebhook(url string, logger zerolog.Logger) *FailureWebhook {
+	return &FailureWebhook{
+		url:    url,
+		client: &http.Client{Timeout: webhookTimeout},
+		logger: logger.With().Str("component", "failure-webhook").Logger(),
+	}
+}
+
+// Notify sends the failure to the webhook. Errors are logged, not returned,
+// so a broken webhook never fails a scrape.
+func (w *FailureWebhook) Notify(ctx context.Context, failure scraper.Failure) {
+	if err := w.send(ctx, failure); err != nil {
+		w.logger.Error().
+			Err(err).
+			Str("provider", failure.Provider).
+			Msg("failed to send failure webhook")
+		return
+	}
+
+	w.logger.Debug().
+		Str("provider", failure.Provider).
+		Int64("consecutiveFailures", failure.ConsecutiveFailures).
+		Msg("sent failure webhook")
+}
+
+func (w *FailureWebhook) send(ctx context.Context, failure scraper.Failure) error {
+	payload := FailurePayload{
+		Event:               "provider_failure",
+		Provider:            failure.Provider,
+		Reason:              failure.Reason,
+		Error:               failure.Err.Error(),
+		StatusCode:          failure.StatusCode,
+		ConsecutiveFailures: failure.ConsecutiveFailures,
+		FailedAt:            failure.FailedAt,
+	}
+
+	return postJSON(ctx, w.client, w.url, payload)
+}
+
+// postJSON posts payload as JSON to url and expects a 2xx response.
+func postJSON(ctx context.Context, client *http.Client, url string, payload any) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("encoding payload: %w", err)
+	}
+
+	// The scrape context may already be canceled, but the notification should still be delivered
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), webhookTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("creating request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("executing request: %w", err)
+	}
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			panic(err)
+		}
+	}()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -3,63 +3,198 @@ package scheduler
 
 import (
 	"context"
+	"fmt"
+	"math/rand/v2"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/rs/zerolog"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 
+	"github.com/andygrunwald/oil-price-scraper/internal/api"
 	"github.com/andygrunwald/oil-price-scraper/internal/scraper"
 )
 
+// tracer creates a span per scrape cycle, the parent of the spans of its providers.
+var tracer = otel.Tracer("github.com/andygrunwald/oil-price-scraper/internal/scheduler")
+
+// schedule is the daily scrape schedule of a group of providers sharing the same scrape hour.
+type schedule struct {
+	hour         int
+	providers    []string
+	nextScrapeAt time.Time
+}
+
+// RetryConfig configures retries of failed providers within a scheduled scrape.
+type RetryConfig struct {
+	// Attempts is the number of retries after a failed scrape. Zero disables retries.
+	Attempts int
+	// ProviderAttempts optionally overrides Attempts per provider.
+	ProviderAttempts map[string]int
+	// Delay is the delay before the first retry. It doubles with every further retry.
+	Delay time.Duration
+	// CycleAttempts is the number of retries of a whole cycle in which every provider
+	// failed (e.g., because the network was down), after the retries of the providers.
+	// Zero disables cycle retries.
+	CycleAttempts int
+	// CycleDelay is the delay before every retry of a whole cycle.
+	CycleDelay time.Duration
+}
+
+// attemptsFor returns the number of retries for a provider.
+func (c RetryConfig) attemptsFor(providerName string) int {
+	if attempts, ok := c.ProviderAttempts[providerName]; ok {
+		return attempts
+	}
+	return c.Attempts
+}
+
+// Metrics defines the interface for recording scheduler metrics.
+type Metrics interface {
+	RecordScrapeCycle(duration float64)
+	RecordScrapeCycleRetry()
+}
+
+// Trimmer deletes stored prices outside of the retention window.
+type Trimmer interface {
+	TrimPricesBefore(ctx context.Context, cutoff time.Time) (int64, error)
+}
+
 // Scheduler manages the daily scraping schedule.
 type Scheduler struct {
-	scraper    *scraper.Scraper
-	scrapeHour int
-	logger     zerolog.Logger
+	scraper       *scraper.Scraper
+	scrapeHour    int
+	providerHours map[string]int
+	retry         RetryConfig
+	metrics       Metrics
+	trimmer       Trimmer
+	retentionDays int
+	jitter        time.Duration
+	initialScrape bool
+	logger        zerolog.Logger
 
 	mu           sync.RWMutex
+	schedules    []*schedule
 	nextScrapeAt time.Time
 	lastScrapeAt *time.Time
 	running      bool
+	// done is closed when Start returns
+	done chan struct{}
+
+	stop     chan struct{}
+	stopOnce sync.Once
+	// reschedule signals a running scheduler that the scrape hours changed
+	reschedule chan struct{}
 }
 
 // New creates a new Scheduler.
-func New(s *scraper.Scraper, scrapeHour int, logger zerolog.Logger) *Scheduler {
+// providerHours optionally overrides the scrape hour per provider.
+// Providers without an entry are scraped at the global scrapeHour.
+func New(s *scraper.Scraper, scrapeHour int, providerHours map[string]int, logger zerolog.Logger) *Scheduler {
 	return &Scheduler{
-		scraper:    s,
-		scrapeHour: scrapeHour,
-		logger:     logger.With().Str("component", "scheduler").Logger(),
+		scraper:       s,
+		scrapeHour:    scrapeHour,
+		providerHours: providerHours,
+		initialScrape: true,
+		logger:        logger.With().Str("component", "scheduler").Logger(),
+		stop:          make(chan struct{}),
+		reschedule:    make(chan struct{}, 1),
+	}
+}
+
+// SetRetry configures retries of failed providers within a scheduled scrape,
+// instead of waiting for the next day.
+func (s *Scheduler) SetRetry(retry RetryConfig) {
+	s.retry = retry
+}
+
+// SetMetrics sets the recorder of scheduler metrics.
+func (s *Scheduler) SetMetrics(m Metrics) {
+	s.metrics = m
+}
+
+// SetRetention enables trimming stored prices older than retentionDays days
+// after every scrape cycle. Zero days disables trimming.
+func (s *Scheduler) SetRetention(trimmer Trimmer, retentionDays int) {
+	s.trimmer = trimmer
+	s.retentionDays = retentionDays
+}
+
+// SetJitter delays every scheduled scrape by a random offset within the window
+// after the scrape hour (e.g., 30m scrapes between 06:00 and 06:30), so scrapes of
+// different installations don't hit the providers at the same time. Zero scrapes
+// exactly at the scrape hour.
+func (s *Scheduler) SetJitter(window time.Duration) {
+	s.jitter = window
+}
+
+// SetInitialScrape enables the initial scrape on Start of the providers that were
+// not scraped today yet (enabled by default). Disabled, the scheduler waits for the
+// first scheduled scrape, e.g., to avoid API requests on every restart during development.
+func (s *Scheduler) SetInitialScrape(enabled bool) {
+	s.initialScrape = enabled
+}
+
+// Reschedule changes the scrape hours and the jitter window (see New and SetJitter).
+// A running scheduler calculates the next scrape times right away. A running scrape
+// cycle is not interrupted; the new times apply after it finished.
+func (s *Scheduler) Reschedule(scrapeHour int, providerHours map[string]int, jitter time.Duration) {
+	s.mu.Lock()
+	s.scrapeHour = scrapeHour
+	s.providerHours = providerHours
+	s.jitter = jitter
+	s.mu.Unlock()
+
+	select {
+	case s.reschedule <- struct{}{}:
+	default:
 	}
 }
 
-// Start starts the scheduler and blocks until the context is cancelled.
+// Start starts the scheduler and blocks until the context is cancelled or Stop is called.
+// It returns nil after Stop and the context error after cancellation.
 func (s *Scheduler) Start(ctx context.Context) error {
+	done := make(chan struct{})
 	s.mu.Lock()
 	s.running = true
+	s.schedules = s.buildSchedules()
+	s.done = done
+	scrapeHour := s.scrapeHour
 	s.mu.Unlock()
 
 	defer func() {
 		s.mu.Lock()
 		s.running = false
 		s.mu.Unlock()
+		close(done)
 	}()
 
+	if s.stopped() {
+		return nil
+	}
+
 	// Run initial scrape if needed
-	s.logger.Info().Int("scrapeHour", s.scrapeHour).Msg("starting scheduler")
+	s.logger.Info().Int("scrapeHour", scrapeHour).Msg("starting scheduler")
 
 	// Check if we should scrape immediately (if we haven't scraped today yet)
-	s.runIfNeeded(ctx)
+	if s.initialScrape {
+		s.runIfNeeded(ctx)
+	} else {
+		s.logger.Info().Msg("initial scrape disabled, waiting for the first scheduled scrape")
+	}
 
-	// Calculate time until next scrape
-	nextScrape := s.calculateNextScrapeTime()
+	// Calculate time until next scrape of every schedule
 	s.mu.Lock()
-	s.nextScrapeAt = nextScrape
+	for _, sch := range s.schedules {
+		sch.nextScrapeAt = s.calculateNextScrapeTime(sch.hour)
+	}
 	s.mu.Unlock()
 
-	s.logger.Info().
-		Time("nextScrape", nextScrape).
-		Dur("duration", time.Until(nextScrape)).
-		Msg("next scrape scheduled")
+	nextScrape := s.updateNextScrapeAt()
 
 	timer := time.NewTimer(time.Until(nextScrape))
 	defer timer.Stop()
@@ -69,30 +204,209 @@ func (s *Scheduler) Start(ctx context.Context) error {
 		case <-ctx.Done():
 			s.logger.Info().Msg("scheduler stopped")
 			return ctx.Err()
-		case <-timer.C:
-			s.runScrape(ctx)
-
-			// Calculate next scrape time (24 hours from now)
-			nextScrape = s.calculateNextScrapeTime()
+		case <-s.stop:
+			s.logger.Info().Msg("scheduler stopped")
+			return nil
+		case <-s.reschedule:
 			s.mu.Lock()
-			s.nextScrapeAt = nextScrape
+			s.schedules = s.buildSchedules()
+			for _, sch := range s.schedules {
+				sch.nextScrapeAt = s.calculateNextScrapeTime(sch.hour)
+			}
 			s.mu.Unlock()
+			s.logger.Info().Msg("schedule changed")
 
-			s.logger.Info().
-				Time("nextScrape", nextScrape).
-				Msg("next scrape scheduled")
+			nextScrape = s.updateNextScrapeAt()
+			timer.Reset(time.Until(nextScrape))
+		case <-timer.C:
+			now := time.Now()
+			for _, sch := range s.dueSchedules(now) {
+				if s.stopped() {
+					break
+				}
+				s.runCycle(ctx, sch)
 
+				// Calculate next scrape time of this schedule (24 hours from now)
+				s.mu.Lock()
+				sch.nextScrapeAt = s.calculateNextScrapeTime(sch.hour)
+				s.mu.Unlock()
+			}
+			s.trim(ctx)
+
+			nextScrape = s.updateNextScrapeAt()
 			timer.Reset(time.Until(nextScrape))
 		}
 	}
 }
 
-// calculateNextScrapeTime calculates the next scrape time based on the scrape hour.
-func (s *Scheduler) calculateNextScrapeTime() time.Time {
+// Stop stops the scheduler without cancelling the context of running scrapes.
+// A running scrape cycle finishes its current scrape, but no retries or further
+// cycles are started. Stop blocks until Start has returned and all timers are
+// released. It is safe to call Stop multiple times and before Start; a stopped
+// scheduler cannot be started again.
+func (s *Scheduler) Stop() {
+	s.stopOnce.Do(func() {
+		close(s.stop)
+	})
+
+	s.mu.RLock()
+	done := s.done
+	s.mu.RUnlock()
+	if done != nil {
+		<-done
+	}
+}
+
+// stopped returns whether Stop has been called.
+func (s *Scheduler) stopped() bool {
+	select {
+	case <-s.stop:
+		return true
+	default:
+		return false
+	}
+}
+
+// RunOnce runs a single scrape of all providers including retries, without
+// waiting for the scrape hours. It returns an error if providers still failed
+// after all retries.
+func (s *Scheduler) RunOnce(ctx context.Context) error {
+	s.mu.Lock()
+	s.running = true
+	s.schedules = s.buildSchedules()
+	schedules := s.schedules
+	s.mu.Unlock()
+
+	defer func() {
+		s.mu.Lock()
+		s.running = false
+		s.mu.Unlock()
+	}()
+
+	var failed []string
+	for _, sch := range schedules {
+		failed = append(failed, s.runCycle(ctx, sch)...)
+	}
+	s.trim(ctx)
+	if len(failed) > 0 {
+		return fmt.Errorf("scrape failed for providers: %s", strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+// retentionCutoff returns the first price date kept with the retention window.
+// Price dates are calendar days in loc (see api.PriceDate), so the window is
+// counted from today in loc, the same day prices are stored for.
+func retentionCutoff(now time.Time, loc *time.Location, retentionDays int) time.Time {
+	return api.PriceDate(now, loc).AddDate(0, 0, -retentionDays)
+}
+
+// trim deletes stored prices outside of the retention window, if enabled.
+func (s *Scheduler) trim(ctx context.Context) {
+	if s.trimmer == nil || s.retentionDays <= 0 {
+		return
+	}
+
+	cutoff := retentionCutoff(time.Now(), s.scraper.PriceLocation(), s.retentionDays)
+	trimmed, err := s.trimmer.TrimPricesBefore(ctx, cutoff)
+	if err != nil {
+		s.logger.Error().
+			Err(err).
+			Str("cutoff", cutoff.Format("2006-01-02")).
+			Msg("failed to trim prices outside of the retention window")
+		return
+	}
+
+	s.logger.Info().
+		Int("retentionDays", s.retentionDays).
+		Str("cutoff", cutoff.Format("2006-01-02")).
+		Int64("trimmed", trimmed).
+		Msg("trimmed prices outside of the retention window")
+}
+
+// buildSchedules groups the registered providers by their scrape hour.
+// The schedule for the global scrape hour always exists, even without providers.
+func (s *Scheduler) buildSchedules() []*schedule {
+	byHour := map[int]*schedule{
+		s.scrapeHour: {hour: s.scrapeHour},
+	}
+
+	for _, provider := range s.scraper.GetProviders() {
+		hour := s.scrapeHour
+		if h, ok := s.providerHours[provider.Name()]; ok {
+			hour = h
+		}
+
+		sch, ok := byHour[hour]
+		if !ok {
+			sch = &schedule{hour: hour}
+			byHour[hour] = sch
+		}
+		sch.providers = append(sch.providers, provider.Name())
+	}
+
+	schedules := make([]*schedule, 0, len(byHour))
+	for _, sch := range byHour {
+		sort.Strings(sch.providers)
+		schedules = append(schedules, sch)
+	}
+	sort.Slice(schedules, func(i, j int) bool {
+		return schedules[i].hour < schedules[j].hour
+	})
+
+	return schedules
+}
+
+// dueSchedules returns all schedules whose next scrape time has been reached.
+func (s *Scheduler) dueSchedules(now time.Time) []*schedule {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var due []*schedule
+	for _, sch := range s.schedules {
+		if !now.Before(sch.nextScrapeAt) {
+			due = append(due, sch)
+		}
+	}
+	return due
+}
+
+// updateNextScrapeAt sets and returns the earliest next scrape time across all schedules.
+func (s *Scheduler) updateNextScrapeAt() time.Time {
+	s.mu.Lock()
+	var next time.Time
+	for _, sch := range s.schedules {
+		if next.IsZero() || sch.nextScrapeAt.Before(next) {
+			next = sch.nextScrapeAt
+		}
+
+		s.logger.Info().
+			Int("scrapeHour", sch.hour).
+			Strs("providers", sch.providers).
+			Time("nextScrape", sch.nextScrapeAt).
+			Msg("next scrape scheduled")
+	}
+	s.nextScrapeAt = next
+	s.mu.Unlock()
+
+	s.logger.Info().
+		Time("nextScrape", next).
+		Dur("duration", time.Until(next)).
+		Msg("next scheduler wake-up")
+
+	return next
+}
+
+// calculateNextScrapeTime calculates the next scrape time based on the scrape hour
+// and a random offset within the jitter window.
+func (s *Scheduler) calculateNextScrapeTime(hour int) time.Time {
 	now := time.Now()
 
 	// Create a time for today at the scrape hour
-	nextScrape := time.Date(now.Year(), now.Month(), now.Day(), s.scrapeHour, 0, 0, 0, now.Location())
+	nextScrape := time.Date(now.Year(), now.Month(), now.Day(), hour, 0, 0, 0, now.Location())
+	if s.jitter > 0 {
+		nextScrape = nextScrape.Add(rand.N(s.jitter))
+	}
 
 	// If the scrape time has already passed today, schedule for tomorrow
 	if now.After(nextScrape) {
@@ -135,29 +449,154 @@ func (s *Scheduler) runIfNeeded(ctx context.Context) {
 	}
 }
 
-// runScrape runs the scraper for all providers.
-func (s *Scheduler) runScrape(ctx context.Context) {
-	s.logger.Info().Msg("running scheduled scrape")
+// runCycle runs the scrape of a schedule. If every provider failed, the whole
+// scrape is retried after the cycle delay, up to the configured cycle attempts.
+// It returns the providers that failed in the last attempt.
+func (s *Scheduler) runCycle(ctx context.Context, sch *schedule) []string {
+	failed := s.runScrape(ctx, sch)
+	for attempt := 1; attempt <= s.retry.CycleAttempts; attempt++ {
+		if len(sch.providers) == 0 || len(failed) < len(sch.providers) {
+			break
+		}
+
+		s.logger.Warn().
+			Int("scrapeHour", sch.hour).
+			Int("attempt", attempt).
+			Dur("delay", s.retry.CycleDelay).
+			Msg("all providers failed, retrying scrape cycle")
+		if s.metrics != nil {
+			s.metrics.RecordScrapeCycleRetry()
+		}
+
+		timer := time.NewTimer(s.retry.CycleDelay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return failed
+		case <-s.stop:
+			timer.Stop()
+			return failed
+		case <-timer.C:
+		}
+
+		failed = s.runScrape(ctx, sch)
+	}
+	return failed
+}
+
+// runScrape runs the scraper for all providers of a schedule.
+// It returns the providers that still failed after all retries.
+func (s *Scheduler) runScrape(ctx context.Context, sch *schedule) []string {
+	s.logger.Info().
+		Int("scrapeHour", sch.hour).
+		Strs("providers", sch.providers).
+		Msg("running scheduled scrape")
+
+	ctx, span := tracer.Start(ctx, "scrape_cycle", trace.WithAttributes(
+		attribute.Int("scrape_hour", sch.hour),
+		attribute.StringSlice("providers", sch.providers),
+	))
+	defer span.End()
 
 	now := time.Now()
 	s.mu.Lock()
 	s.lastScrapeAt = &now
 	s.mu.Unlock()
 
-	if err := s.scraper.ScrapeAll(ctx); err != nil {
-		s.logger.Error().Err(err).Msg("scheduled scrape failed")
-	} else {
-		s.logger.Info().Msg("scheduled scrape completed")
+	// The cycle duration includes retries and is recorded regardless of the outcome
+	defer func() {
+		duration := time.Since(now)
+		if s.metrics != nil {
+			s.metrics.RecordScrapeCycle(duration.Seconds())
+		}
+		s.logger.Info().Dur("duration", duration).Msg("scrape cycle finished")
+	}()
+
+	failed := s.scrapeProviders(ctx, sch.providers, 0)
+
+	// Retry failed providers with increasing delay
+	var gaveUp []string
+	delay := s.retry.Delay
+	for attempt := 1; len(failed) > 0; attempt++ {
+		var retry []string
+		for _, name := range failed {
+			if attempt <= s.retry.attemptsFor(name) {
+				retry = append(retry, name)
+			} else {
+				s.logger.Error().
+					Str("provider", name).
+					Int("attempts", attempt).
+					Msg("scheduled scrape failed, giving up until next cycle")
+				gaveUp = append(gaveUp, name)
+			}
+		}
+		if len(retry) == 0 {
+			break
+		}
+
+		s.logger.Warn().
+			Strs("providers", retry).
+			Int("attempt", attempt).
+			Dur("delay", delay).
+			Msg("retrying failed providers")
+
+		retryTimer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			retryTimer.Stop()
+			return append(gaveUp, retry...)
+		case <-s.stop:
+			retryTimer.Stop()
+			return append(gaveUp, retry...)
+		case <-retryTimer.C:
+		}
+
+		failed = s.scrapeProviders(ctx, retry, attempt)
+		delay *= 2
+	}
+
+	s.logger.Info().Msg("scheduled scrape completed")
+	return gaveUp
+}
+
+// scrapeProviders scrapes the providers one by one and returns the names of the failed ones.
+func (s *Scheduler) scrapeProviders(ctx context.Context, providers []string, attempt int) []string {
+	var failed []string
+	for _, name := range providers {
+		if err := s.scraper.ScrapeProvider(ctx, name); err != nil {
+			s.logger.Error().
+				Err(err).
+				Str("provider", name).
+				Int("attempt", attempt).
+				Msg("scheduled scrape of provider failed")
+			failed = append(failed, name)
+		}
 	}
+	return failed
 }
 
-// NextScrapeAt returns the time of the next scheduled scrape.
+// NextScrapeAt returns the time of the next scheduled scrape of any provider.
 func (s *Scheduler) NextScrapeAt() time.Time {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 	return s.nextScrapeAt
 }
 
+// NextScrapeAtFor returns the time of the next scheduled scrape of a provider.
+// It returns the zero time if the provider is not scheduled.
+func (s *Scheduler) NextScrapeAtFor(providerName string) time.Time {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, sch := range s.schedules {
+		for _, name := range sch.providers {
+			if name == providerName {
+				return sch.nextScrapeAt
+			}
+		}
+	}
+	return time.Time{}
+}
+
 // LastScrapeAt returns the time of the last scrape.
 func (s *Scheduler) LastScrapeAt() *time.Time {
 	s.mu.RLock()
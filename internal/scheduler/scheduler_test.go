@@ -0,0 +1,115 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"go.uber.org/goleak"
+
+	"github.com/andygrunwald/oil-price-scraper/internal/scraper"
+)
+
+// newTestScheduler creates a scheduler without providers, so no scrape touches a database.
+func newTestScheduler() *Scheduler {
+	s := scraper.New(nil, false, zerolog.Nop())
+	return New(s, 6, nil, zerolog.Nop())
+}
+
+func TestStopReleasesGoroutines(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	sched := newTestScheduler()
+	errc := make(chan error, 1)
+	go func() {
+		errc <- sched.Start(context.Background())
+	}()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !sched.IsRunning() {
+		if time.Now().After(deadline) {
+			t.Fatal("scheduler did not start")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	sched.Stop()
+	if sched.IsRunning() {
+		t.Error("scheduler still running after Stop returned")
+	}
+
+	select {
+	case err := <-errc:
+		if err != nil {
+			t.Errorf("Start returned %v after Stop, want nil", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Start did not return after Stop")
+	}
+
+	// Stopping again must neither block nor panic
+	sched.Stop()
+}
+
+func TestStopBeforeStart(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	sched := newTestScheduler()
+	sched.Stop()
+
+	if err := sched.Start(context.Background()); err != nil {
+		t.Errorf("Start of a stopped scheduler returned %v, want nil", err)
+	}
+	if sched.IsRunning() {
+		t.Error("stopped scheduler is running")
+	}
+}
+
+func TestStartReturnsContextError(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	sched := newTestScheduler()
+	ctx, cancel := context.WithCancel(context.Background())
+	errc := make(chan error, 1)
+	go func() {
+		errc <- sched.Start(ctx)
+	}()
+	cancel()
+
+	select {
+	case err := <-errc:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("Start returned %v, want %v", err, context.Canceled)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Start did not return after cancellation")
+	}
+}
+
+func TestRetentionCutoff(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Fatalf("loading location: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		now  time.Time
+		want string
+	}{
+		{"afternoon", time.Date(2026, 3, 10, 14, 0, 0, 0, berlin), "2026-03-03"},
+		{"before midnight", time.Date(2026, 3, 10, 23, 30, 0, 0, berlin), "2026-03-03"},
+		// Still the previous day in UTC
+		{"after midnight", time.Date(2026, 3, 11, 0, 30, 0, 0, berlin), "2026-03-04"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := retentionCutoff(tt.now.UTC(), berlin, 7)
+			if got.Format("2006-01-02") != tt.want {
+				t.Errorf("retentionCutoff(%s) = %s, want %s", tt.now, got.Format("2006-01-02"), tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,386 @@
+package scraper
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/andygrunwald/oil-price-scraper/internal/api"
+	"github.com/andygrunwald/oil-price-scraper/internal/models"
+)
+
+// BackfillOptions configures a backfill run.
+type BackfillOptions struct {
+	// MinDelay is the minimum delay between chunk requests in seconds.
+	MinDelay int
+	// MaxDelay is the maximum delay between chunk requests in seconds.
+	MaxDelay int
+	// ProgressEvery logs the progress every N chunks. Zero logs after every chunk.
+	ProgressEvery int
+	// OnProgress is called after every chunk (e.g., to render a progress bar).
+	OnProgress func(BackfillProgress)
+	// Concurrency is the number of chunks fetched at the same time.
+	// Requests still start at most once per delay. Zero or one fetches sequentially.
+	Concurrency int
+}
+
+// BackfillProgress describes the progress of a running backfill.
+type BackfillProgress struct {
+	Chunk       int
+	TotalChunks int
+	Inserted    int
+	Skipped     int
+	Elapsed     time.Duration
+	ETA         time.Duration
+}
+
+// BackfillResult summarizes a backfill run.
+type BackfillResult struct {
+	From     time.Time
+	To       time.Time
+	Chunks   int
+	Inserted int
+	Skipped  int
+	Failed   int
+	Duration time.Duration
+}
+
+// Percent returns the completed share of chunks in percent.
+func (p BackfillProgress) Percent() float64 {
+	if p.TotalChunks == 0 {
+		return 100
+	}
+	return float64(p.Chunk) / float64(p.TotalChunks) * 100
+}
+
+// dateRange is an inclusive range of days.
+type dateRange struct {
+	from time.Time
+	to   time.Time
+}
+
+// calendarDay returns the calendar day of t in its location as midnight UTC.
+// Providers report daily prices as UTC midnight timestamps, so ranges are
+// compared in UTC to avoid off-by-one-day gaps at the range edges.
+func calendarDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// monthlyChunks splits the range from..to into chunks of at most one calendar month.
+func monthlyChunks(from, to time.Time) []dateRange {
+	var chunks []dateRange
+	for start := from; !start.After(to); {
+		// Last day of the month of start
+		end := time.Date(start.Year(), start.Month()+1, 0, 0, 0, 0, 0, start.Location())
+		if end.After(to) {
+			end = to
+		}
+		chunks = append(chunks, dateRange{from: start, to: end})
+		start = time.Date(end.Year(), end.Month(), end.Day()+1, 0, 0, 0, 0, end.Location())
+	}
+	return chunks
+}
+
+// Backfill backfills historical data from a provider.
+// The range covers the calendar days of from and to in their locations (inclusive).
+// It is fetched in monthly chunks with a random delay between MinDelay and
+// MaxDelay seconds between chunks. The returned result covers the chunks
+// processed so far, also if an error occurred.
+func (s *Scraper) Backfill(ctx context.Context, providerName string, from, to time.Time, opts BackfillOptions) (BackfillResult, error) {
+	from, to = calendarDay(from), calendarDay(to)
+	result := BackfillResult{From: from, To: to}
+
+	s.mu.RLock()
+	provider, ok := s.providers[providerName]
+	s.mu.RUnlock()
+
+	if !ok {
+		s.logger.Warn().Str("provider", providerName).Msg("provider not found")
+		return result, nil
+	}
+
+	if !provider.Capabilities().Backfill {
+		s.logger.Warn().
+			Str("provider", providerName).
+			Msg("provider does not support backfill")
+		return result, nil
+	}
+
+	chunks := monthlyChunks(from, to)
+	result.Chunks = len(chunks)
+
+	s.logger.Info().
+		Str("provider", providerName).
+		Str("from", from.Format("2006-01-02")).
+		Str("to", to.Format("2006-01-02")).
+		Int("chunks", len(chunks)).
+		Int("concurrency", max(opts.Concurrency, 1)).
+		Msg("starting backfill")
+
+	start := time.Now()
+	done := 0
+	err := s.fetchChunks(ctx, provider, chunks, opts, func(chunk dateRange, prices []models.PriceResult) {
+		s.logger.Debug().
+			Str("provider", providerName).
+			Str("from", chunk.from.Format("2006-01-02")).
+			Str("to", chunk.to.Format("2006-01-02")).
+			Int("count", len(prices)).
+			Msg("fetched historical prices")
+
+		// A chunk without data may be an upstream breakage, but also a gap in the history
+		if err := api.CheckResults(provider, prices); err != nil {
+			s.logger.Warn().
+				Err(err).
+				Str("provider", providerName).
+				Str("from", chunk.from.Format("2006-01-02")).
+				Str("to", chunk.to.Format("2006-01-02")).
+				Msg("suspicious backfill response")
+		}
+
+		prices = s.applyTransformers(prices)
+		chunkInserted, chunkSkipped, chunkFailed := s.storeBackfillPrices(ctx, prices)
+		result.Inserted += chunkInserted
+		result.Skipped += chunkSkipped
+		result.Failed += chunkFailed
+
+		// Estimate the remaining time based on the average duration per chunk
+		elapsed := time.Since(start)
+		done++
+		progress := BackfillProgress{
+			Chunk:       done,
+			TotalChunks: len(chunks),
+			Inserted:    result.Inserted,
+			Skipped:     result.Skipped,
+			Elapsed:     elapsed,
+			ETA:         elapsed / time.Duration(done) * time.Duration(len(chunks)-done),
+		}
+
+		if opts.ProgressEvery <= 0 || done%opts.ProgressEvery == 0 || done == len(chunks) {
+			s.logger.Info().
+				Str("provider", providerName).
+				Int("chunk", progress.Chunk).
+				Int("totalChunks", progress.TotalChunks).
+				Float64("percent", progress.Percent()).
+				Int("inserted", progress.Inserted).
+				Int("skipped", progress.Skipped).
+				Dur("elapsed", progress.Elapsed).
+				Dur("eta", progress.ETA).
+				Msg("backfill progress")
+		}
+
+		if opts.OnProgress != nil {
+			opts.OnProgress(progress)
+		}
+	})
+	if err != nil {
+		result.Duration = time.Since(start)
+		return result, err
+	}
+
+	result.Duration = time.Since(start)
+
+	s.logger.Info().
+		Str("provider", providerName).
+		Int("inserted", result.Inserted).
+		Int("skipped", result.Skipped).
+		Int("failed", result.Failed).
+		Dur("duration", result.Duration).
+		Msg("backfill completed")
+
+	return result, nil
+}
+
+// fetchedChunk is the outcome of fetching a chunk of a backfill.
+type fetchedChunk struct {
+	chunk  dateRange
+	prices []models.PriceResult
+	err    error
+	// reserved is the size reserved in the raw response budget.
+	reserved int64
+}
+
+// fetchChunks fetches the historical prices of all chunks and calls handle with
+// the prices of every chunk. handle is always called from the calling goroutine,
+// so prices are stored one chunk at a time while further chunks are fetched.
+// Requests start with a random delay between MinDelay and MaxDelay after the
+// previous request started, also with several concurrent fetches. The first
+// failed fetch stops the backfill.
+func (s *Scraper) fetchChunks(ctx context.Context, provider api.Provider, chunks []dateRange, opts BackfillOptions, handle func(dateRange, []models.PriceResult)) error {
+	maxSize := api.MetadataOf(provider).ResponseSizeLimit()
+	if opts.Concurrency <= 1 {
+		for i, chunk := range chunks {
+			if i > 0 {
+				if err := s.backfillDelay(ctx, opts.MinDelay, opts.MaxDelay); err != nil {
+					return err
+				}
+			}
+
+			reserved, err := s.rawBudget.reserve(ctx, maxSize)
+			if err != nil {
+				return fmt.Errorf("waiting for raw response memory: %w", err)
+			}
+			prices, err := s.fetchHistorical(ctx, provider, chunk.from, chunk.to)
+			if err != nil {
+				s.rawBudget.release(reserved)
+				return api.WrapTimeout(err)
+			}
+			if !s.storeRawResponse {
+				dropRawResponses(prices)
+			}
+			reserved = s.rawBudget.shrink(reserved, prices)
+			handle(chunk, prices)
+			s.rawBudget.release(reserved)
+		}
+		return nil
+	}
+
+	fetchCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	jobs := make(chan dateRange)
+	results := make(chan fetchedChunk)
+
+	var wg sync.WaitGroup
+	for range opts.Concurrency {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for chunk := range jobs {
+				// Requests wait for the raw response budget before they are sent
+				var result fetchedChunk
+				reserved, err := s.rawBudget.reserve(fetchCtx, maxSize)
+				if err != nil {
+					result = fetchedChunk{chunk: chunk, err: fmt.Errorf("waiting for raw response memory: %w", err)}
+				} else {
+					prices, err := s.fetchHistorical(fetchCtx, provider, chunk.from, chunk.to)
+					if !s.storeRawResponse {
+						dropRawResponses(prices)
+					}
+					reserved = s.rawBudget.shrink(reserved, prices)
+					result = fetchedChunk{chunk: chunk, prices: prices, err: api.WrapTimeout(err), reserved: reserved}
+				}
+				select {
+				case results <- result:
+				case <-fetchCtx.Done():
+					s.rawBudget.release(reserved)
+					return
+				}
+			}
+		}()
+	}
+
+	// Dispatch the chunks with the polite delay between request starts
+	go func() {
+		defer close(jobs)
+		for i, chunk := range chunks {
+			if i > 0 {
+				if err := s.backfillDelay(fetchCtx, opts.MinDelay, opts.MaxDelay); err != nil {
+					return
+				}
+			}
+			select {
+			case jobs <- chunk:
+			case <-fetchCtx.Done():
+				return
+			}
+		}
+	}()
+
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	for result := range results {
+		if result.err != nil {
+			s.rawBudget.release(result.reserved)
+			return result.err
+		}
+		handle(result.chunk, result.prices)
+		s.rawBudget.release(result.reserved)
+	}
+
+	// The dispatch stops early if the backfill was canceled
+	return ctx.Err()
+}
+
+// backfillDelay waits a random duration between minDelay and maxDelay seconds.
+func (s *Scraper) backfillDelay(ctx context.Context, minDelay, maxDelay int) error {
+	if maxDelay < minDelay {
+		maxDelay = minDelay
+	}
+	delay := time.Duration(minDelay) * time.Second
+	if maxDelay > minDelay {
+		delay += time.Duration(rand.Int63n(int64(maxDelay-minDelay) * int64(time.Second)))
+	}
+	if delay <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// storeBackfillPrices stores historical prices, skipping prices that already exist.
+// It returns the number of inserted, skipped, and failed prices.
+func (s *Scraper) storeBackfillPrices(ctx context.Context, prices []models.PriceResult) (int, int, int) {
+	inserted := 0
+	skipped := 0
+	failed := 0
+	s.applyLabel(prices)
+	s.writeSinks(ctx, prices)
+	for _, price := range prices {
+		// Check if already exists
+		exists, err := s.db.ExistsForDate(ctx, price.Provider, price.ProductType, price.Date, price.ZipCode, price.Region)
+		if err != nil {
+			s.logger.Error().
+				Err(err).
+				Str("provider", price.Provider).
+				Str("date", price.Date.Format("2006-01-02")).
+				Msg("failed to check existence")
+			failed++
+			if s.promMetrics != nil {
+				s.promMetrics.RecordDBOperation("select", "error")
+			}
+			continue
+		}
+		if s.promMetrics != nil {
+			s.promMetrics.RecordDBOperation("select", "success")
+		}
+
+		if exists {
+			skipped++
+			s.auditSkip(ctx, price)
+			continue
+		}
+
+		if err := s.db.InsertPrice(ctx, price, s.storeRawResponse, s.storeResponseHeaders); err != nil {
+			s.logger.Error().
+				Err(err).
+				Str("provider", price.Provider).
+				Str("date", price.Date.Format("2006-01-02")).
+				Msg("failed to insert price")
+			failed++
+			if s.promMetrics != nil {
+				s.promMetrics.RecordDBOperation("insert", "error")
+			}
+			s.saveFailedInsert(ctx, price, err)
+		} else {
+			inserted++
+			if s.promMetrics != nil {
+				s.promMetrics.RecordDBOperation("insert", "success")
+			}
+		}
+	}
+
+	return inserted, skipped, failed
+}
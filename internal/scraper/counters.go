@@ -0,0 +1,142 @@
+package scraper
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/andygrunwald/oil-price-scraper/internal/models"
+)
+
+// counterFlushTimeout bounds a single asynchronous write of provider counters.
+const counterFlushTimeout = 10 * time.Second
+
+// CounterStore persists the lifetime request and error counters of providers.
+// SaveProviderCounters adds the given increments to the stored counters, so
+// several processes (e.g., run and a manual scrape) can share the counters.
+type CounterStore interface {
+	GetProviderCounters(ctx context.Context) (map[string]models.ProviderCounters, error)
+	SaveProviderCounters(ctx context.Context, provider string, increments models.ProviderCounters) error
+}
+
+// counters returns the lifetime counters of the metrics.
+func (m *Metrics) counters() models.ProviderCounters {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return models.ProviderCounters{
+		TotalRequests:   m.TotalRequests,
+		TotalErrors:     m.TotalErrors,
+		TransportErrors: m.TransportErrors,
+		TimeoutErrors:   m.TimeoutErrors,
+		StatusErrors:    m.StatusErrors,
+		RateLimitErrors: m.RateLimitErrors,
+		ParseErrors:     m.ParseErrors,
+	}
+}
+
+// counterIncrements returns the increments of the counters c since persisted.
+func counterIncrements(c, persisted models.ProviderCounters) models.ProviderCounters {
+	return models.ProviderCounters{
+		TotalRequests:   c.TotalRequests - persisted.TotalRequests,
+		TotalErrors:     c.TotalErrors - persisted.TotalErrors,
+		TransportErrors: c.TransportErrors - persisted.TransportErrors,
+		TimeoutErrors:   c.TimeoutErrors - persisted.TimeoutErrors,
+		StatusErrors:    c.StatusErrors - persisted.StatusErrors,
+		RateLimitErrors: c.RateLimitErrors - persisted.RateLimitErrors,
+		ParseErrors:     c.ParseErrors - persisted.ParseErrors,
+	}
+}
+
+// SetCounterStore loads the stored lifetime counters into the metrics of all
+// registered providers and persists the increments after every scrape from then on.
+// Writes happen in the background; increments of failed writes are written with the next one.
+// It must be called after registering the providers.
+func (s *Scraper) SetCounterStore(ctx context.Context, store CounterStore) error {
+	stored, err := store.GetProviderCounters(ctx)
+	if err != nil {
+		return fmt.Errorf("loading provider counters: %w", err)
+	}
+
+	s.counterMu.Lock()
+	defer s.counterMu.Unlock()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.persistedCounters = make(map[string]models.ProviderCounters, len(s.providerMetrics))
+	for name, metrics := range s.providerMetrics {
+		c, ok := stored[name]
+		if !ok {
+			continue
+		}
+		s.persistedCounters[name] = c
+		metrics.mu.Lock()
+		metrics.TotalRequests += c.TotalRequests
+		metrics.TotalErrors += c.TotalErrors
+		metrics.TransportErrors += c.TransportErrors
+		metrics.TimeoutErrors += c.TimeoutErrors
+		metrics.StatusErrors += c.StatusErrors
+		metrics.RateLimitErrors += c.RateLimitErrors
+		metrics.ParseErrors += c.ParseErrors
+		metrics.mu.Unlock()
+	}
+	s.counterStore = store
+	return nil
+}
+
+// persistCounters writes the counter increments of a provider in the background.
+// A failed write is logged; the next scrape or FlushCounters writes the increments again.
+func (s *Scraper) persistCounters(providerName string, metrics *Metrics) {
+	if s.counterStore == nil || s.dryRun {
+		return
+	}
+
+	s.counterWG.Add(1)
+	go func() {
+		defer s.counterWG.Done()
+		ctx, cancel := context.WithTimeout(context.Background(), counterFlushTimeout)
+		defer cancel()
+		if err := s.saveCounters(ctx, providerName, metrics); err != nil {
+			s.logger.Warn().Err(err).Str("provider", providerName).Msg("failed to persist provider counters")
+		}
+	}()
+}
+
+// saveCounters adds the counter increments of a provider since the last successful
+// write to the store. Writes are serialized, so no increment is added twice.
+func (s *Scraper) saveCounters(ctx context.Context, providerName string, metrics *Metrics) error {
+	s.counterMu.Lock()
+	defer s.counterMu.Unlock()
+
+	counters := metrics.counters()
+	increments := counterIncrements(counters, s.persistedCounters[providerName])
+	if increments == (models.ProviderCounters{}) {
+		return nil
+	}
+	if err := s.counterStore.SaveProviderCounters(ctx, providerName, increments); err != nil {
+		return err
+	}
+	s.persistedCounters[providerName] = counters
+	return nil
+}
+
+// FlushCounters waits for pending background writes and writes the counter
+// increments of all providers. Commands call it before exiting.
+func (s *Scraper) FlushCounters(ctx context.Context) error {
+	if s.counterStore == nil || s.dryRun {
+		return nil
+	}
+	s.counterWG.Wait()
+
+	s.mu.RLock()
+	metrics := make(map[string]*Metrics, len(s.providerMetrics))
+	for name, m := range s.providerMetrics {
+		metrics[name] = m
+	}
+	s.mu.RUnlock()
+
+	for name, m := range metrics {
+		if err := s.saveCounters(ctx, name, m); err != nil {
+			return err
+		}
+	}
+	return nil
+}
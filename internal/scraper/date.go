@@ -0,0 +1,79 @@
+package scraper
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/andygrunwald/oil-price-scraper/internal/api"
+	"github.com/andygrunwald/oil-price-scraper/internal/models"
+)
+
+// ScrapeDate fetches and stores the prices of a provider for the calendar day of date,
+// e.g., to fill a single missing day. Unlike Backfill, it sends exactly one request for
+// that day and ignores prices of other days in the response. It returns
+// api.ErrBackfillNotSupported for providers that cannot fetch past dates.
+func (s *Scraper) ScrapeDate(ctx context.Context, providerName string, date time.Time) (BackfillResult, error) {
+	day := calendarDay(date)
+	result := BackfillResult{From: day, To: day, Chunks: 1}
+
+	s.mu.RLock()
+	provider, ok := s.providers[providerName]
+	s.mu.RUnlock()
+
+	if !ok {
+		return result, fmt.Errorf("provider %q is not registered", providerName)
+	}
+	if !provider.Capabilities().Backfill {
+		return result, fmt.Errorf("cannot fetch past dates: %w", api.ErrBackfillNotSupported)
+	}
+
+	s.logger.Info().
+		Str("provider", providerName).
+		Str("date", day.Format("2006-01-02")).
+		Msg("scraping single day")
+
+	start := time.Now()
+	fetched, err := s.fetchHistorical(ctx, provider, day, day)
+	if err != nil {
+		return result, fmt.Errorf("fetching prices of %s: %w", day.Format("2006-01-02"), api.WrapTimeout(err))
+	}
+
+	var prices []models.PriceResult
+	for _, price := range fetched {
+		if calendarDay(price.Date).Equal(day) {
+			prices = append(prices, price)
+		}
+	}
+	if len(prices) == 0 {
+		return result, fmt.Errorf("no price for %s in the response", day.Format("2006-01-02"))
+	}
+
+	prices = s.applyTransformers(prices)
+	if s.fetchedHandler != nil {
+		s.fetchedHandler(providerName, prices)
+	}
+
+	if s.dryRun {
+		s.logger.Info().
+			Str("provider", providerName).
+			Int("count", len(prices)).
+			Msg("dry run, not storing prices")
+		result.Duration = time.Since(start)
+		return result, nil
+	}
+
+	result.Inserted, result.Skipped, result.Failed = s.storeBackfillPrices(ctx, prices)
+	result.Duration = time.Since(start)
+
+	s.logger.Info().
+		Str("provider", providerName).
+		Str("date", day.Format("2006-01-02")).
+		Int("inserted", result.Inserted).
+		Int("skipped", result.Skipped).
+		Int("failed", result.Failed).
+		Dur("duration", result.Duration).
+		Msg("single day scrape completed")
+
+	return result, nil
+}
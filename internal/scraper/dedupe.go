@@ -0,0 +1,55 @@
+package scraper
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"github.com/andygrunwald/oil-price-scraper/internal/api"
+	"github.com/andygrunwald/oil-price-scraper/internal/models"
+)
+
+// fetchCurrent fetches the current prices of a provider. Concurrent fetches of the
+// same provider (e.g., a scheduled and a manually triggered scrape) share one request
+// and its result, see fetchShared.
+func (s *Scraper) fetchCurrent(ctx context.Context, provider api.Provider) ([]models.PriceResult, error) {
+	key := provider.Name() + "|current"
+	return s.fetchShared(ctx, key, provider.Name(), func(ctx context.Context) ([]models.PriceResult, error) {
+		return provider.FetchCurrentPrices(ctx)
+	})
+}
+
+// fetchHistorical fetches the prices of a provider between from and to. Concurrent
+// fetches of the same provider and range share one request and its result, see fetchShared.
+func (s *Scraper) fetchHistorical(ctx context.Context, provider api.Provider, from, to time.Time) ([]models.PriceResult, error) {
+	key := provider.Name() + "|historical|" + from.Format(time.RFC3339) + "|" + to.Format(time.RFC3339)
+	return s.fetchShared(ctx, key, provider.Name(), func(ctx context.Context) ([]models.PriceResult, error) {
+		return provider.FetchHistoricalPrices(ctx, from, to)
+	})
+}
+
+// fetchShared runs fetch once for all concurrent callers with the same key. The shared
+// fetch is not cancelled with the context of the caller that started it, so its
+// cancellation does not fail the others; it is bounded by the provider's request timeout.
+// Every caller stops waiting when its own context is done and gets its own copy of
+// the prices, as callers modify them (e.g., transformers).
+func (s *Scraper) fetchShared(ctx context.Context, key, providerName string, fetch func(ctx context.Context) ([]models.PriceResult, error)) ([]models.PriceResult, error) {
+	fetchCtx := context.WithoutCancel(ctx)
+	ch := s.fetches.DoChan(key, func() (any, error) {
+		return fetch(fetchCtx)
+	})
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case result := <-ch:
+		if result.Shared {
+			s.logger.Debug().
+				Str("provider", providerName).
+				Str("key", key).
+				Msg("shared a concurrent identical fetch")
+		}
+		prices, _ := result.Val.([]models.PriceResult)
+		return slices.Clone(prices), result.Err
+	}
+}
@@ -0,0 +1,90 @@
+package scraper
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/andygrunwald/oil-price-scraper/internal/models"
+)
+
+// metadataSaveTimeout bounds a single write of provider metadata.
+const metadataSaveTimeout = 10 * time.Second
+
+// MetadataStore persists the metadata providers report about their prices.
+type MetadataStore interface {
+	GetProviderMetadata(ctx context.Context) (map[string]models.ProviderMetadata, error)
+	SaveProviderMetadata(ctx context.Context, provider string, metadata models.ProviderMetadata) error
+}
+
+// SetMetadataStore loads the stored provider metadata into the metrics of all
+// registered providers and persists changed metadata from then on, so a change
+// is detected across restarts. It must be called after registering the providers.
+func (s *Scraper) SetMetadataStore(ctx context.Context, store MetadataStore) error {
+	stored, err := store.GetProviderMetadata(ctx)
+	if err != nil {
+		return fmt.Errorf("loading provider metadata: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for name, metrics := range s.providerMetrics {
+		m, ok := stored[name]
+		if !ok {
+			continue
+		}
+		metrics.mu.Lock()
+		metrics.Metadata = &m
+		metrics.mu.Unlock()
+	}
+	s.metadataStore = store
+	return nil
+}
+
+// checkMetadata compares the metadata reported with the prices to the last known
+// metadata of the provider. A change (e.g., of the unit) is logged as warning,
+// as it may mean the prices are no longer per 100 liters in EUR.
+func (s *Scraper) checkMetadata(ctx context.Context, providerName string, metrics *Metrics, prices []models.PriceResult) {
+	var reported *models.ProviderMetadata
+	for _, p := range prices {
+		if p.Metadata != nil {
+			reported = p.Metadata
+			break
+		}
+	}
+	if reported == nil {
+		return
+	}
+
+	metrics.mu.Lock()
+	previous := metrics.Metadata
+	if previous != nil && previous.SameValues(*reported) {
+		metrics.mu.Unlock()
+		return
+	}
+	current := *reported
+	current.ChangedAt = time.Now().UTC()
+	metrics.Metadata = &current
+	metrics.mu.Unlock()
+
+	if previous != nil {
+		s.logger.Warn().
+			Str("provider", providerName).
+			Str("previousUnit", previous.Unit).
+			Str("unit", current.Unit).
+			Str("previousCurrency", previous.Currency).
+			Str("currency", current.Currency).
+			Str("previousProductName", previous.ProductName).
+			Str("productName", current.ProductName).
+			Msg("provider changed the reported metadata of its prices, check that they are still per 100 liters in EUR")
+	}
+
+	if s.metadataStore == nil || s.dryRun {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), metadataSaveTimeout)
+	defer cancel()
+	if err := s.metadataStore.SaveProviderMetadata(ctx, providerName, current); err != nil {
+		s.logger.Warn().Err(err).Str("provider", providerName).Msg("failed to persist provider metadata")
+	}
+}
@@ -0,0 +1,88 @@
+package scraper
+
+import (
+	"context"
+
+	"golang.org/x/sync/semaphore"
+
+	"github.com/andygrunwald/oil-price-scraper/internal/models"
+)
+
+// rawBudget bounds the total size of raw responses held in memory at the same time,
+// from fetching a response until its prices are stored. A nil budget is unlimited.
+// Memory is reserved before a request with the provider's maximum response size
+// (see api.Metadata.MaxResponseSize) and shrunk to the actual size once the response
+// was read, so bodies never exceed the budget while they are fetched.
+type rawBudget struct {
+	sem   *semaphore.Weighted
+	limit int64
+}
+
+// newRawBudget creates a rawBudget of limit bytes. A limit of zero returns nil (unlimited).
+func newRawBudget(limit int64) *rawBudget {
+	if limit <= 0 {
+		return nil
+	}
+	return &rawBudget{sem: semaphore.NewWeighted(limit), limit: limit}
+}
+
+// reserve waits until maxSize bytes fit into the budget and returns the reserved size,
+// which must be passed to shrink or release. A maxSize larger than the budget reserves
+// the whole budget, so the response is fetched alone.
+func (b *rawBudget) reserve(ctx context.Context, maxSize int64) (int64, error) {
+	if b == nil {
+		return 0, nil
+	}
+	n := min(maxSize, b.limit)
+	if n <= 0 {
+		return 0, nil
+	}
+	if err := b.sem.Acquire(ctx, n); err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
+// shrink returns the part of a reservation not needed by the raw response of prices
+// to the budget and returns the remaining reserved size.
+func (b *rawBudget) shrink(reserved int64, prices []models.PriceResult) int64 {
+	if b == nil {
+		return 0
+	}
+	keep := min(rawResponseSize(prices), reserved)
+	b.release(reserved - keep)
+	return keep
+}
+
+// release returns n reserved bytes to the budget.
+func (b *rawBudget) release(n int64) {
+	if b == nil || n == 0 {
+		return
+	}
+	b.sem.Release(n)
+}
+
+// rawResponseSize returns the size of the raw response of prices.
+// All prices of a response share its raw body.
+func rawResponseSize(prices []models.PriceResult) int64 {
+	if len(prices) == 0 {
+		return 0
+	}
+	return int64(len(prices[0].RawResponse))
+}
+
+// dropRawResponses removes the raw response from prices, so the body can be
+// garbage collected while the prices are processed.
+func dropRawResponses(prices []models.PriceResult) {
+	for i := range prices {
+		prices[i].RawResponse = nil
+	}
+}
+
+// SetMaxRawResponseMemory bounds the total size of raw responses held in memory at the
+// same time, e.g. by concurrent backfill chunks or overlapping scrapes. Requests wait
+// until the provider's maximum response size fits before they are sent. Zero disables the limit.
+// Without raw response storage, bodies are released right after fetching regardless.
+func (s *Scraper) SetMaxRawResponseMemory(bytes int64) {
+	s.rawBudget = newRawBudget(bytes)
+}
@@ -0,0 +1,73 @@
+package scraper
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/andygrunwald/oil-price-scraper/internal/models"
+)
+
+// recordedResponse is the sidecar written next to a recorded raw response.
+type recordedResponse struct {
+	Provider   string            `json:"provider"`
+	SourceURL  string            `json:"source_url,omitempty"`
+	FetchedAt  time.Time         `json:"fetched_at"`
+	StatusCode int               `json:"status_code,omitempty"`
+	Headers    map[string]string `json:"headers,omitempty"`
+}
+
+// SetRecordDir enables writing the raw response of every scrape to dir, e.g. to
+// capture fixtures for --provider-fixture or the validate-response command.
+// An empty dir disables recording.
+func (s *Scraper) SetRecordDir(dir string) {
+	s.recordDir = dir
+}
+
+// recordResponse writes the raw response of prices to a timestamped file in the record
+// directory, with a sidecar holding the request URL. Errors are logged and otherwise ignored.
+func (s *Scraper) recordResponse(providerName string, prices []models.PriceResult) {
+	if s.recordDir == "" || len(prices) == 0 || len(prices[0].RawResponse) == 0 {
+		return
+	}
+
+	price := prices[0]
+	fetchedAt := price.FetchedAt
+	if fetchedAt.IsZero() {
+		fetchedAt = time.Now()
+	}
+	base := filepath.Join(s.recordDir, fmt.Sprintf("%s-%s", providerName, fetchedAt.UTC().Format("20060102T150405.000Z")))
+
+	meta := recordedResponse{
+		Provider:  providerName,
+		SourceURL: price.SourceURL,
+		FetchedAt: fetchedAt,
+	}
+	if price.ResponseSnapshot != nil {
+		meta.StatusCode = price.ResponseSnapshot.StatusCode
+		meta.Headers = price.ResponseSnapshot.Headers
+	}
+
+	err := os.WriteFile(base+".json", price.RawResponse, 0o644)
+	if err == nil {
+		var sidecar []byte
+		sidecar, err = json.MarshalIndent(meta, "", "  ")
+		if err == nil {
+			err = os.WriteFile(base+".meta.json", append(sidecar, '\n'), 0o644)
+		}
+	}
+	if err != nil {
+		s.logger.Warn().
+			Err(err).
+			Str("provider", providerName).
+			Msg("failed to record raw response")
+		return
+	}
+
+	s.logger.Debug().
+		Str("provider", providerName).
+		Str("file", base+".json").
+		Msg("recorded raw response")
+}
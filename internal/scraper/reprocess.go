@@ -0,0 +1,126 @@
+package scraper
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/andygrunwald/oil-price-scraper/internal/api"
+	"github.com/andygrunwald/oil-price-scraper/internal/database"
+)
+
+// ReprocessResult summarizes a reprocess run.
+type ReprocessResult struct {
+	// Processed is the number of records with a stored raw response.
+	Processed int
+	// Updated is the number of records whose price changed.
+	Updated int
+	// Unchanged is the number of records whose price stayed the same.
+	Unchanged int
+	// Unmatched is the number of records the raw response yielded no price for.
+	Unmatched int
+	// Failed is the number of records whose raw response could not be parsed or stored.
+	Failed int
+}
+
+// Reprocess re-parses stored raw responses of a provider with the current parsing
+// logic and updates the stored prices accordingly. No provider API is called.
+// With dryRun, changes are only logged.
+func (s *Scraper) Reprocess(ctx context.Context, providerName string, from, to time.Time, dryRun bool) (ReprocessResult, error) {
+	var result ReprocessResult
+
+	s.mu.RLock()
+	provider, ok := s.providers[providerName]
+	s.mu.RUnlock()
+
+	if !ok {
+		return result, fmt.Errorf("provider not found: %s", providerName)
+	}
+
+	parser, ok := provider.(api.ResponseParser)
+	if !ok {
+		return result, fmt.Errorf("provider %s does not support parsing raw responses", providerName)
+	}
+
+	prices, err := s.db.GetPricesForDateRange(ctx, providerName, from, to, "")
+	if err != nil {
+		return result, err
+	}
+
+	for _, stored := range prices {
+		if len(stored.RawResponse) == 0 {
+			continue
+		}
+		result.Processed++
+
+		zipCode := ""
+		if stored.ZipCode != nil {
+			zipCode = *stored.ZipCode
+		}
+
+		derived, err := parser.ParseRawResponse(stored.RawResponse, api.ParseParams{
+			FetchedAt:    stored.FetchedAt,
+			ZipCode:      zipCode,
+			Region:       stored.Region,
+			DateLocation: s.priceLocation,
+		})
+		if err != nil {
+			s.logger.Error().
+				Err(err).
+				Uint64("id", stored.ID).
+				Str("date", stored.PriceDate.Format("2006-01-02")).
+				Msg("failed to parse raw response")
+			result.Failed++
+			continue
+		}
+
+		// Stored prices went through the transformers, so the derived ones must as well
+		derived = s.applyTransformers(derived)
+
+		// The raw response may contain prices for several dates and products,
+		// only the one matching the stored record is relevant.
+		matched := false
+		for _, price := range derived {
+			if price.ProductType != stored.ProductType || price.Date.Format("2006-01-02") != stored.PriceDate.Format("2006-01-02") {
+				continue
+			}
+			matched = true
+
+			// The stored price was rounded to the scale of the column
+			if database.SamePrice(price.PricePer100L, stored.PricePer100L) {
+				result.Unchanged++
+				break
+			}
+
+			s.logger.Info().
+				Uint64("id", stored.ID).
+				Str("product_type", stored.ProductType).
+				Str("date", stored.PriceDate.Format("2006-01-02")).
+				Float64("oldPrice", stored.PricePer100L).
+				Float64("newPrice", price.PricePer100L).
+				Bool("dryRun", dryRun).
+				Msg("reprocessed price changed")
+
+			if !dryRun {
+				if err := s.db.UpdatePriceValue(ctx, stored.ID, price.PricePer100L); err != nil {
+					s.logger.Error().Err(err).Uint64("id", stored.ID).Msg("failed to update price")
+					result.Failed++
+					break
+				}
+			}
+			result.Updated++
+			break
+		}
+
+		if !matched {
+			s.logger.Warn().
+				Uint64("id", stored.ID).
+				Str("product_type", stored.ProductType).
+				Str("date", stored.PriceDate.Format("2006-01-02")).
+				Msg("raw response contains no matching price")
+			result.Unmatched++
+		}
+	}
+
+	return result, nil
+}
@@ -3,36 +3,117 @@ package scraper
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/rs/zerolog"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/sync/singleflight"
 
 	"github.com/andygrunwald/oil-price-scraper/internal/api"
 	"github.com/andygrunwald/oil-price-scraper/internal/database"
 	"github.com/andygrunwald/oil-price-scraper/internal/models"
+	"github.com/andygrunwald/oil-price-scraper/internal/sink"
+	"github.com/andygrunwald/oil-price-scraper/internal/transform"
 )
 
+// tracer creates the spans of scrape operations (a no-op unless tracing is set up).
+var tracer = otel.Tracer("github.com/andygrunwald/oil-price-scraper/internal/scraper")
+
 // PrometheusMetrics defines the interface for recording Prometheus metrics.
 type PrometheusMetrics interface {
 	RecordAPIRequest(provider, status string, duration float64)
 	RecordLastScrape(provider string, timestamp float64)
 	RecordCurrentPrice(provider, scope, productType string, price float64)
+	RecordHeadlinePrice(provider string, price float64)
+	RecordPrimaryPrice(price float64)
 	RecordDBOperation(operation, status string)
 	RecordPricesStored(provider string, count float64)
+	RecordProviderHealth(healthy, total int)
+	RecordAPIError(provider, reason string)
+	RecordResponseSize(provider string, bytes int)
+	RecordNotModified(provider string)
 }
 
 // Metrics holds scraping metrics for a provider.
 type Metrics struct {
-	mu                sync.RWMutex
-	TotalRequests     int64
-	TotalErrors       int64
-	LastScrapeAt      *time.Time
-	LastScrapeSuccess bool
-	LastResponseTime  time.Duration
-	LastPrice         *float64
-	LastError         *string
-	LastRawResponse   string
+	mu              sync.RWMutex
+	TotalRequests   int64
+	TotalErrors     int64
+	TransportErrors int64
+	TimeoutErrors   int64
+	StatusErrors    int64
+	RateLimitErrors int64
+	ParseErrors     int64
+	// ConsecutiveFailures counts the failed scrapes since the last successful one
+	ConsecutiveFailures int64
+	LastScrapeAt        *time.Time
+	LastScrapeSuccess   bool
+	LastSuccessAt       *time.Time
+	LastResponseTime    time.Duration
+	LastResponseBytes   int
+	LastPrice           *float64
+	// LastPriceProductType is the product type of LastPrice (see SetHeadlineStrategies)
+	LastPriceProductType string
+	LastError            *string
+	LastRawResponse      string
+	// Metadata is the last metadata the provider reported about its prices (see SetMetadataStore)
+	Metadata *models.ProviderMetadata
+}
+
+// Failure describes a failed scrape of a provider.
+type Failure struct {
+	Provider string
+	// Reason is the error category (see api.ErrorReason)
+	Reason string
+	Err    error
+	// StatusCode is the HTTP status code of the response, if the provider returned one
+	StatusCode          int
+	ConsecutiveFailures int64
+	FailedAt            time.Time
+}
+
+// ScrapeSummary describes the outcome of scraping several providers.
+type ScrapeSummary struct {
+	// Succeeded are the providers scraped successfully, in scrape order
+	Succeeded []string
+	// Failed are the providers whose scrape failed, in scrape order
+	Failed []string
+}
+
+// AnyFailed returns true if at least one provider failed.
+func (s ScrapeSummary) AnyFailed() bool {
+	return len(s.Failed) > 0
+}
+
+// AllFailed returns true if providers were scraped and every one of them failed.
+func (s ScrapeSummary) AllFailed() bool {
+	return len(s.Failed) > 0 && len(s.Succeeded) == 0
+}
+
+// recordError counts an error in TotalErrors and its category (see api.ErrorReason).
+// The caller must hold the lock.
+func (m *Metrics) recordError(reason string) {
+	m.TotalErrors++
+	m.ConsecutiveFailures++
+	switch reason {
+	case api.ErrorReasonTransport:
+		m.TransportErrors++
+	case api.ErrorReasonTimeout:
+		m.TimeoutErrors++
+	case api.ErrorReasonStatus:
+		m.StatusErrors++
+	case api.ErrorReasonRateLimit:
+		m.RateLimitErrors++
+	case api.ErrorReasonParse:
+		m.ParseErrors++
+	}
 }
 
 // GetSnapshot returns a thread-safe snapshot of the metrics.
@@ -40,38 +121,96 @@ func (m *Metrics) GetSnapshot() MetricsSnapshot {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 	return MetricsSnapshot{
-		TotalRequests:     m.TotalRequests,
-		TotalErrors:       m.TotalErrors,
-		LastScrapeAt:      m.LastScrapeAt,
-		LastScrapeSuccess: m.LastScrapeSuccess,
-		LastResponseTime:  m.LastResponseTime,
-		LastPrice:         m.LastPrice,
-		LastError:         m.LastError,
-		LastRawResponse:   m.LastRawResponse,
+		TotalRequests:        m.TotalRequests,
+		TotalErrors:          m.TotalErrors,
+		TransportErrors:      m.TransportErrors,
+		TimeoutErrors:        m.TimeoutErrors,
+		StatusErrors:         m.StatusErrors,
+		RateLimitErrors:      m.RateLimitErrors,
+		ParseErrors:          m.ParseErrors,
+		ConsecutiveFailures:  m.ConsecutiveFailures,
+		LastScrapeAt:         m.LastScrapeAt,
+		LastScrapeSuccess:    m.LastScrapeSuccess,
+		LastSuccessAt:        m.LastSuccessAt,
+		LastResponseTime:     m.LastResponseTime,
+		LastResponseBytes:    m.LastResponseBytes,
+		LastPrice:            m.LastPrice,
+		LastPriceProductType: m.LastPriceProductType,
+		LastError:            m.LastError,
+		LastRawResponse:      m.LastRawResponse,
+		Metadata:             m.Metadata,
 	}
 }
 
 // MetricsSnapshot is a thread-safe copy of Metrics data.
 type MetricsSnapshot struct {
-	TotalRequests     int64
-	TotalErrors       int64
-	LastScrapeAt      *time.Time
-	LastScrapeSuccess bool
-	LastResponseTime  time.Duration
-	LastPrice         *float64
-	LastError         *string
-	LastRawResponse   string
+	TotalRequests        int64
+	TotalErrors          int64
+	TransportErrors      int64
+	TimeoutErrors        int64
+	StatusErrors         int64
+	RateLimitErrors      int64
+	ParseErrors          int64
+	ConsecutiveFailures  int64
+	LastScrapeAt         *time.Time
+	LastScrapeSuccess    bool
+	LastSuccessAt        *time.Time
+	LastResponseTime     time.Duration
+	LastResponseBytes    int
+	LastPrice            *float64
+	LastPriceProductType string
+	LastError            *string
+	LastRawResponse      string
+	// Metadata is the last metadata the provider reported about its prices (see SetMetadataStore)
+	Metadata *models.ProviderMetadata
+}
+
+// cachedPrices holds the last fetched prices of a provider.
+type cachedPrices struct {
+	prices    []models.PriceResult
+	fetchedAt time.Time
 }
 
 // Scraper orchestrates scraping from multiple providers.
 type Scraper struct {
-	db               *database.DB
-	providers        map[string]api.Provider
-	providerMetrics  map[string]*Metrics
-	promMetrics      PrometheusMetrics
-	storeRawResponse bool
-	logger           zerolog.Logger
-	mu               sync.RWMutex
+	db                   *database.DB
+	providers            map[string]api.Provider
+	registered           []string
+	providerMetrics      map[string]*Metrics
+	promMetrics          PrometheusMetrics
+	storeRawResponse     bool
+	storeResponseHeaders bool
+	cacheTTL             time.Duration
+	cache                map[string]cachedPrices
+	throttle             *writeThrottle
+	dryRun               bool
+	label                string
+	transformers         transform.Pipeline
+	sinks                []sink.Sink
+	fetchedHandler       func(providerName string, prices []models.PriceResult)
+	failureHandler       func(ctx context.Context, failure Failure)
+	currentPricePolicies map[string]api.CurrentPricePolicy
+	headlineStrategies   map[string]string
+	primaryProvider      string
+	primaryPrice         *models.PriceResult
+	latestOnly           map[string]bool
+	currentPriceHandler  func(ctx context.Context, providerName string, prices []models.PriceResult)
+	storedHandler        func(ctx context.Context, providerName string, stored bool)
+	summaryHandler       func(ctx context.Context, summary Summary)
+	counterStore         CounterStore
+	metadataStore        MetadataStore
+	counterWG            sync.WaitGroup
+	rawBudget            *rawBudget
+	// counterMu serializes counter writes, so every increment is added to the store once
+	counterMu sync.Mutex
+	// persistedCounters are the counters (per provider) in memory the store already contains
+	persistedCounters map[string]models.ProviderCounters
+	// fetches de-duplicates concurrent identical provider fetches (see fetchShared)
+	fetches       singleflight.Group
+	recordDir     string
+	priceLocation *time.Location
+	logger        zerolog.Logger
+	mu            sync.RWMutex
 }
 
 // New creates a new Scraper.
@@ -81,18 +220,51 @@ func New(db *database.DB, storeRawResponse bool, logger zerolog.Logger) *Scraper
 		providers:        make(map[string]api.Provider),
 		providerMetrics:  make(map[string]*Metrics),
 		storeRawResponse: storeRawResponse,
+		cache:            make(map[string]cachedPrices),
+		throttle:         newWriteThrottle(0),
+		priceLocation:    time.UTC,
 		logger:           logger.With().Str("component", "scraper").Logger(),
 	}
 }
 
+// SetPriceLocation sets the time zone whose calendar day is the price date (see api.PriceDate).
+// It decides which prices are today's. It should match the location of the providers.
+func (s *Scraper) SetPriceLocation(loc *time.Location) {
+	if loc == nil {
+		loc = time.UTC
+	}
+	s.priceLocation = loc
+}
+
+// PriceLocation returns the time zone whose calendar day is the price date.
+func (s *Scraper) PriceLocation() *time.Location {
+	return s.priceLocation
+}
+
 // RegisterProvider registers a provider with the scraper.
 func (s *Scraper) RegisterProvider(provider api.Provider) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
+	if _, ok := s.providers[provider.Name()]; !ok {
+		s.registered = append(s.registered, provider.Name())
+	}
 	s.providers[provider.Name()] = provider
 	s.providerMetrics[provider.Name()] = &Metrics{}
 }
 
+// ReplaceProvider replaces a registered provider with the same name, keeping its
+// metrics (e.g., after its configuration changed). Scrapes in flight finish with
+// the replaced provider. It returns false if no provider with the name is registered.
+func (s *Scraper) ReplaceProvider(provider api.Provider) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.providers[provider.Name()]; !ok {
+		return false
+	}
+	s.providers[provider.Name()] = provider
+	return true
+}
+
 // GetProviders returns all registered providers.
 func (s *Scraper) GetProviders() []api.Provider {
 	s.mu.RLock()
@@ -114,31 +286,325 @@ func (s *Scraper) GetMetrics(providerName string) *Metrics {
 // SetPrometheusMetrics sets the Prometheus metrics recorder.
 func (s *Scraper) SetPrometheusMetrics(m PrometheusMetrics) {
 	s.promMetrics = m
+	if m != nil {
+		s.recordProviderHealth()
+	}
+}
+
+// SetCacheTTL sets how long fetched prices are reused by cached scrapes.
+// A TTL of zero disables the cache.
+func (s *Scraper) SetCacheTTL(ttl time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.cacheTTL = ttl
+}
+
+// SetStoreResponseHeaders enables storing a snapshot of the HTTP status and
+// response headers alongside the raw response. It has no effect if raw
+// responses are not stored.
+func (s *Scraper) SetStoreResponseHeaders(store bool) {
+	s.storeResponseHeaders = store
+}
+
+// SetMinWriteInterval sets the minimum interval between writes of an unchanged
+// price of the same series (provider, product type, zip code).
+// Within this interval, repeated identical prices skip the database.
+// An interval of zero disables throttling.
+func (s *Scraper) SetMinWriteInterval(interval time.Duration) {
+	s.throttle = newWriteThrottle(interval)
+}
+
+// SetTransformers sets the pipeline applied to every price before it is stored.
+func (s *Scraper) SetTransformers(pipeline transform.Pipeline) {
+	s.transformers = pipeline
+}
+
+// applyTransformers runs prices through the transformer pipeline and logs dropped prices.
+func (s *Scraper) applyTransformers(prices []models.PriceResult) []models.PriceResult {
+	kept, dropped := s.transformers.Apply(prices)
+	for _, d := range dropped {
+		// Prices dropped without an error were filtered intentionally
+		event := s.logger.Debug()
+		if d.Err != nil {
+			event = s.logger.Warn()
+		}
+		event.
+			Err(d.Err).
+			Str("provider", d.Price.Provider).
+			Str("product_type", d.Price.ProductType).
+			Str("date", d.Price.Date.Format("2006-01-02")).
+			Float64("price", d.Price.PricePer100L).
+			Str("transformer", d.Transformer).
+			Msg("price dropped by transformer")
+	}
+	return kept
+}
+
+// SetSinks sets the sinks every scraped price is written to in addition to the database
+// (e.g., a JSON lines file). Sinks receive all prices after the transformers ran,
+// including prices already stored and prices of dry runs.
+func (s *Scraper) SetSinks(sinks []sink.Sink) {
+	s.sinks = sinks
+}
+
+// writeSinks writes the prices to all sinks. Failing sinks are logged and do not affect the database.
+func (s *Scraper) writeSinks(ctx context.Context, prices []models.PriceResult) {
+	for _, out := range s.sinks {
+		for _, price := range prices {
+			if err := out.Write(ctx, price); err != nil {
+				s.logger.Error().
+					Err(err).
+					Str("provider", price.Provider).
+					Str("product_type", price.ProductType).
+					Msg("failed to write price to sink")
+				// Skip the remaining prices of the failing sink instead of logging every one
+				break
+			}
+		}
+	}
+}
+
+// SetDryRun disables storing prices. Prices are still fetched and passed
+// to the fetched handler. In dry-run mode, the scraper does not need a database.
+func (s *Scraper) SetDryRun(dryRun bool) {
+	s.dryRun = dryRun
+}
+
+// SetLabel sets a free-form label stored with every price (e.g., "source=manual-import-2024"),
+// so prices of manual runs can be told apart from scheduled scrapes. Empty stores no label.
+func (s *Scraper) SetLabel(label string) {
+	s.label = label
+}
+
+// applyLabel sets the label on all prices if a label is configured.
+func (s *Scraper) applyLabel(prices []models.PriceResult) {
+	if s.label == "" {
+		return
+	}
+	for i := range prices {
+		prices[i].Label = s.label
+	}
+}
+
+// SetFetchedHandler sets a function that is called with the prices fetched
+// from a provider, before they are stored.
+func (s *Scraper) SetFetchedHandler(fn func(providerName string, prices []models.PriceResult)) {
+	s.fetchedHandler = fn
+}
+
+// SetFailureHandler sets a function that is called whenever fetching the
+// current prices of a provider fails.
+func (s *Scraper) SetFailureHandler(fn func(ctx context.Context, failure Failure)) {
+	s.failureHandler = fn
+}
+
+// SetCurrentPriceHandler sets a function that is called after storing with the
+// prices that are current according to the provider's current price policy.
+func (s *Scraper) SetCurrentPriceHandler(fn func(ctx context.Context, providerName string, prices []models.PriceResult)) {
+	s.currentPriceHandler = fn
+}
+
+// SetStoredHandler sets a function that is called after every fetch of the current
+// prices of a provider. stored is true only if the response was checked and all of its
+// prices were stored (or already existed); it is false for failed fetches, 304 Not Modified
+// answers, failed database writes, and dry runs.
+func (s *Scraper) SetStoredHandler(fn func(ctx context.Context, providerName string, stored bool)) {
+	s.storedHandler = fn
+}
+
+// SetCurrentPricePolicies overrides the current price policy of providers
+// (provider name -> policy). Other providers use the policy of their metadata.
+func (s *Scraper) SetCurrentPricePolicies(policies map[string]api.CurrentPricePolicy) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.currentPricePolicies = policies
+}
+
+// SetStoreLatestOnly makes the scraper store only the newest price per series
+// (product type, zip code, region) of every scrape of the given providers.
+// National providers like HeizOel24 return several days per scrape; this keeps
+// one point per day. Backfills are not affected.
+func (s *Scraper) SetStoreLatestOnly(providers []string) {
+	latestOnly := make(map[string]bool, len(providers))
+	for _, name := range providers {
+		latestOnly[strings.TrimSpace(name)] = true
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.latestOnly = latestOnly
+}
+
+// SetHeadlineStrategies sets which price of a response represents a provider
+// (provider name -> api.HeadlineFirst, api.HeadlineCheapest, or a product type),
+// e.g., as last price in /status. Only the current prices of a response (see
+// SetCurrentPricePolicies) are considered. All prices are stored regardless.
+// Providers without an entry use the first price.
+func (s *Scraper) SetHeadlineStrategies(strategies map[string]string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.headlineStrategies = strategies
+}
+
+// SetPrimaryProvider sets the provider whose headline price (see SetHeadlineStrategies)
+// is the headline price of the service. Empty uses the first registered provider.
+func (s *Scraper) SetPrimaryProvider(providerName string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.primaryProvider = providerName
+}
+
+// PrimaryProvider returns the name of the primary provider, or an empty string
+// if no provider is registered.
+func (s *Scraper) PrimaryProvider() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.primaryProviderLocked()
+}
+
+// primaryProviderLocked returns the name of the primary provider. The caller must hold the lock.
+func (s *Scraper) primaryProviderLocked() string {
+	if s.primaryProvider != "" {
+		return s.primaryProvider
+	}
+	if len(s.registered) > 0 {
+		return s.registered[0]
+	}
+	return ""
+}
+
+// PrimaryPrice returns the headline price of the primary provider from its last
+// successful scrape, or nil if it was not scraped successfully yet.
+func (s *Scraper) PrimaryPrice() *models.PriceResult {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.primaryPrice == nil {
+		return nil
+	}
+	price := *s.primaryPrice
+	return &price
+}
+
+// CurrentPricePolicy returns the current price policy of a provider.
+func (s *Scraper) CurrentPricePolicy(providerName string) api.CurrentPricePolicy {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if policy, ok := s.currentPricePolicies[providerName]; ok {
+		return policy
+	}
+	if provider, ok := s.providers[providerName]; ok {
+		if policy := api.MetadataOf(provider).CurrentPrice; policy != "" {
+			return policy
+		}
+	}
+	return api.CurrentPriceLatest
+}
+
+// HasProvider returns true if a provider with the given name is registered.
+func (s *Scraper) HasProvider(providerName string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	_, ok := s.providers[providerName]
+	return ok
 }
 
 // ScrapeAll scrapes current prices from all registered providers.
 func (s *Scraper) ScrapeAll(ctx context.Context) error {
+	return s.ScrapeProviders(ctx, s.providerNames())
+}
+
+// ScrapeAllSummary scrapes current prices from all registered providers
+// and returns which providers succeeded and which failed.
+func (s *Scraper) ScrapeAllSummary(ctx context.Context) ScrapeSummary {
+	return s.scrapeProviders(ctx, s.providerNames(), false)
+}
+
+// ScrapeAllCached scrapes prices from all registered providers, reusing
+// prices fetched within the cache TTL instead of calling the provider API again.
+func (s *Scraper) ScrapeAllCached(ctx context.Context) error {
+	s.scrapeProviders(ctx, s.providerNames(), true)
+	return nil
+}
+
+// ScrapeProviders scrapes current prices from the given providers.
+// Errors of single providers are logged and do not abort the remaining providers.
+func (s *Scraper) ScrapeProviders(ctx context.Context, providerNames []string) error {
+	s.scrapeProviders(ctx, providerNames, false)
+	return nil
+}
+
+// recordProviderHealth records how many providers succeeded on their last scrape.
+func (s *Scraper) recordProviderHealth() {
 	s.mu.RLock()
-	providers := make([]api.Provider, 0, len(s.providers))
-	for _, p := range s.providers {
-		providers = append(providers, p)
+	total := len(s.providerMetrics)
+	healthy := 0
+	for _, m := range s.providerMetrics {
+		if m.GetSnapshot().LastScrapeSuccess {
+			healthy++
+		}
 	}
 	s.mu.RUnlock()
 
-	for _, provider := range providers {
-		if err := s.ScrapeProvider(ctx, provider.Name()); err != nil {
+	s.promMetrics.RecordProviderHealth(healthy, total)
+}
+
+// providerNames returns the names of all registered providers.
+func (s *Scraper) providerNames() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	names := make([]string, 0, len(s.providers))
+	for name := range s.providers {
+		names = append(names, name)
+	}
+	return names
+}
+
+// scrapeProviders scrapes the given providers one after another. Errors are
+// logged and do not abort the remaining providers.
+func (s *Scraper) scrapeProviders(ctx context.Context, providerNames []string, useCache bool) ScrapeSummary {
+	var summary ScrapeSummary
+	for _, name := range providerNames {
+		if err := s.scrapeProvider(ctx, name, useCache); err != nil {
 			s.logger.Error().
 				Err(err).
-				Str("provider", provider.Name()).
+				Str("provider", name).
 				Msg("failed to scrape provider")
+			summary.Failed = append(summary.Failed, name)
+			continue
 		}
+		summary.Succeeded = append(summary.Succeeded, name)
 	}
 
-	return nil
+	return summary
 }
 
 // ScrapeProvider scrapes current prices from a specific provider.
+// It always calls the provider API and refreshes the cache.
 func (s *Scraper) ScrapeProvider(ctx context.Context, providerName string) error {
+	return s.scrapeProvider(ctx, providerName, false)
+}
+
+// ScrapeProviderCached scrapes prices from a specific provider, reusing
+// prices fetched within the cache TTL instead of calling the provider API again.
+func (s *Scraper) ScrapeProviderCached(ctx context.Context, providerName string) error {
+	return s.scrapeProvider(ctx, providerName, true)
+}
+
+// cachedPricesFor returns the cached prices of a provider if they are within the cache TTL.
+func (s *Scraper) cachedPricesFor(providerName string) ([]models.PriceResult, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.cacheTTL <= 0 {
+		return nil, false
+	}
+	cached, ok := s.cache[providerName]
+	if !ok || time.Since(cached.fetchedAt) > s.cacheTTL {
+		return nil, false
+	}
+	return cached.prices, true
+}
+
+func (s *Scraper) scrapeProvider(ctx context.Context, providerName string, useCache bool) error {
 	s.mu.RLock()
 	provider, ok := s.providers[providerName]
 	metrics := s.providerMetrics[providerName]
@@ -149,30 +615,112 @@ func (s *Scraper) ScrapeProvider(ctx context.Context, providerName string) error
 		return nil
 	}
 
+	ctx, span := tracer.Start(ctx, "scrape_provider", trace.WithAttributes(
+		attribute.String("provider", providerName),
+		attribute.Bool("cached", useCache),
+	))
+	defer span.End()
+
+	if useCache {
+		if prices, ok := s.cachedPricesFor(providerName); ok {
+			s.logger.Info().
+				Str("provider", providerName).
+				Int("count", len(prices)).
+				Msg("using cached prices")
+			s.storePrices(ctx, providerName, prices)
+			return nil
+		}
+	}
+
 	s.logger.Info().Str("provider", providerName).Msg("scraping provider")
 
+	// Reserve memory for the largest response the provider may send before fetching it
+	reserved, err := s.rawBudget.reserve(ctx, api.MetadataOf(provider).ResponseSizeLimit())
+	if err != nil {
+		return fmt.Errorf("waiting for raw response memory: %w", err)
+	}
+	defer func() { s.rawBudget.release(reserved) }()
+
 	start := time.Now()
 	metrics.mu.Lock()
 	metrics.TotalRequests++
 	metrics.mu.Unlock()
 
-	prices, err := provider.FetchCurrentPrices(ctx)
+	prices, err := s.fetchCurrent(ctx, provider)
+	err = api.WrapTimeout(err)
 	duration := time.Since(start)
 
+	stored := false
+	if s.storedHandler != nil {
+		defer func() { s.storedHandler(ctx, providerName, stored) }()
+	}
+
+	// A 304 Not Modified answer to a conditional request is a successful scrape without new prices
+	notModified := errors.Is(err, api.ErrNotModified)
+	if notModified {
+		err = nil
+	} else if err == nil {
+		err = api.CheckResults(provider, prices)
+		if err == nil {
+			err = api.CheckSuccess(provider, prices, time.Now().In(s.priceLocation))
+		}
+	}
+
+	// All prices of a response share its raw body
+	responseBytes := -1
+	if len(prices) > 0 {
+		responseBytes = len(prices[0].RawResponse)
+	}
+
+	var reason string
+	if err != nil {
+		reason = api.ErrorReason(err)
+	}
+
+	// The headline is selected among the current prices only, not older days of the response
+	current := api.CurrentPrices(s.CurrentPricePolicy(providerName), prices, time.Now().In(s.priceLocation))
+
+	s.mu.Lock()
+	strategy := s.headlineStrategies[providerName]
+	headline, hasHeadline := api.HeadlinePrice(strategy, current)
+	isPrimary := providerName == s.primaryProviderLocked()
+	if err == nil && hasHeadline && isPrimary {
+		primary := headline
+		primary.RawResponse = nil
+		s.primaryPrice = &primary
+	}
+	s.mu.Unlock()
+	if err == nil && !hasHeadline && len(current) > 0 {
+		s.logger.Warn().
+			Str("provider", providerName).
+			Str("headlineProduct", strategy).
+			Msg("configured headline product not found in the current prices, keeping the last headline price")
+	}
+
 	now := time.Now()
+	var consecutiveFailures int64
 	metrics.mu.Lock()
 	metrics.LastScrapeAt = &now
 	metrics.LastResponseTime = duration
+	if responseBytes >= 0 {
+		metrics.LastResponseBytes = responseBytes
+	}
 	if err != nil {
-		metrics.TotalErrors++
+		metrics.recordError(reason)
 		metrics.LastScrapeSuccess = false
 		errStr := err.Error()
 		metrics.LastError = &errStr
+		consecutiveFailures = metrics.ConsecutiveFailures
 	} else {
 		metrics.LastScrapeSuccess = true
+		metrics.LastSuccessAt = &now
 		metrics.LastError = nil
+		metrics.ConsecutiveFailures = 0
+		if hasHeadline {
+			metrics.LastPrice = &headline.PricePer100L
+			metrics.LastPriceProductType = headline.ProductType
+		}
 		if len(prices) > 0 {
-			metrics.LastPrice = &prices[0].PricePer100L
 			if len(prices[0].RawResponse) > 0 {
 				// Store a truncated version for status endpoint
 				rawResp := string(prices[0].RawResponse)
@@ -184,6 +732,7 @@ func (s *Scraper) ScrapeProvider(ctx context.Context, providerName string) error
 		}
 	}
 	metrics.mu.Unlock()
+	s.persistCounters(providerName, metrics)
 
 	// Record Prometheus metrics for API request
 	if s.promMetrics != nil {
@@ -192,33 +741,138 @@ func (s *Scraper) ScrapeProvider(ctx context.Context, providerName string) error
 			status = "error"
 		}
 		s.promMetrics.RecordAPIRequest(providerName, status, duration.Seconds())
+		if responseBytes >= 0 {
+			s.promMetrics.RecordResponseSize(providerName, responseBytes)
+		}
+		if err != nil {
+			s.promMetrics.RecordAPIError(providerName, reason)
+		}
+		if err == nil && hasHeadline {
+			s.promMetrics.RecordHeadlinePrice(providerName, headline.PricePer100L)
+			if isPrimary {
+				s.promMetrics.RecordPrimaryPrice(headline.PricePer100L)
+			}
+		}
+		s.recordProviderHealth()
 	}
 
 	if err != nil {
 		s.logger.Error().
 			Err(err).
 			Str("provider", providerName).
+			Str("reason", reason).
 			Dur("duration", duration).
 			Msg("failed to fetch prices")
+
+		if s.failureHandler != nil {
+			failure := Failure{
+				Provider:            providerName,
+				Reason:              reason,
+				Err:                 err,
+				ConsecutiveFailures: consecutiveFailures,
+				FailedAt:            now,
+			}
+			var statusErr *api.StatusError
+			if errors.As(err, &statusErr) {
+				failure.StatusCode = statusErr.StatusCode
+			}
+			s.failureHandler(ctx, failure)
+		}
+
+		span.RecordError(err)
+		span.SetStatus(codes.Error, reason)
 		return err
 	}
+	span.SetAttributes(attribute.Int("prices", len(prices)))
 
 	// Record successful scrape timestamp
 	if s.promMetrics != nil {
 		s.promMetrics.RecordLastScrape(providerName, float64(time.Now().Unix()))
 	}
 
+	if notModified {
+		s.logger.Info().
+			Str("provider", providerName).
+			Dur("duration", duration).
+			Msg("prices not modified since the last fetch, skipping")
+		span.SetAttributes(attribute.Bool("not_modified", true))
+		if s.promMetrics != nil {
+			s.promMetrics.RecordNotModified(providerName)
+		}
+		return nil
+	}
+
 	s.logger.Info().
 		Str("provider", providerName).
 		Int("count", len(prices)).
 		Dur("duration", duration).
 		Msg("fetched prices")
 
-	// Store prices in database
+	s.checkMetadata(ctx, providerName, metrics, prices)
+	s.recordResponse(providerName, prices)
+	if !s.storeRawResponse {
+		dropRawResponses(prices)
+	}
+	reserved = s.rawBudget.shrink(reserved, prices)
+
+	s.mu.Lock()
+	if s.cacheTTL > 0 {
+		s.cache[providerName] = cachedPrices{prices: prices, fetchedAt: time.Now()}
+	}
+	s.mu.Unlock()
+
+	stored = s.storePrices(ctx, providerName, prices)
+
+	return nil
+}
+
+// storePrices stores fetched prices in the database, skipping prices that already exist.
+// It returns true if all prices were stored or already existed, and false for dry runs.
+func (s *Scraper) storePrices(ctx context.Context, providerName string, prices []models.PriceResult) bool {
+	prices = s.applyTransformers(prices)
+	s.applyLabel(prices)
+
+	s.mu.RLock()
+	latestOnly := s.latestOnly[providerName]
+	s.mu.RUnlock()
+	if latestOnly {
+		latest := api.CurrentPrices(api.CurrentPriceLatest, prices, time.Now().In(s.priceLocation))
+		if ignored := len(prices) - len(latest); ignored > 0 {
+			s.logger.Debug().
+				Str("provider", providerName).
+				Int("ignored", ignored).
+				Msg("storing only the latest price per series")
+		}
+		prices = latest
+	}
+
+	if s.fetchedHandler != nil {
+		s.fetchedHandler(providerName, prices)
+	}
+	s.writeSinks(ctx, prices)
+
+	if s.dryRun {
+		s.logger.Info().
+			Str("provider", providerName).
+			Int("count", len(prices)).
+			Msg("dry run, not storing prices")
+		return false
+	}
+
+	complete := true
 	var storedCount float64
 	for _, price := range prices {
+		if s.throttle.ShouldSkip(price) {
+			s.logger.Debug().
+				Str("provider", price.Provider).
+				Str("product_type", price.ProductType).
+				Str("date", price.Date.Format("2006-01-02")).
+				Msg("unchanged price written recently, skipping")
+			continue
+		}
+
 		// Check if already exists
-		exists, err := s.db.ExistsForDate(ctx, price.Provider, price.ProductType, price.Date, price.ZipCode)
+		exists, err := s.db.ExistsForDate(ctx, price.Provider, price.ProductType, price.Date, price.ZipCode, price.Region)
 		if err != nil {
 			s.logger.Error().
 				Err(err).
@@ -229,6 +883,7 @@ func (s *Scraper) ScrapeProvider(ctx context.Context, providerName string) error
 			if s.promMetrics != nil {
 				s.promMetrics.RecordDBOperation("select", "error")
 			}
+			complete = false
 			continue
 		}
 		if s.promMetrics != nil {
@@ -241,10 +896,12 @@ func (s *Scraper) ScrapeProvider(ctx context.Context, providerName string) error
 				Str("product_type", price.ProductType).
 				Str("date", price.Date.Format("2006-01-02")).
 				Msg("price already exists, skipping")
+			s.throttle.Record(price)
+			s.auditSkip(ctx, price)
 			continue
 		}
 
-		if err := s.db.InsertPrice(ctx, price, s.storeRawResponse); err != nil {
+		if err := s.db.InsertPrice(ctx, price, s.storeRawResponse, s.storeResponseHeaders); err != nil {
 			s.logger.Error().
 				Err(err).
 				Str("provider", price.Provider).
@@ -254,11 +911,13 @@ func (s *Scraper) ScrapeProvider(ctx context.Context, providerName string) error
 			if s.promMetrics != nil {
 				s.promMetrics.RecordDBOperation("insert", "error")
 			}
+			s.saveFailedInsert(ctx, price, err)
+			complete = false
 		} else {
 			storedCount++
+			s.throttle.Record(price)
 			if s.promMetrics != nil {
 				s.promMetrics.RecordDBOperation("insert", "success")
-				s.promMetrics.RecordCurrentPrice(price.Provider, string(price.Scope), price.ProductType, price.PricePer100L)
 			}
 		}
 	}
@@ -268,82 +927,21 @@ func (s *Scraper) ScrapeProvider(ctx context.Context, providerName string) error
 		s.promMetrics.RecordPricesStored(providerName, storedCount)
 	}
 
-	return nil
-}
-
-// Backfill backfills historical data from a provider.
-func (s *Scraper) Backfill(ctx context.Context, providerName string, from, to time.Time, minDelay, maxDelay int) error {
-	s.mu.RLock()
-	provider, ok := s.providers[providerName]
-	s.mu.RUnlock()
-
-	if !ok {
-		s.logger.Warn().Str("provider", providerName).Msg("provider not found")
-		return nil
+	// Only prices that are current according to the provider's policy are exposed
+	// as current price, not every stored price (e.g., older days of a national provider)
+	current := api.CurrentPrices(s.CurrentPricePolicy(providerName), prices, time.Now().In(s.priceLocation))
+	if s.promMetrics != nil {
+		for _, price := range current {
+			s.promMetrics.RecordCurrentPrice(price.Provider, string(price.Scope), price.ProductType, price.PricePer100L)
+		}
 	}
-
-	if !provider.SupportsBackfill() {
-		s.logger.Warn().
-			Str("provider", providerName).
-			Msg("provider does not support backfill")
-		return nil
+	if s.currentPriceHandler != nil && len(current) > 0 {
+		s.currentPriceHandler(ctx, providerName, current)
 	}
-
-	s.logger.Info().
-		Str("provider", providerName).
-		Str("from", from.Format("2006-01-02")).
-		Str("to", to.Format("2006-01-02")).
-		Msg("starting backfill")
-
-	// Fetch all historical prices at once (HeizOel24 supports date range queries)
-	prices, err := provider.FetchHistoricalPrices(ctx, from, to)
-	if err != nil {
-		return err
+	if s.summaryHandler != nil && len(current) > 0 {
+		s.summaryHandler(ctx, s.summarize(ctx, providerName, current))
 	}
-
-	s.logger.Info().
-		Str("provider", providerName).
-		Int("count", len(prices)).
-		Msg("fetched historical prices")
-
-	// Store prices in database
-	inserted := 0
-	skipped := 0
-	for _, price := range prices {
-		// Check if already exists
-		exists, err := s.db.ExistsForDate(ctx, price.Provider, price.ProductType, price.Date, price.ZipCode)
-		if err != nil {
-			s.logger.Error().
-				Err(err).
-				Str("provider", price.Provider).
-				Str("date", price.Date.Format("2006-01-02")).
-				Msg("failed to check existence")
-			continue
-		}
-
-		if exists {
-			skipped++
-			continue
-		}
-
-		if err := s.db.InsertPrice(ctx, price, s.storeRawResponse); err != nil {
-			s.logger.Error().
-				Err(err).
-				Str("provider", price.Provider).
-				Str("date", price.Date.Format("2006-01-02")).
-				Msg("failed to insert price")
-		} else {
-			inserted++
-		}
-	}
-
-	s.logger.Info().
-		Str("provider", providerName).
-		Int("inserted", inserted).
-		Int("skipped", skipped).
-		Msg("backfill completed")
-
-	return nil
+	return complete
 }
 
 // HasScrapedToday checks if the provider has been scraped today.
@@ -357,7 +955,7 @@ func (s *Scraper) HasScrapedToday(ctx context.Context, providerName string) (boo
 	}
 
 	// Get today's date
-	today := time.Now().Truncate(24 * time.Hour)
+	today := api.PriceDate(time.Now(), s.priceLocation)
 
 	// Check for each possible product type
 	// For simplicity, we'll just check if any record exists for today
@@ -366,17 +964,45 @@ func (s *Scraper) HasScrapedToday(ctx context.Context, providerName string) (boo
 
 	// Use the provider's standard product type or check the database
 	zipCode := ""
-	if provider.PriceScope() == models.PriceScopeLocal {
+	if provider.Capabilities().Scope == models.PriceScopeLocal {
 		// For local providers, we'd need to know the zip code
 		// This is a simplification - in practice you'd want to pass this
 		return false, nil
 	}
 
 	// Check if a record exists for today
-	exists, err := s.db.ExistsForDate(ctx, providerName, "standard", today, zipCode)
+	exists, err := s.db.ExistsForDate(ctx, providerName, "standard", today, zipCode, "")
 	if err != nil {
 		return false, err
 	}
 
 	return exists, nil
 }
+
+// auditSkip records a price skipped because it was already stored in the audit log.
+func (s *Scraper) auditSkip(ctx context.Context, price models.PriceResult) {
+	if err := s.db.AuditSkip(ctx, price); err != nil {
+		s.logger.Error().
+			Err(err).
+			Str("provider", price.Provider).
+			Str("date", price.Date.Format("2006-01-02")).
+			Msg("failed to write audit log")
+	}
+}
+
+// failedInsertTimeout bounds keeping a price whose insert failed, which must
+// not be cut short by the cancellation that may have failed the insert.
+const failedInsertTimeout = 30 * time.Second
+
+// saveFailedInsert keeps a price whose insert failed in the dead-letter table (see database.DB.SetDeadLetter).
+func (s *Scraper) saveFailedInsert(ctx context.Context, price models.PriceResult, insertErr error) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), failedInsertTimeout)
+	defer cancel()
+	if err := s.db.SaveFailedInsert(ctx, price, insertErr); err != nil {
+		s.logger.Error().
+			Err(err).
+			Str("provider", price.Provider).
+			Str("date", price.Date.Format("2006-01-02")).
+			Msg("failed to keep price in dead-letter table, it is lost")
+	}
+}
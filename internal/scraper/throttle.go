@@ -0,0 +1,72 @@
+package scraper
+
+import (
+	"sync"
+	"time"
+
+	"github.com/andygrunwald/oil-price-scraper/internal/models"
+)
+
+// lastWrite is the last written value of a price series.
+type lastWrite struct {
+	date      time.Time
+	price     float64
+	writtenAt time.Time
+}
+
+// writeThrottle skips repeated writes of unchanged prices of the same series
+// (provider, product type, zip code, region) within a minimum interval.
+type writeThrottle struct {
+	mu       sync.Mutex
+	interval time.Duration
+	writes   map[string]lastWrite
+}
+
+// newWriteThrottle creates a new writeThrottle. An interval of zero disables throttling.
+func newWriteThrottle(interval time.Duration) *writeThrottle {
+	return &writeThrottle{
+		interval: interval,
+		writes:   make(map[string]lastWrite),
+	}
+}
+
+// seriesKey returns the key identifying the series of a price.
+func seriesKey(price models.PriceResult) string {
+	return price.Provider + "|" + price.ProductType + "|" + price.ZipCode + "|" + price.Region
+}
+
+// ShouldSkip returns true if the same price for the same date was written
+// for the series within the interval.
+func (t *writeThrottle) ShouldSkip(price models.PriceResult) bool {
+	if t.interval <= 0 {
+		return false
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	last, ok := t.writes[seriesKey(price)]
+	if !ok {
+		return false
+	}
+
+	return time.Since(last.writtenAt) < t.interval &&
+		last.date.Equal(price.Date) &&
+		last.price == price.PricePer100L
+}
+
+// Record remembers that the price was written for its series.
+func (t *writeThrottle) Record(price models.PriceResult) {
+	if t.interval <= 0 {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.writes[seriesKey(price)] = lastWrite{
+		date:      price.Date,
+		price:     price.PricePer100L,
+		writtenAt: time.Now(),
+	}
+}
@@ -0,0 +1,129 @@
+package scraper
+
+import (
+	"context"
+	"fmt"
+	"math"
+
+	"github.com/andygrunwald/oil-price-scraper/internal/models"
+)
+
+// Trend directions.
+const (
+	TrendUp   = "up"
+	TrendDown = "down"
+	TrendFlat = "flat"
+)
+
+// trendLookback is the number of stored prices a trend is computed from.
+const trendLookback = 8
+
+// trendTolerance is the price change per 100 liters below which prices count as unchanged.
+const trendTolerance = 0.005
+
+// Trend is the direction of the latest price change of a series and how many
+// consecutive changes went in the same direction.
+type Trend struct {
+	Direction string `json:"direction"`
+	// Streak is the number of consecutive stored prices moving in Direction.
+	Streak int `json:"streak"`
+	// Change is the price difference to the previous stored price.
+	Change float64 `json:"change"`
+}
+
+// Arrow returns the arrow of the trend direction.
+func (t Trend) Arrow() string {
+	switch t.Direction {
+	case TrendUp:
+		return "↑"
+	case TrendDown:
+		return "↓"
+	default:
+		return "→"
+	}
+}
+
+// String returns a short human-readable trend (e.g., "↓ 3 days in a row").
+func (t Trend) String() string {
+	if t.Streak == 0 {
+		return t.Arrow()
+	}
+	if t.Streak == 1 {
+		return fmt.Sprintf("%s 1 day", t.Arrow())
+	}
+	return fmt.Sprintf("%s %d days in a row", t.Arrow(), t.Streak)
+}
+
+// ComputeTrend computes the trend of a series from its prices, newest first.
+// With fewer than two prices, the trend is flat without a streak.
+func ComputeTrend(prices []float64) Trend {
+	if len(prices) < 2 {
+		return Trend{Direction: TrendFlat}
+	}
+
+	direction := func(newer, older float64) string {
+		diff := newer - older
+		switch {
+		case math.Abs(diff) < trendTolerance:
+			return TrendFlat
+		case diff > 0:
+			return TrendUp
+		default:
+			return TrendDown
+		}
+	}
+
+	trend := Trend{
+		Direction: direction(prices[0], prices[1]),
+		Change:    math.Round((prices[0]-prices[1])*10000) / 10000,
+	}
+	for i := 0; i+1 < len(prices); i++ {
+		if direction(prices[i], prices[i+1]) != trend.Direction {
+			break
+		}
+		trend.Streak++
+	}
+	return trend
+}
+
+// Summary summarizes the current prices of a provider after a scrape.
+type Summary struct {
+	Provider string
+	Prices   []SummaryPrice
+}
+
+// SummaryPrice is a current price with the trend of its series.
+type SummaryPrice struct {
+	Price models.PriceResult
+	Trend Trend
+}
+
+// SetSummaryHandler sets a function that is called with a summary of the
+// current prices of a provider and their trends after they were stored.
+func (s *Scraper) SetSummaryHandler(fn func(ctx context.Context, summary Summary)) {
+	s.summaryHandler = fn
+}
+
+// summarize computes the trends of the current prices from the stored prices of their series.
+// Series whose prices cannot be queried are summarized without a trend.
+func (s *Scraper) summarize(ctx context.Context, providerName string, current []models.PriceResult) Summary {
+	summary := Summary{
+		Provider: providerName,
+		Prices:   make([]SummaryPrice, 0, len(current)),
+	}
+	for _, price := range current {
+		trend := Trend{Direction: TrendFlat}
+		recent, err := s.db.GetRecentSeriesPrices(ctx, price, trendLookback)
+		if err != nil {
+			s.logger.Warn().
+				Err(err).
+				Str("provider", price.Provider).
+				Str("product_type", price.ProductType).
+				Msg("failed to compute price trend")
+		} else {
+			trend = ComputeTrend(recent)
+		}
+		summary.Prices = append(summary.Prices, SummaryPrice{Price: price, Trend: trend})
+	}
+	return summary
+}
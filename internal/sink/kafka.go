@@ -0,0 +1,470 @@
+package sink
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/andygrunwald/oil-price-scraper/internal/models"
+)
+
+// kafkaTimeout bounds connecting to a Kafka broker and every request.
+const kafkaTimeout = 10 * time.Second
+
+// kafkaMaxResponseSize caps the size of a broker response, so a broken or
+// malicious broker cannot make the sink allocate arbitrary amounts of memory.
+const kafkaMaxResponseSize = 64 << 20
+
+// API keys and versions of the Kafka requests the sink sends. Produce v3 is the first
+// version with record batches (Kafka 0.11) and the oldest one Kafka 4 still accepts.
+const (
+	kafkaAPIProduce         = 0
+	kafkaAPIProduceVersion  = 3
+	kafkaAPIMetadata        = 3
+	kafkaAPIMetadataVersion = 1
+)
+
+// kafkaCastagnoli is the CRC-32C table record batches are checksummed with.
+var kafkaCastagnoli = crc32.MakeTable(crc32.Castagnoli)
+
+// Kafka produces every price as a JSON message to a partition of a Kafka topic.
+// The message key is the provider of the price. It speaks the Kafka protocol directly:
+// it looks up the leader of the partition with the bootstrap broker, connects to it
+// lazily, and reconnects after errors. Every produce waits for all in-sync replicas
+// (acks=all), so a write only succeeds once the message is committed.
+// Authentication (SASL) is not supported. It is safe for concurrent use.
+type Kafka struct {
+	addr      string
+	topic     string
+	partition int32
+	// tls is the TLS configuration of the connections, nil for plain connections
+	tls *tls.Config
+	// block retries failed produces until they succeed or the context is done.
+	// Otherwise, the price is dropped after one failed attempt.
+	block bool
+
+	mu            sync.Mutex
+	conn          net.Conn
+	correlationID int32
+}
+
+// NewKafka creates a Kafka sink producing to a partition of topic, with addr (host:port)
+// as bootstrap broker. tlsConfig may be nil.
+func NewKafka(addr, topic string, partition int32, tlsConfig *tls.Config, block bool) (*Kafka, error) {
+	if !validTopic(topic) {
+		return nil, fmt.Errorf("invalid Kafka topic %q: expected up to 249 letters, digits, '.', '_', or '-'", topic)
+	}
+	if partition < 0 {
+		return nil, fmt.Errorf("invalid Kafka partition %d", partition)
+	}
+	return &Kafka{addr: addr, topic: topic, partition: partition, tls: tlsConfig, block: block}, nil
+}
+
+// validTopic reports whether topic is a legal Kafka topic name.
+func validTopic(topic string) bool {
+	if topic == "" || topic == "." || topic == ".." || len(topic) > 249 {
+		return false
+	}
+	for _, r := range topic {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '.' || r == '_' || r == '-') {
+			return false
+		}
+	}
+	return true
+}
+
+// parseKafka parses the argument of a kafka sink:
+// <host>:<port>/<topic>[?partition=<n>&on-error=drop|block&tls=true&ca=<path>].
+func parseKafka(arg string) (*Kafka, error) {
+	u, err := url.Parse("kafka://" + arg)
+	if err != nil {
+		return nil, fmt.Errorf("invalid kafka sink: %w", err)
+	}
+	topic := strings.TrimPrefix(u.Path, "/")
+	if u.Host == "" || topic == "" || u.User != nil {
+		return nil, fmt.Errorf("invalid kafka sink %q, expected kafka:<host>:<port>/<topic>", u.Redacted())
+	}
+
+	addr := u.Host
+	if u.Port() == "" {
+		addr = net.JoinHostPort(u.Hostname(), "9092")
+	}
+
+	var partition int32
+	if v := u.Query().Get("partition"); v != "" {
+		p, err := strconv.ParseInt(v, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid kafka sink partition %q: %w", v, err)
+		}
+		partition = int32(p)
+	}
+
+	block, err := parseOnError(u.Query())
+	if err != nil {
+		return nil, fmt.Errorf("invalid kafka sink: %w", err)
+	}
+	tlsConfig, err := parseTLS(u)
+	if err != nil {
+		return nil, fmt.Errorf("invalid kafka sink: %w", err)
+	}
+
+	return NewKafka(addr, topic, partition, tlsConfig, block)
+}
+
+// Write produces the price as a JSON message.
+func (k *Kafka) Write(ctx context.Context, price models.PriceResult) error {
+	msg, err := json.Marshal(price)
+	if err != nil {
+		return fmt.Errorf("encoding price: %w", err)
+	}
+	batch := kafkaRecordBatch([]byte(price.Provider), msg, time.Now())
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if err := publish(ctx, k.block, func() error { return k.produce(batch) }, k.close); err != nil {
+		return fmt.Errorf("producing price to Kafka: %w", err)
+	}
+	return nil
+}
+
+// produce sends the record batch to the leader of the partition, connecting first
+// if needed, and waits for the acknowledgement. The caller must hold the lock.
+func (k *Kafka) produce(batch []byte) error {
+	if k.conn == nil {
+		if err := k.connect(); err != nil {
+			return err
+		}
+	}
+
+	var req kafkaEncoder
+	req.nullableString(nil) // transactional_id
+	req.int16(-1)           // acks: all in-sync replicas
+	req.int32(int32(kafkaTimeout / time.Millisecond))
+	req.int32(1) // topics
+	req.string(k.topic)
+	req.int32(1) // partitions
+	req.int32(k.partition)
+	req.bytes(batch)
+
+	resp, err := k.roundTrip(kafkaAPIProduce, kafkaAPIProduceVersion, req.b)
+	if err != nil {
+		return err
+	}
+
+	for range resp.arrayLen() {
+		resp.string() // topic
+		for range resp.arrayLen() {
+			resp.int32() // partition
+			errorCode := resp.int16()
+			resp.int64() // base offset
+			resp.int64() // log append time
+			if resp.err == nil && errorCode != 0 {
+				return fmt.Errorf("broker error code %d", errorCode)
+			}
+		}
+	}
+	if resp.err != nil {
+		return fmt.Errorf("decoding produce response: %w", resp.err)
+	}
+	return nil
+}
+
+// connect looks up the leader of the partition with the bootstrap broker and
+// connects to it. The caller must hold the lock.
+func (k *Kafka) connect() error {
+	if err := k.dial(k.addr); err != nil {
+		return err
+	}
+
+	leader, err := k.leader()
+	if err != nil {
+		return err
+	}
+	if leader == k.addr {
+		return nil
+	}
+
+	k.close()
+	return k.dial(leader)
+}
+
+// dial connects to the broker at addr. The caller must hold the lock.
+func (k *Kafka) dial(addr string) error {
+	dialer := &net.Dialer{Timeout: kafkaTimeout}
+	var conn net.Conn
+	var err error
+	if k.tls != nil {
+		config := k.tls
+		if host, _, _ := net.SplitHostPort(addr); host != config.ServerName {
+			// The leader may be another host than the bootstrap broker
+			config = config.Clone()
+			config.ServerName = host
+		}
+		conn, err = tls.DialWithDialer(dialer, "tcp", addr, config)
+	} else {
+		conn, err = dialer.Dial("tcp", addr)
+	}
+	if err != nil {
+		return fmt.Errorf("connecting to %s: %w", addr, err)
+	}
+	k.conn = conn
+	return nil
+}
+
+// leader returns the address of the leader of the partition.
+// The caller must hold the lock.
+func (k *Kafka) leader() (string, error) {
+	var req kafkaEncoder
+	req.int32(1) // topics
+	req.string(k.topic)
+
+	resp, err := k.roundTrip(kafkaAPIMetadata, kafkaAPIMetadataVersion, req.b)
+	if err != nil {
+		return "", err
+	}
+
+	brokers := make(map[int32]string)
+	for range resp.arrayLen() {
+		nodeID := resp.int32()
+		host := resp.string()
+		port := resp.int32()
+		resp.nullableString() // rack
+		brokers[nodeID] = net.JoinHostPort(host, strconv.Itoa(int(port)))
+	}
+	resp.int32() // controller ID
+
+	leaderID := int32(-1)
+	for range resp.arrayLen() {
+		topicError := resp.int16()
+		topic := resp.string()
+		resp.bool() // is internal
+		for range resp.arrayLen() {
+			partitionError := resp.int16()
+			partition := resp.int32()
+			leader := resp.int32()
+			for range resp.arrayLen() {
+				resp.int32() // replica
+			}
+			for range resp.arrayLen() {
+				resp.int32() // in-sync replica
+			}
+			if topic == k.topic && partition == k.partition {
+				if partitionError != 0 {
+					return "", fmt.Errorf("partition %d of topic %s: broker error code %d", k.partition, k.topic, partitionError)
+				}
+				leaderID = leader
+			}
+		}
+		if resp.err == nil && topic == k.topic && topicError != 0 {
+			return "", fmt.Errorf("topic %s: broker error code %d", k.topic, topicError)
+		}
+	}
+	if resp.err != nil {
+		return "", fmt.Errorf("decoding metadata response: %w", resp.err)
+	}
+
+	addr, ok := brokers[leaderID]
+	if !ok {
+		return "", fmt.Errorf("no leader for partition %d of topic %s", k.partition, k.topic)
+	}
+	return addr, nil
+}
+
+// roundTrip sends a request and returns the body of its response.
+// The caller must hold the lock.
+func (k *Kafka) roundTrip(apiKey, apiVersion int16, body []byte) (*kafkaDecoder, error) {
+	k.correlationID++
+
+	var req kafkaEncoder
+	req.int32(0) // size, set below
+	req.int16(apiKey)
+	req.int16(apiVersion)
+	req.int32(k.correlationID)
+	clientID := "oilscraper"
+	req.nullableString(&clientID)
+	req.b = append(req.b, body...)
+	binary.BigEndian.PutUint32(req.b, uint32(len(req.b)-4))
+
+	if err := k.conn.SetDeadline(time.Now().Add(kafkaTimeout)); err != nil {
+		return nil, err
+	}
+	if _, err := k.conn.Write(req.b); err != nil {
+		return nil, fmt.Errorf("sending request: %w", err)
+	}
+
+	var size [4]byte
+	if _, err := io.ReadFull(k.conn, size[:]); err != nil {
+		return nil, fmt.Errorf("reading response: %w", err)
+	}
+	n := binary.BigEndian.Uint32(size[:])
+	if n > kafkaMaxResponseSize {
+		return nil, fmt.Errorf("response of %d bytes exceeds the maximum of %d bytes", n, kafkaMaxResponseSize)
+	}
+	resp := make([]byte, n)
+	if _, err := io.ReadFull(k.conn, resp); err != nil {
+		return nil, fmt.Errorf("reading response: %w", err)
+	}
+
+	dec := &kafkaDecoder{b: resp}
+	if id := dec.int32(); dec.err == nil && id != k.correlationID {
+		return nil, fmt.Errorf("response to request %d, expected %d", id, k.correlationID)
+	}
+	return dec, dec.err
+}
+
+// close closes the connection, so the next write reconnects. The caller must hold the lock.
+func (k *Kafka) close() {
+	if k.conn != nil {
+		_ = k.conn.Close()
+		k.conn = nil
+	}
+}
+
+// kafkaRecordBatch encodes a record batch (magic 2) with a single record.
+func kafkaRecordBatch(key, value []byte, timestamp time.Time) []byte {
+	var record []byte
+	record = append(record, 0)              // attributes
+	record = binary.AppendVarint(record, 0) // timestamp delta
+	record = binary.AppendVarint(record, 0) // offset delta
+	record = binary.AppendVarint(record, int64(len(key)))
+	record = append(record, key...)
+	record = binary.AppendVarint(record, int64(len(value)))
+	record = append(record, value...)
+	record = binary.AppendVarint(record, 0) // headers
+
+	// The checksum covers everything from the attributes to the end
+	var body kafkaEncoder
+	body.int16(0) // attributes: no compression, create time
+	body.int32(0) // last offset delta
+	body.int64(timestamp.UnixMilli())
+	body.int64(timestamp.UnixMilli())
+	body.int64(-1) // producer ID
+	body.int16(-1) // producer epoch
+	body.int32(-1) // base sequence
+	body.int32(1)  // records
+	body.b = binary.AppendVarint(body.b, int64(len(record)))
+	body.b = append(body.b, record...)
+
+	var batch kafkaEncoder
+	batch.int64(0)                              // base offset
+	batch.int32(int32(4 + 1 + 4 + len(body.b))) // length after this field
+	batch.int32(-1)                             // partition leader epoch
+	batch.b = append(batch.b, 2)                // magic
+	batch.b = binary.BigEndian.AppendUint32(batch.b, crc32.Checksum(body.b, kafkaCastagnoli))
+	batch.b = append(batch.b, body.b...)
+	return batch.b
+}
+
+// kafkaEncoder appends values in the encoding of the Kafka protocol.
+type kafkaEncoder struct {
+	b []byte
+}
+
+func (e *kafkaEncoder) int16(v int16) { e.b = binary.BigEndian.AppendUint16(e.b, uint16(v)) }
+func (e *kafkaEncoder) int32(v int32) { e.b = binary.BigEndian.AppendUint32(e.b, uint32(v)) }
+func (e *kafkaEncoder) int64(v int64) { e.b = binary.BigEndian.AppendUint64(e.b, uint64(v)) }
+
+func (e *kafkaEncoder) string(s string) {
+	e.int16(int16(len(s)))
+	e.b = append(e.b, s...)
+}
+
+func (e *kafkaEncoder) nullableString(s *string) {
+	if s == nil {
+		e.int16(-1)
+		return
+	}
+	e.string(*s)
+}
+
+func (e *kafkaEncoder) bytes(b []byte) {
+	e.int32(int32(len(b)))
+	e.b = append(e.b, b...)
+}
+
+// errKafkaShortResponse is returned for responses that end before all fields are read.
+var errKafkaShortResponse = errors.New("response too short")
+
+// kafkaDecoder reads values in the encoding of the Kafka protocol. After the first
+// error, all reads return zero values and err is set.
+type kafkaDecoder struct {
+	b   []byte
+	err error
+}
+
+func (d *kafkaDecoder) next(n int) []byte {
+	if d.err != nil {
+		return nil
+	}
+	if n < 0 || len(d.b) < n {
+		d.err = errKafkaShortResponse
+		return nil
+	}
+	v := d.b[:n]
+	d.b = d.b[n:]
+	return v
+}
+
+func (d *kafkaDecoder) int16() int16 {
+	if b := d.next(2); b != nil {
+		return int16(binary.BigEndian.Uint16(b))
+	}
+	return 0
+}
+
+func (d *kafkaDecoder) int32() int32 {
+	if b := d.next(4); b != nil {
+		return int32(binary.BigEndian.Uint32(b))
+	}
+	return 0
+}
+
+func (d *kafkaDecoder) int64() int64 {
+	if b := d.next(8); b != nil {
+		return int64(binary.BigEndian.Uint64(b))
+	}
+	return 0
+}
+
+func (d *kafkaDecoder) bool() bool {
+	if b := d.next(1); b != nil {
+		return b[0] != 0
+	}
+	return false
+}
+
+func (d *kafkaDecoder) string() string {
+	return string(d.next(int(d.int16())))
+}
+
+func (d *kafkaDecoder) nullableString() {
+	if n := d.int16(); n >= 0 {
+		d.next(int(n))
+	}
+}
+
+// arrayLen returns the length of an array. Null arrays have a length of 0.
+func (d *kafkaDecoder) arrayLen() int {
+	n := d.int32()
+	if n < 0 || d.err != nil {
+		return 0
+	}
+	// Every element takes at least one byte
+	if int(n) > len(d.b) {
+		d.err = errKafkaShortResponse
+		return 0
+	}
+	return int(n)
+}
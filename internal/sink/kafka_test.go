@@ -0,0 +1,178 @@
+package sink
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"hash/crc32"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestKafkaRecordBatch(t *testing.T) {
+	// Encoded by hand from the record batch layout of the Kafka protocol (magic 2),
+	// with the CRC-32C computed independently over everything after the checksum.
+	want := "0000000000000000" + // base offset
+		"0000003a" + // length: 58 bytes after this field
+		"ffffffff" + // partition leader epoch
+		"02" + // magic
+		"e99b8dd8" + // CRC-32C
+		"0000" + // attributes
+		"00000000" + // last offset delta
+		"0000018bcfe56800" + // first timestamp
+		"0000018bcfe56800" + // max timestamp
+		"ffffffffffffffff" + // producer ID
+		"ffff" + // producer epoch
+		"ffffffff" + // base sequence
+		"00000001" + // records
+		"10" + // record length: 8 (zigzag varint)
+		"00" + // record attributes
+		"00" + // timestamp delta
+		"00" + // offset delta
+		"026b" + // key "k"
+		"0276" + // value "v"
+		"00" // headers
+
+	got := kafkaRecordBatch([]byte("k"), []byte("v"), time.UnixMilli(1700000000000))
+	if hex.EncodeToString(got) != want {
+		t.Fatalf("kafkaRecordBatch() =\n%x\nwant\n%s", got, want)
+	}
+
+	if length := binary.BigEndian.Uint32(got[8:12]); int(length) != len(got)-12 {
+		t.Errorf("length = %d, want %d", length, len(got)-12)
+	}
+	if crc := binary.BigEndian.Uint32(got[17:21]); crc != crc32.Checksum(got[21:], kafkaCastagnoli) {
+		t.Errorf("CRC = %08x, want %08x", crc, crc32.Checksum(got[21:], kafkaCastagnoli))
+	}
+}
+
+func TestKafkaCastagnoli(t *testing.T) {
+	// The standard check value of CRC-32C
+	if got := crc32.Checksum([]byte("123456789"), kafkaCastagnoli); got != 0xe3069283 {
+		t.Errorf("CRC-32C check value = %08x, want e3069283", got)
+	}
+}
+
+func TestKafkaDecoderTruncated(t *testing.T) {
+	tests := []struct {
+		name string
+		b    string
+		read func(d *kafkaDecoder)
+	}{
+		{name: "int16", b: "00", read: func(d *kafkaDecoder) { d.int16() }},
+		{name: "int32", b: "000000", read: func(d *kafkaDecoder) { d.int32() }},
+		{name: "int64", b: "00000000000000", read: func(d *kafkaDecoder) { d.int64() }},
+		{name: "bool", b: "", read: func(d *kafkaDecoder) { d.bool() }},
+		{name: "string shorter than its length", b: "0005616263", read: func(d *kafkaDecoder) { d.string() }},
+		{name: "negative string length", b: "ffff", read: func(d *kafkaDecoder) { d.string() }},
+		{name: "nullable string shorter than its length", b: "000361", read: func(d *kafkaDecoder) { d.nullableString() }},
+		{name: "array longer than the response", b: "7fffffff00", read: func(d *kafkaDecoder) { d.arrayLen() }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, err := hex.DecodeString(tt.b)
+			if err != nil {
+				t.Fatal(err)
+			}
+			d := &kafkaDecoder{b: b}
+			tt.read(d)
+			if !errors.Is(d.err, errKafkaShortResponse) {
+				t.Fatalf("err = %v, want %v", d.err, errKafkaShortResponse)
+			}
+
+			// All reads after the first error return zero values
+			if v := d.int32(); v != 0 {
+				t.Errorf("int32() after error = %d, want 0", v)
+			}
+			if n := d.arrayLen(); n != 0 {
+				t.Errorf("arrayLen() after error = %d, want 0", n)
+			}
+		})
+	}
+}
+
+func TestKafkaDecoderNullArray(t *testing.T) {
+	d := &kafkaDecoder{b: []byte{0xff, 0xff, 0xff, 0xff}}
+	if n := d.arrayLen(); n != 0 || d.err != nil {
+		t.Errorf("arrayLen() = %d, %v, want 0, nil", n, d.err)
+	}
+}
+
+func TestKafkaRoundTripResponseTooLarge(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+
+	go func() {
+		defer server.Close()
+		// Read the request, then announce a response of 4 GiB
+		var size [4]byte
+		if _, err := io.ReadFull(server, size[:]); err != nil {
+			return
+		}
+		if _, err := io.CopyN(io.Discard, server, int64(binary.BigEndian.Uint32(size[:]))); err != nil {
+			return
+		}
+		_, _ = server.Write([]byte{0xff, 0xff, 0xff, 0xff})
+	}()
+
+	k := &Kafka{conn: client}
+	_, err := k.roundTrip(kafkaAPIMetadata, kafkaAPIMetadataVersion, nil)
+	if err == nil || !strings.Contains(err.Error(), "exceeds the maximum") {
+		t.Fatalf("roundTrip() error = %v, want response size error", err)
+	}
+}
+
+func TestParseKafka(t *testing.T) {
+	tests := []struct {
+		name          string
+		arg           string
+		wantAddr      string
+		wantTopic     string
+		wantPartition int32
+		wantBlock     bool
+		wantTLS       bool
+		wantErr       bool
+	}{
+		{name: "host and port", arg: "broker:9093/prices", wantAddr: "broker:9093", wantTopic: "prices"},
+		{name: "default port", arg: "broker/prices", wantAddr: "broker:9092", wantTopic: "prices"},
+		{name: "IPv6 default port", arg: "[::1]/prices", wantAddr: "[::1]:9092", wantTopic: "prices"},
+		{name: "partition", arg: "broker:9092/prices?partition=3", wantAddr: "broker:9092", wantTopic: "prices", wantPartition: 3},
+		{name: "block", arg: "broker:9092/prices?on-error=block", wantAddr: "broker:9092", wantTopic: "prices", wantBlock: true},
+		{name: "tls", arg: "broker:9092/prices?tls=true", wantAddr: "broker:9092", wantTopic: "prices", wantTLS: true},
+		{name: "missing topic", arg: "broker:9092", wantErr: true},
+		{name: "missing host", arg: "/prices", wantErr: true},
+		{name: "credentials", arg: "user:pass@broker:9092/prices", wantErr: true},
+		{name: "invalid topic", arg: "broker:9092/pri ces", wantErr: true},
+		{name: "topic with slash", arg: "broker:9092/a/b", wantErr: true},
+		{name: "negative partition", arg: "broker:9092/prices?partition=-1", wantErr: true},
+		{name: "invalid partition", arg: "broker:9092/prices?partition=x", wantErr: true},
+		{name: "invalid on-error", arg: "broker:9092/prices?on-error=retry", wantErr: true},
+		{name: "invalid tls", arg: "broker:9092/prices?tls=maybe", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			k, err := parseKafka(tt.arg)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("parseKafka(%q) = %+v, want error", tt.arg, k)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseKafka(%q) returned error: %v", tt.arg, err)
+			}
+			if k.addr != tt.wantAddr || k.topic != tt.wantTopic || k.partition != tt.wantPartition || k.block != tt.wantBlock {
+				t.Errorf("parseKafka(%q) = addr %q, topic %q, partition %d, block %v, want %q, %q, %d, %v",
+					tt.arg, k.addr, k.topic, k.partition, k.block, tt.wantAddr, tt.wantTopic, tt.wantPartition, tt.wantBlock)
+			}
+			if (k.tls != nil) != tt.wantTLS {
+				t.Errorf("parseKafka(%q) TLS = %v, want %v", tt.arg, k.tls != nil, tt.wantTLS)
+			}
+		})
+	}
+}
@@ -0,0 +1,254 @@
+package sink
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/andygrunwald/oil-price-scraper/internal/models"
+)
+
+// natsTimeout bounds connecting to the NATS server and every publish.
+const natsTimeout = 5 * time.Second
+
+// NATSAuth are the credentials a NATS sink authenticates with:
+// either a user and password or a token.
+type NATSAuth struct {
+	User     string
+	Password string
+	Token    string
+}
+
+// NATS publishes every price as a JSON message to a subject of a NATS server.
+// It speaks the NATS client protocol directly and connects lazily, reconnecting
+// after errors. Every publish is confirmed with a PING, so a write only succeeds
+// once the server has received the message. It is safe for concurrent use.
+type NATS struct {
+	addr    string
+	subject string
+	auth    NATSAuth
+	// tls is the TLS configuration of the connection. Without it, TLS is only used
+	// if the server requires it.
+	tls *tls.Config
+	// block retries failed publishes until they succeed or the context is done.
+	// Otherwise, the price is dropped after one failed attempt.
+	block bool
+
+	mu     sync.Mutex
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+// NewNATS creates a NATS sink publishing to subject on the server at addr (host:port).
+// tlsConfig may be nil.
+func NewNATS(addr, subject string, auth NATSAuth, tlsConfig *tls.Config, block bool) (*NATS, error) {
+	if !validSubject(subject) {
+		return nil, fmt.Errorf("invalid NATS subject %q: expected dot-separated tokens without whitespace or wildcards", subject)
+	}
+	return &NATS{addr: addr, subject: subject, auth: auth, tls: tlsConfig, block: block}, nil
+}
+
+// validSubject reports whether prices can be published to subject: dot-separated,
+// non-empty tokens without whitespace. The wildcards * and > are only valid to subscribe.
+func validSubject(subject string) bool {
+	for token := range strings.SplitSeq(subject, ".") {
+		if token == "" || strings.ContainsAny(token, "*> \t\r\n") {
+			return false
+		}
+	}
+	return true
+}
+
+// parseNATS parses the argument of a nats sink:
+// [<user>:<password>@|<token>@]<host>:<port>/<subject>[?on-error=drop|block&tls=true&ca=<path>].
+func parseNATS(arg string) (*NATS, error) {
+	u, err := url.Parse("nats://" + arg)
+	if err != nil {
+		return nil, fmt.Errorf("invalid nats sink: %w", err)
+	}
+	subject := strings.TrimPrefix(u.Path, "/")
+	if u.Host == "" || subject == "" {
+		return nil, fmt.Errorf("invalid nats sink %q, expected nats:<host>:<port>/<subject>", u.Redacted())
+	}
+
+	addr := u.Host
+	if u.Port() == "" {
+		addr = net.JoinHostPort(u.Hostname(), "4222")
+	}
+
+	// As in NATS URLs, a user without password is a token
+	var auth NATSAuth
+	if u.User != nil {
+		if password, ok := u.User.Password(); ok {
+			auth.User = u.User.Username()
+			auth.Password = password
+		} else {
+			auth.Token = u.User.Username()
+		}
+	}
+
+	block, err := parseOnError(u.Query())
+	if err != nil {
+		return nil, fmt.Errorf("invalid nats sink: %w", err)
+	}
+	tlsConfig, err := parseTLS(u)
+	if err != nil {
+		return nil, fmt.Errorf("invalid nats sink: %w", err)
+	}
+
+	return NewNATS(addr, subject, auth, tlsConfig, block)
+}
+
+// Write publishes the price as a JSON message.
+func (n *NATS) Write(ctx context.Context, price models.PriceResult) error {
+	msg, err := json.Marshal(price)
+	if err != nil {
+		return fmt.Errorf("encoding price: %w", err)
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if err := publish(ctx, n.block, func() error { return n.publish(msg) }, n.close); err != nil {
+		return fmt.Errorf("publishing price to NATS: %w", err)
+	}
+	return nil
+}
+
+// publish sends the message, connecting first if needed, and waits for the confirmation.
+// The caller must hold the lock.
+func (n *NATS) publish(msg []byte) error {
+	if n.conn == nil {
+		if err := n.connect(); err != nil {
+			return err
+		}
+	}
+
+	if err := n.conn.SetDeadline(time.Now().Add(natsTimeout)); err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(n.conn, "PUB %s %d\r\n%s\r\nPING\r\n", n.subject, len(msg), msg); err != nil {
+		return fmt.Errorf("sending message: %w", err)
+	}
+	return n.awaitPong()
+}
+
+// connect connects to the server and performs the handshake. The caller must hold the lock.
+func (n *NATS) connect() error {
+	conn, err := net.DialTimeout("tcp", n.addr, natsTimeout)
+	if err != nil {
+		return fmt.Errorf("connecting: %w", err)
+	}
+	return n.handshake(conn)
+}
+
+// handshake reads the INFO of the server on conn, upgrades it to TLS if needed, and
+// authenticates. The caller must hold the lock.
+func (n *NATS) handshake(conn net.Conn) error {
+	n.conn = conn
+	n.reader = bufio.NewReader(conn)
+
+	if err := conn.SetDeadline(time.Now().Add(natsTimeout)); err != nil {
+		return err
+	}
+
+	// The server greets with its INFO
+	line, err := n.reader.ReadString('\n')
+	if err != nil {
+		return fmt.Errorf("reading server info: %w", err)
+	}
+	infoJSON, ok := strings.CutPrefix(line, "INFO ")
+	if !ok {
+		return fmt.Errorf("unexpected server greeting %q", strings.TrimSpace(line))
+	}
+	var info struct {
+		TLSRequired bool `json:"tls_required"`
+	}
+	if err := json.Unmarshal([]byte(infoJSON), &info); err != nil {
+		return fmt.Errorf("decoding server info: %w", err)
+	}
+
+	// The connection is upgraded to TLS after the INFO
+	useTLS := n.tls != nil || info.TLSRequired
+	if useTLS {
+		config := n.tls
+		if config == nil {
+			config = &tls.Config{}
+		}
+		if config.ServerName == "" {
+			config = config.Clone()
+			config.ServerName, _, _ = net.SplitHostPort(n.addr)
+		}
+		tlsConn := tls.Client(conn, config)
+		if err := tlsConn.Handshake(); err != nil {
+			return fmt.Errorf("TLS handshake: %w", err)
+		}
+		n.conn = tlsConn
+		n.reader = bufio.NewReader(tlsConn)
+	}
+
+	connect, err := json.Marshal(struct {
+		Verbose     bool   `json:"verbose"`
+		Pedantic    bool   `json:"pedantic"`
+		TLSRequired bool   `json:"tls_required"`
+		Name        string `json:"name"`
+		Lang        string `json:"lang"`
+		User        string `json:"user,omitempty"`
+		Password    string `json:"pass,omitempty"`
+		Token       string `json:"auth_token,omitempty"`
+	}{
+		TLSRequired: useTLS,
+		Name:        "oilscraper",
+		Lang:        "go",
+		User:        n.auth.User,
+		Password:    n.auth.Password,
+		Token:       n.auth.Token,
+	})
+	if err != nil {
+		return fmt.Errorf("encoding connect: %w", err)
+	}
+	if _, err := fmt.Fprintf(n.conn, "CONNECT %s\r\nPING\r\n", connect); err != nil {
+		return fmt.Errorf("sending connect: %w", err)
+	}
+	return n.awaitPong()
+}
+
+// awaitPong reads protocol messages until the server answers a PING.
+// The caller must hold the lock.
+func (n *NATS) awaitPong() error {
+	for {
+		line, err := n.reader.ReadString('\n')
+		if err != nil {
+			return fmt.Errorf("reading server response: %w", err)
+		}
+		line = strings.TrimSpace(line)
+
+		switch {
+		case line == "PONG":
+			return nil
+		case line == "PING":
+			if _, err := fmt.Fprint(n.conn, "PONG\r\n"); err != nil {
+				return fmt.Errorf("answering server ping: %w", err)
+			}
+		case strings.HasPrefix(line, "-ERR"):
+			return fmt.Errorf("server error: %s", strings.TrimSpace(strings.TrimPrefix(line, "-ERR")))
+		}
+		// +OK and INFO updates need no answer
+	}
+}
+
+// close closes the connection, so the next write reconnects. The caller must hold the lock.
+func (n *NATS) close() {
+	if n.conn != nil {
+		_ = n.conn.Close()
+		n.conn = nil
+		n.reader = nil
+	}
+}
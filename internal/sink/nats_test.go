@@ -0,0 +1,264 @@
+package sink
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/andygrunwald/oil-price-scraper/internal/models"
+)
+
+func TestValidSubject(t *testing.T) {
+	tests := []struct {
+		subject string
+		want    bool
+	}{
+		{subject: "prices", want: true},
+		{subject: "oil.prices.de", want: true},
+		{subject: "oil-prices_1", want: true},
+		{subject: "", want: false},
+		{subject: "oil..prices", want: false},
+		{subject: ".prices", want: false},
+		{subject: "prices.", want: false},
+		{subject: "oil.*", want: false},
+		{subject: "oil.>", want: false},
+		{subject: "oil prices", want: false},
+		{subject: "oil\tprices", want: false},
+		{subject: "oil\r\nPUB", want: false},
+	}
+
+	for _, tt := range tests {
+		if got := validSubject(tt.subject); got != tt.want {
+			t.Errorf("validSubject(%q) = %v, want %v", tt.subject, got, tt.want)
+		}
+	}
+}
+
+func TestParseNATS(t *testing.T) {
+	tests := []struct {
+		name        string
+		arg         string
+		wantAddr    string
+		wantSubject string
+		wantAuth    NATSAuth
+		wantBlock   bool
+		wantTLS     bool
+		wantErr     bool
+	}{
+		{name: "host and port", arg: "nats:4223/oil.prices", wantAddr: "nats:4223", wantSubject: "oil.prices"},
+		{name: "default port", arg: "nats/oil.prices", wantAddr: "nats:4222", wantSubject: "oil.prices"},
+		{name: "user and password", arg: "user:secret@nats:4222/prices", wantAddr: "nats:4222", wantSubject: "prices", wantAuth: NATSAuth{User: "user", Password: "secret"}},
+		{name: "token", arg: "s3cr3t@nats:4222/prices", wantAddr: "nats:4222", wantSubject: "prices", wantAuth: NATSAuth{Token: "s3cr3t"}},
+		{name: "block", arg: "nats:4222/prices?on-error=block", wantAddr: "nats:4222", wantSubject: "prices", wantBlock: true},
+		{name: "tls", arg: "nats:4222/prices?tls=true", wantAddr: "nats:4222", wantSubject: "prices", wantTLS: true},
+		{name: "missing subject", arg: "nats:4222", wantErr: true},
+		{name: "missing host", arg: "/prices", wantErr: true},
+		{name: "wildcard subject", arg: "nats:4222/oil.*", wantErr: true},
+		{name: "invalid on-error", arg: "nats:4222/prices?on-error=retry", wantErr: true},
+		{name: "missing CA file", arg: "nats:4222/prices?ca=/nonexistent/ca.pem", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n, err := parseNATS(tt.arg)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("parseNATS(%q) = %+v, want error", tt.arg, n)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseNATS(%q) returned error: %v", tt.arg, err)
+			}
+			if n.addr != tt.wantAddr || n.subject != tt.wantSubject || n.auth != tt.wantAuth || n.block != tt.wantBlock {
+				t.Errorf("parseNATS(%q) = addr %q, subject %q, auth %+v, block %v, want %q, %q, %+v, %v",
+					tt.arg, n.addr, n.subject, n.auth, n.block, tt.wantAddr, tt.wantSubject, tt.wantAuth, tt.wantBlock)
+			}
+			if (n.tls != nil) != tt.wantTLS {
+				t.Errorf("parseNATS(%q) TLS = %v, want %v", tt.arg, n.tls != nil, tt.wantTLS)
+			}
+		})
+	}
+}
+
+// fakeNATSServer runs script as the server side of a connection over net.Pipe
+// and returns the client side. The error of script is sent to the returned channel.
+func fakeNATSServer(t *testing.T, script func(r *bufio.Reader, w io.Writer) error) (net.Conn, <-chan error) {
+	t.Helper()
+	client, server := net.Pipe()
+	t.Cleanup(func() { _ = client.Close() })
+
+	done := make(chan error, 1)
+	go func() {
+		defer server.Close()
+		done <- script(bufio.NewReader(server), server)
+	}()
+	return client, done
+}
+
+// expectLine reads a line from r and checks that it starts with prefix.
+func expectLine(r *bufio.Reader, prefix string) (string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return "", fmt.Errorf("reading %s: %w", prefix, err)
+	}
+	line = strings.TrimSuffix(line, "\r\n")
+	if !strings.HasPrefix(line, prefix) {
+		return "", fmt.Errorf("got %q, want %s", line, prefix)
+	}
+	return line, nil
+}
+
+func TestNATSPublish(t *testing.T) {
+	price := models.PriceResult{Provider: "heizoel24", PricePer100L: 97.81}
+	want, err := json.Marshal(price)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	conn, done := fakeNATSServer(t, func(r *bufio.Reader, w io.Writer) error {
+		if _, err := io.WriteString(w, "INFO {\"server_id\":\"test\",\"tls_required\":false}\r\n"); err != nil {
+			return err
+		}
+
+		line, err := expectLine(r, "CONNECT ")
+		if err != nil {
+			return err
+		}
+		var connect struct {
+			User     string `json:"user"`
+			Password string `json:"pass"`
+			Name     string `json:"name"`
+		}
+		if err := json.Unmarshal([]byte(strings.TrimPrefix(line, "CONNECT ")), &connect); err != nil {
+			return fmt.Errorf("decoding CONNECT: %w", err)
+		}
+		if connect.User != "user" || connect.Password != "secret" || connect.Name != "oilscraper" {
+			return fmt.Errorf("CONNECT = %+v, want user, secret, oilscraper", connect)
+		}
+		if _, err := expectLine(r, "PING"); err != nil {
+			return err
+		}
+
+		// The client must answer pings of the server while waiting for its PONG
+		if _, err := io.WriteString(w, "+OK\r\nPING\r\n"); err != nil {
+			return err
+		}
+		if _, err := expectLine(r, "PONG"); err != nil {
+			return err
+		}
+		if _, err := io.WriteString(w, "PONG\r\n"); err != nil {
+			return err
+		}
+
+		if line, err = expectLine(r, "PUB "); err != nil {
+			return err
+		}
+		if wantLine := fmt.Sprintf("PUB oil.prices %d", len(want)); line != wantLine {
+			return fmt.Errorf("got %q, want %q", line, wantLine)
+		}
+		payload, err := expectLine(r, "")
+		if err != nil {
+			return err
+		}
+		if payload != string(want) {
+			return fmt.Errorf("payload = %s, want %s", payload, want)
+		}
+		if _, err := expectLine(r, "PING"); err != nil {
+			return err
+		}
+		_, err = io.WriteString(w, "PONG\r\n")
+		return err
+	})
+
+	n, err := NewNATS("nats:4222", "oil.prices", NATSAuth{User: "user", Password: "secret"}, nil, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	n.mu.Lock()
+	err = n.handshake(conn)
+	n.mu.Unlock()
+	if err != nil {
+		t.Fatalf("handshake() returned error: %v", err)
+	}
+	if err := n.Write(context.Background(), price); err != nil {
+		t.Fatalf("Write() returned error: %v", err)
+	}
+	if err := <-done; err != nil {
+		t.Fatalf("server: %v", err)
+	}
+}
+
+func TestNATSHandshakeErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		script  func(r *bufio.Reader, w io.Writer) error
+		wantErr string
+	}{
+		{
+			name: "server error",
+			script: func(r *bufio.Reader, w io.Writer) error {
+				if _, err := io.WriteString(w, "INFO {}\r\n"); err != nil {
+					return err
+				}
+				if _, err := expectLine(r, "CONNECT "); err != nil {
+					return err
+				}
+				if _, err := expectLine(r, "PING"); err != nil {
+					return err
+				}
+				_, err := io.WriteString(w, "-ERR 'Authorization Violation'\r\n")
+				return err
+			},
+			wantErr: "server error: 'Authorization Violation'",
+		},
+		{
+			name: "unexpected greeting",
+			script: func(r *bufio.Reader, w io.Writer) error {
+				_, err := io.WriteString(w, "HELLO\r\n")
+				return err
+			},
+			wantErr: `unexpected server greeting "HELLO"`,
+		},
+		{
+			name: "invalid info",
+			script: func(r *bufio.Reader, w io.Writer) error {
+				_, err := io.WriteString(w, "INFO {\r\n")
+				return err
+			},
+			wantErr: "decoding server info",
+		},
+		{
+			name: "connection closed",
+			script: func(r *bufio.Reader, w io.Writer) error {
+				return nil
+			},
+			wantErr: "reading server info",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn, done := fakeNATSServer(t, tt.script)
+
+			n, err := NewNATS("nats:4222", "prices", NATSAuth{}, nil, false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			n.mu.Lock()
+			err = n.handshake(conn)
+			n.mu.Unlock()
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("handshake() error = %v, want %q", err, tt.wantErr)
+			}
+			if err := <-done; err != nil {
+				t.Fatalf("server: %v", err)
+			}
+		})
+	}
+}
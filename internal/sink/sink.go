@@ -0,0 +1,188 @@
+// Package sink provides outputs scraped prices are written to in addition to the database.
+package sink
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/andygrunwald/oil-price-scraper/internal/models"
+)
+
+// Sink receives every scraped price.
+type Sink interface {
+	// Write writes a single price.
+	Write(ctx context.Context, price models.PriceResult) error
+}
+
+// JSONLines writes prices as one JSON object per line.
+// It is safe for concurrent use.
+type JSONLines struct {
+	w  io.Writer
+	mu sync.Mutex
+}
+
+// NewJSONLines creates a JSONLines sink writing to w.
+func NewJSONLines(w io.Writer) *JSONLines {
+	return &JSONLines{w: w}
+}
+
+// Write writes the price as a single line, so readers never see partial lines of concurrent writes.
+func (j *JSONLines) Write(ctx context.Context, price models.PriceResult) error {
+	line, err := json.Marshal(price)
+	if err != nil {
+		return fmt.Errorf("encoding price: %w", err)
+	}
+	line = append(line, '\n')
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if _, err := j.w.Write(line); err != nil {
+		return fmt.Errorf("writing price: %w", err)
+	}
+	return nil
+}
+
+// Parse creates a sink from its specification:
+//
+//	stdout         JSON lines to stdout
+//	jsonl:<path>   JSON lines appended to a file (created if missing)
+//	nats:[<user>:<password>@|<token>@]<host>:<port>/<subject>[?on-error=drop|block&tls=true&ca=<path>]
+//	               JSON messages published to a NATS subject (see NATS)
+//	kafka:<host>:<port>/<topic>[?partition=<n>&on-error=drop|block&tls=true&ca=<path>]
+//	               JSON messages produced to a partition of a Kafka topic (see Kafka)
+//
+// Files are written unbuffered and stay open for the lifetime of the process.
+func Parse(spec string) (Sink, error) {
+	name, arg, _ := strings.Cut(strings.TrimSpace(spec), ":")
+
+	switch name {
+	case "stdout":
+		return NewJSONLines(os.Stdout), nil
+
+	case "jsonl":
+		if arg == "" {
+			return nil, fmt.Errorf("invalid sink %q, expected jsonl:<path>", spec)
+		}
+		f, err := os.OpenFile(arg, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			return nil, fmt.Errorf("opening sink file: %w", err)
+		}
+		return NewJSONLines(f), nil
+
+	case "nats":
+		return parseNATS(arg)
+
+	case "kafka":
+		return parseKafka(arg)
+
+	default:
+		return nil, fmt.Errorf("unknown sink %q (available: stdout, jsonl:<path>, nats:<host>:<port>/<subject>, kafka:<host>:<port>/<topic>)", name)
+	}
+}
+
+// ParseAll creates the sinks of all specifications, skipping empty ones.
+func ParseAll(specs []string) ([]Sink, error) {
+	sinks := make([]Sink, 0, len(specs))
+	for _, spec := range specs {
+		if strings.TrimSpace(spec) == "" {
+			continue
+		}
+		s, err := Parse(spec)
+		if err != nil {
+			return nil, err
+		}
+		sinks = append(sinks, s)
+	}
+	return sinks, nil
+}
+
+// WritesToStdout reports whether any of the specifications writes to stdout.
+func WritesToStdout(specs []string) bool {
+	for _, spec := range specs {
+		if strings.TrimSpace(spec) == "stdout" {
+			return true
+		}
+	}
+	return false
+}
+
+// publishMaxBackoff caps the delay between publish attempts of a blocking sink.
+const publishMaxBackoff = 30 * time.Second
+
+// publish runs send until it succeeds and runs reset after every failure (e.g., to
+// reconnect). Without block, it gives up after the first failure. With block, it
+// retries with increasing delay until the context is done.
+func publish(ctx context.Context, block bool, send func() error, reset func()) error {
+	backoff := time.Second
+	for {
+		err := send()
+		if err == nil {
+			return nil
+		}
+		reset()
+		if !block {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("%w (gave up: %w)", err, ctx.Err())
+		case <-time.After(backoff):
+		}
+		backoff = min(2*backoff, publishMaxBackoff)
+	}
+}
+
+// parseOnError parses the on-error parameter of a sink: drop (default) or block.
+// It returns true for block.
+func parseOnError(query url.Values) (bool, error) {
+	switch onError := query.Get("on-error"); onError {
+	case "", "drop":
+		return false, nil
+	case "block":
+		return true, nil
+	default:
+		return false, fmt.Errorf("on-error %q, expected drop or block", onError)
+	}
+}
+
+// parseTLS parses the TLS parameters of a sink: tls=true enables TLS, ca=<path>
+// additionally trusts the PEM certificates of the file. It returns nil without TLS.
+func parseTLS(u *url.URL) (*tls.Config, error) {
+	query := u.Query()
+	enabled := false
+	if v := query.Get("tls"); v != "" {
+		var err error
+		if enabled, err = strconv.ParseBool(v); err != nil {
+			return nil, fmt.Errorf("tls %q, expected true or false", v)
+		}
+	}
+	caFile := query.Get("ca")
+	if !enabled && caFile == "" {
+		return nil, nil
+	}
+
+	config := &tls.Config{ServerName: u.Hostname()}
+	if caFile != "" {
+		pem, err := os.ReadFile(caFile)
+		if err != nil {
+			return nil, fmt.Errorf("reading CA file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in CA file %s", caFile)
+		}
+		config.RootCAs = pool
+	}
+	return config, nil
+}
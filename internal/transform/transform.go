@@ -0,0 +1,234 @@
+// Package transform provides transformations applied to prices before they are stored.
+package transform
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/andygrunwald/oil-price-scraper/internal/models"
+)
+
+// Transformer transforms a single price before it is stored.
+type Transformer interface {
+	// Name returns the transformer identifier used in logs.
+	Name() string
+
+	// Transform returns the transformed price. If keep is false, the price is dropped.
+	Transform(price models.PriceResult) (result models.PriceResult, keep bool, err error)
+}
+
+// Pipeline is a chain of transformers applied in order.
+type Pipeline []Transformer
+
+// Dropped describes a price removed by a transformer.
+type Dropped struct {
+	Price       models.PriceResult
+	Transformer string
+	Err         error
+}
+
+// Apply runs every price through all transformers in order. Prices dropped or
+// failing in a transformer are not passed to later transformers and are returned
+// separately.
+func (p Pipeline) Apply(prices []models.PriceResult) ([]models.PriceResult, []Dropped) {
+	if len(p) == 0 {
+		return prices, nil
+	}
+
+	kept := make([]models.PriceResult, 0, len(prices))
+	var dropped []Dropped
+
+prices:
+	for _, price := range prices {
+		for _, t := range p {
+			result, keep, err := t.Transform(price)
+			if err != nil || !keep {
+				dropped = append(dropped, Dropped{Price: price, Transformer: t.Name(), Err: err})
+				continue prices
+			}
+			price = result
+		}
+		kept = append(kept, price)
+	}
+
+	return kept, dropped
+}
+
+// Round rounds prices to a number of decimal places.
+type Round struct {
+	Decimals int
+}
+
+// Name returns the transformer identifier.
+func (r Round) Name() string {
+	return "round"
+}
+
+// Transform rounds the price.
+func (r Round) Transform(price models.PriceResult) (models.PriceResult, bool, error) {
+	factor := math.Pow(10, float64(r.Decimals))
+	price.PricePer100L = math.Round(price.PricePer100L*factor) / factor
+	return price, true, nil
+}
+
+// Convert converts prices from one currency to another with a fixed rate.
+// Prices in other currencies are passed through unchanged.
+type Convert struct {
+	From string
+	To   string
+	Rate float64
+}
+
+// Name returns the transformer identifier.
+func (c Convert) Name() string {
+	return "convert"
+}
+
+// Transform converts the price if it is in the source currency.
+func (c Convert) Transform(price models.PriceResult) (models.PriceResult, bool, error) {
+	if !strings.EqualFold(price.Currency, c.From) {
+		return price, true, nil
+	}
+	price.PricePer100L *= c.Rate
+	price.Currency = c.To
+	return price, true, nil
+}
+
+// Scale multiplies prices by a factor (e.g., to normalize a price per liter to per 100 liters).
+type Scale struct {
+	Factor float64
+}
+
+// Name returns the transformer identifier.
+func (s Scale) Name() string {
+	return "scale"
+}
+
+// Transform scales the price.
+func (s Scale) Transform(price models.PriceResult) (models.PriceResult, bool, error) {
+	price.PricePer100L *= s.Factor
+	return price, true, nil
+}
+
+// Bounds drops prices outside of a plausible range (anomaly rejection).
+type Bounds struct {
+	Min float64
+	Max float64
+}
+
+// Name returns the transformer identifier.
+func (b Bounds) Name() string {
+	return "bounds"
+}
+
+// Transform drops the price if it is outside of Min and Max.
+func (b Bounds) Transform(price models.PriceResult) (models.PriceResult, bool, error) {
+	if price.PricePer100L < b.Min || price.PricePer100L > b.Max {
+		return price, false, fmt.Errorf("price %.4f outside of %.4f-%.4f", price.PricePer100L, b.Min, b.Max)
+	}
+	return price, true, nil
+}
+
+// ProductTypes drops prices of product types that are not allowed for their provider.
+// Providers without an entry keep all product types.
+type ProductTypes struct {
+	// Allowed maps provider names to the allowed (normalized) product types.
+	Allowed map[string][]string
+}
+
+// Name returns the transformer identifier.
+func (f ProductTypes) Name() string {
+	return "product-types"
+}
+
+// Transform drops the price if its product type is not allowed.
+func (f ProductTypes) Transform(price models.PriceResult) (models.PriceResult, bool, error) {
+	allowed, ok := f.Allowed[price.Provider]
+	if !ok || len(allowed) == 0 {
+		return price, true, nil
+	}
+	for _, productType := range allowed {
+		if strings.EqualFold(productType, price.ProductType) {
+			return price, true, nil
+		}
+	}
+	return price, false, nil
+}
+
+// Parse creates a transformer from a specification:
+//
+//	round:<decimals>            e.g. round:2
+//	convert:<from>:<to>:<rate>  e.g. convert:CHF:EUR:1.05
+//	scale:<factor>              e.g. scale:100
+//	bounds:<min>:<max>          e.g. bounds:20:300
+func Parse(spec string) (Transformer, error) {
+	parts := strings.Split(strings.TrimSpace(spec), ":")
+	name, args := parts[0], parts[1:]
+
+	switch name {
+	case "round":
+		if len(args) != 1 {
+			return nil, fmt.Errorf("invalid transformer %q, expected round:<decimals>", spec)
+		}
+		decimals, err := strconv.Atoi(args[0])
+		if err != nil || decimals < 0 {
+			return nil, fmt.Errorf("invalid transformer %q, decimals must be a non-negative integer", spec)
+		}
+		return Round{Decimals: decimals}, nil
+
+	case "convert":
+		if len(args) != 3 {
+			return nil, fmt.Errorf("invalid transformer %q, expected convert:<from>:<to>:<rate>", spec)
+		}
+		rate, err := strconv.ParseFloat(args[2], 64)
+		if err != nil || rate <= 0 {
+			return nil, fmt.Errorf("invalid transformer %q, rate must be a positive number", spec)
+		}
+		return Convert{From: strings.ToUpper(args[0]), To: strings.ToUpper(args[1]), Rate: rate}, nil
+
+	case "scale":
+		if len(args) != 1 {
+			return nil, fmt.Errorf("invalid transformer %q, expected scale:<factor>", spec)
+		}
+		factor, err := strconv.ParseFloat(args[0], 64)
+		if err != nil || factor <= 0 {
+			return nil, fmt.Errorf("invalid transformer %q, factor must be a positive number", spec)
+		}
+		return Scale{Factor: factor}, nil
+
+	case "bounds":
+		if len(args) != 2 {
+			return nil, fmt.Errorf("invalid transformer %q, expected bounds:<min>:<max>", spec)
+		}
+		minPrice, err := strconv.ParseFloat(args[0], 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid transformer %q, min must be a number", spec)
+		}
+		maxPrice, err := strconv.ParseFloat(args[1], 64)
+		if err != nil || maxPrice < minPrice {
+			return nil, fmt.Errorf("invalid transformer %q, max must be a number not below min", spec)
+		}
+		return Bounds{Min: minPrice, Max: maxPrice}, nil
+
+	default:
+		return nil, fmt.Errorf("unknown transformer %q", name)
+	}
+}
+
+// ParsePipeline creates a pipeline from transformer specifications (see Parse), in order.
+func ParsePipeline(specs []string) (Pipeline, error) {
+	pipeline := make(Pipeline, 0, len(specs))
+	for _, spec := range specs {
+		if strings.TrimSpace(spec) == "" {
+			continue
+		}
+		t, err := Parse(spec)
+		if err != nil {
+			return nil, err
+		}
+		pipeline = append(pipeline, t)
+	}
+	return pipeline, nil
+}
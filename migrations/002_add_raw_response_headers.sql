@@ -0,0 +1,6 @@
+-- Oil Price Scraper - Raw Response Headers
+-- Stores a snapshot of the HTTP status and response headers next to the raw response
+
+ALTER TABLE oil_prices ADD COLUMN IF NOT EXISTS raw_response_headers JSONB DEFAULT NULL;
+
+COMMENT ON COLUMN oil_prices.raw_response_headers IS 'HTTP status and response headers of the API call';
@@ -0,0 +1,13 @@
+-- Oil Price Scraper - Region
+-- Stores the country/region a price is valid for and makes it part of the unique series key
+
+ALTER TABLE oil_prices ADD COLUMN IF NOT EXISTS region VARCHAR(10) NOT NULL DEFAULT 'DE';
+
+ALTER TABLE oil_prices DROP CONSTRAINT IF EXISTS unique_provider_product_date;
+ALTER TABLE oil_prices DROP CONSTRAINT IF EXISTS unique_provider_product_date_region;
+ALTER TABLE oil_prices ADD CONSTRAINT unique_provider_product_date_region
+    UNIQUE NULLS NOT DISTINCT (provider, product_type, price_date, zip_code, region);
+
+CREATE INDEX IF NOT EXISTS idx_region ON oil_prices (region);
+
+COMMENT ON COLUMN oil_prices.region IS 'Country/region the price is valid for (e.g., DE, AT, CH)';
@@ -0,0 +1,6 @@
+-- Oil Price Scraper - Source URL
+-- Stores the request URL each price was fetched from for provenance
+
+ALTER TABLE oil_prices ADD COLUMN IF NOT EXISTS source_url TEXT DEFAULT NULL;
+
+COMMENT ON COLUMN oil_prices.source_url IS 'Request URL the price was fetched from';
@@ -0,0 +1,6 @@
+-- Oil Price Scraper - Compressed Raw Responses
+-- Stores raw responses gzip-compressed if enabled (--compress-raw-response)
+
+ALTER TABLE oil_prices ADD COLUMN IF NOT EXISTS raw_response_gzip BYTEA DEFAULT NULL;
+
+COMMENT ON COLUMN oil_prices.raw_response_gzip IS 'Gzip-compressed raw API response (alternative to raw_response)';
@@ -0,0 +1,15 @@
+-- Oil Price Scraper - Latest Price Indexes
+-- Composite indexes for latest-per-provider lookups and date range queries
+
+-- GetLatestPrice (WHERE provider = $1 ORDER BY price_date DESC, created_at DESC LIMIT 1)
+-- and range queries of a provider (WHERE provider = $1 AND price_date BETWEEN ...).
+-- This reads the first index entry instead of sorting the provider's whole history.
+CREATE INDEX IF NOT EXISTS idx_provider_date_created
+    ON oil_prices (provider, price_date DESC, created_at DESC);
+
+-- GetLatestPrices (DISTINCT ON the series key ORDER BY price_date DESC, created_at DESC)
+CREATE INDEX IF NOT EXISTS idx_series_latest
+    ON oil_prices (provider, product_type, zip_code, region, price_date DESC, created_at DESC);
+
+-- Superseded by idx_provider_date_created, which has provider as leading column
+DROP INDEX IF EXISTS idx_provider;
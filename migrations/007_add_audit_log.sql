@@ -0,0 +1,26 @@
+-- Oil Price Scraper - Audit Log
+-- Append-only history of data changes, written if enabled (--audit)
+
+CREATE TABLE IF NOT EXISTS audit_log (
+    id              BIGSERIAL PRIMARY KEY,
+    price_id        BIGINT DEFAULT NULL,
+    provider        VARCHAR(50) NOT NULL,
+    product_type    VARCHAR(50) NOT NULL,
+    price_date      DATE NOT NULL,
+    zip_code        VARCHAR(10) DEFAULT NULL,
+    region          VARCHAR(10) NOT NULL,
+    action          VARCHAR(10) NOT NULL CHECK (action IN ('insert', 'update', 'skip')),
+    old_price       DECIMAL(10, 4) DEFAULT NULL,
+    new_price       DECIMAL(10, 4) DEFAULT NULL,
+    recorded_at     TIMESTAMP NOT NULL,
+    prev_hash       CHAR(64) NOT NULL,
+    hash            CHAR(64) NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_log_price ON audit_log (provider, price_date);
+
+COMMENT ON TABLE audit_log IS 'Append-only audit log of price inserts, updates, and skips';
+COMMENT ON COLUMN audit_log.price_id IS 'Affected oil_prices record (NULL for skips)';
+COMMENT ON COLUMN audit_log.action IS 'insert, update (old_price -> new_price), or skip (price already stored)';
+COMMENT ON COLUMN audit_log.prev_hash IS 'Hash of the previous entry (empty hash for the first entry)';
+COMMENT ON COLUMN audit_log.hash IS 'SHA-256 over prev_hash and the entry; modified or removed entries break the chain';
@@ -0,0 +1,8 @@
+-- Oil Price Scraper - Label
+-- Free-form note tagging prices of manual runs (e.g., "source=manual-import-2024")
+
+ALTER TABLE oil_prices ADD COLUMN IF NOT EXISTS label VARCHAR(100) DEFAULT NULL;
+
+CREATE INDEX IF NOT EXISTS idx_label ON oil_prices (label) WHERE label IS NOT NULL;
+
+COMMENT ON COLUMN oil_prices.label IS 'Free-form label set with --label (NULL for unlabeled prices)';
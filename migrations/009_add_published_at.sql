@@ -0,0 +1,6 @@
+-- Oil Price Scraper - Published At
+-- When the provider published or last updated a price, as reported by the provider (e.g., HeizOel24 data point timestamps)
+
+ALTER TABLE oil_prices ADD COLUMN IF NOT EXISTS published_at TIMESTAMP DEFAULT NULL;
+
+COMMENT ON COLUMN oil_prices.published_at IS 'Provider-reported publication timestamp (NULL if the provider does not report one)';
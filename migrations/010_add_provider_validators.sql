@@ -0,0 +1,15 @@
+-- Oil Price Scraper - Provider Validators
+-- ETag/Last-Modified of the last provider response, used for conditional requests (--conditional-requests)
+
+CREATE TABLE IF NOT EXISTS provider_validators (
+    provider        VARCHAR(50) PRIMARY KEY,
+    url             TEXT NOT NULL,
+    etag            TEXT DEFAULT NULL,
+    last_modified   TEXT DEFAULT NULL,
+    saved_at        TIMESTAMP NOT NULL
+);
+
+COMMENT ON TABLE provider_validators IS 'Cache validators of the last response per provider';
+COMMENT ON COLUMN provider_validators.url IS 'Request URL the validators belong to';
+COMMENT ON COLUMN provider_validators.etag IS 'ETag header sent back as If-None-Match';
+COMMENT ON COLUMN provider_validators.last_modified IS 'Last-Modified header sent back as If-Modified-Since';
@@ -0,0 +1,16 @@
+-- Oil Price Scraper - Provider Counters
+-- Lifetime request and error counters per provider, so /status totals survive restarts
+
+CREATE TABLE IF NOT EXISTS provider_counters (
+    provider            VARCHAR(50) PRIMARY KEY,
+    total_requests      BIGINT NOT NULL DEFAULT 0,
+    total_errors        BIGINT NOT NULL DEFAULT 0,
+    transport_errors    BIGINT NOT NULL DEFAULT 0,
+    timeout_errors      BIGINT NOT NULL DEFAULT 0,
+    http_status_errors  BIGINT NOT NULL DEFAULT 0,
+    rate_limit_errors   BIGINT NOT NULL DEFAULT 0,
+    parse_errors        BIGINT NOT NULL DEFAULT 0,
+    updated_at          TIMESTAMP NOT NULL
+);
+
+COMMENT ON TABLE provider_counters IS 'Lifetime request and error counters per provider';
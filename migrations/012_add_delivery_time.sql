@@ -0,0 +1,8 @@
+-- Oil Price Scraper - Delivery Time
+-- Delivery time of the product a price is for, as reported by the provider (e.g., Hoyer's days and deliveryTimeType)
+
+ALTER TABLE oil_prices ADD COLUMN IF NOT EXISTS delivery_days INTEGER DEFAULT NULL;
+ALTER TABLE oil_prices ADD COLUMN IF NOT EXISTS delivery_time_type VARCHAR(50) DEFAULT NULL;
+
+COMMENT ON COLUMN oil_prices.delivery_days IS 'Provider-reported delivery time in days (NULL if the provider does not report one)';
+COMMENT ON COLUMN oil_prices.delivery_time_type IS 'Provider-reported kind of delivery time (e.g., workdays)';
@@ -0,0 +1,8 @@
+-- Oil Price Scraper - Product Flags
+-- Product properties as flagged by the provider (e.g., Hoyer's isPremium and isClimateNeutral)
+
+ALTER TABLE oil_prices ADD COLUMN IF NOT EXISTS is_premium BOOLEAN DEFAULT NULL;
+ALTER TABLE oil_prices ADD COLUMN IF NOT EXISTS is_climate_neutral BOOLEAN DEFAULT NULL;
+
+COMMENT ON COLUMN oil_prices.is_premium IS 'Provider-reported premium flag of the product (NULL if the provider does not report one)';
+COMMENT ON COLUMN oil_prices.is_climate_neutral IS 'Provider-reported climate-neutral flag of the product (NULL if the provider does not report one)';
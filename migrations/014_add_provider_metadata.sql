@@ -0,0 +1,14 @@
+-- Oil Price Scraper - Provider Metadata
+-- Metadata providers report about their prices (e.g., HeizOel24's ChartUnit), to detect upstream changes
+
+CREATE TABLE IF NOT EXISTS provider_metadata (
+    provider        VARCHAR(50) PRIMARY KEY,
+    unit            TEXT DEFAULT NULL,
+    product_name    TEXT DEFAULT NULL,
+    chart_name      TEXT DEFAULT NULL,
+    currency        TEXT DEFAULT NULL,
+    changed_at      TIMESTAMP NOT NULL
+);
+
+COMMENT ON TABLE provider_metadata IS 'Last metadata reported by each provider about its prices';
+COMMENT ON COLUMN provider_metadata.changed_at IS 'When the provider first reported these values';
@@ -0,0 +1,20 @@
+-- Oil Price Scraper - Failed Inserts
+-- Dead-letter table of prices whose insert failed (--dead-letter), retried with oilscraper retry-failed
+
+CREATE TABLE IF NOT EXISTS failed_inserts (
+    id                  BIGSERIAL PRIMARY KEY,
+    provider            VARCHAR(50) NOT NULL,
+    price               JSONB NOT NULL,
+    raw_response        BYTEA DEFAULT NULL,
+    response_snapshot   JSONB DEFAULT NULL,
+    error               TEXT NOT NULL,
+    attempts            INTEGER NOT NULL DEFAULT 0,
+    failed_at           TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_failed_inserts_provider ON failed_inserts (provider, id);
+
+COMMENT ON TABLE failed_inserts IS 'Prices whose insert failed, kept until retry-failed stores them';
+COMMENT ON COLUMN failed_inserts.price IS 'The price as JSON (models.PriceResult)';
+COMMENT ON COLUMN failed_inserts.error IS 'Error of the last failed insert';
+COMMENT ON COLUMN failed_inserts.attempts IS 'Number of failed retries';
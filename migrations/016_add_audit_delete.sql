@@ -0,0 +1,9 @@
+-- Oil Price Scraper - Audit Log Deletes
+-- Records deleted price records (delete command, retention, verify --fix) in the audit log
+
+ALTER TABLE audit_log DROP CONSTRAINT IF EXISTS audit_log_action_check;
+ALTER TABLE audit_log ADD CONSTRAINT audit_log_action_check CHECK (action IN ('insert', 'update', 'skip', 'delete'));
+
+COMMENT ON TABLE audit_log IS 'Append-only audit log of price inserts, updates, skips, and deletes';
+COMMENT ON COLUMN audit_log.price_id IS 'Affected oil_prices record (NULL for skips)';
+COMMENT ON COLUMN audit_log.action IS 'insert, update (old_price -> new_price), skip (price already stored), or delete (old_price)';
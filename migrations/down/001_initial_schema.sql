@@ -0,0 +1,4 @@
+-- Oil Price Scraper - Initial Schema (down)
+-- Drops the oil_prices table including all stored prices
+
+DROP TABLE IF EXISTS oil_prices;
@@ -0,0 +1,3 @@
+-- Oil Price Scraper - Raw Response Headers (down)
+
+ALTER TABLE oil_prices DROP COLUMN IF EXISTS raw_response_headers;
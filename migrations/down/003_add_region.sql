@@ -0,0 +1,8 @@
+-- Oil Price Scraper - Region (down)
+-- Fails if prices of the same series are stored for several regions, as they would violate the previous constraint
+
+ALTER TABLE oil_prices DROP CONSTRAINT IF EXISTS unique_provider_product_date_region;
+DROP INDEX IF EXISTS idx_region;
+ALTER TABLE oil_prices DROP COLUMN IF EXISTS region;
+ALTER TABLE oil_prices ADD CONSTRAINT unique_provider_product_date
+    UNIQUE NULLS NOT DISTINCT (provider, product_type, price_date, zip_code);
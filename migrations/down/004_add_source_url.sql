@@ -0,0 +1,3 @@
+-- Oil Price Scraper - Source URL (down)
+
+ALTER TABLE oil_prices DROP COLUMN IF EXISTS source_url;
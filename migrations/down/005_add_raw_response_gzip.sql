@@ -0,0 +1,4 @@
+-- Oil Price Scraper - Compressed Raw Responses (down)
+-- Raw responses stored compressed are lost
+
+ALTER TABLE oil_prices DROP COLUMN IF EXISTS raw_response_gzip;
@@ -0,0 +1,5 @@
+-- Oil Price Scraper - Latest Price Indexes (down)
+
+DROP INDEX IF EXISTS idx_provider_date_created;
+DROP INDEX IF EXISTS idx_series_latest;
+CREATE INDEX IF NOT EXISTS idx_provider ON oil_prices (provider);
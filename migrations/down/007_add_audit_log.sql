@@ -0,0 +1,3 @@
+-- Oil Price Scraper - Audit Log (down)
+
+DROP TABLE IF EXISTS audit_log;
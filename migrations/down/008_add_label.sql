@@ -0,0 +1,4 @@
+-- Oil Price Scraper - Label (down)
+
+DROP INDEX IF EXISTS idx_label;
+ALTER TABLE oil_prices DROP COLUMN IF EXISTS label;
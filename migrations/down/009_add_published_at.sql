@@ -0,0 +1,3 @@
+-- Oil Price Scraper - Published At (down)
+
+ALTER TABLE oil_prices DROP COLUMN IF EXISTS published_at;
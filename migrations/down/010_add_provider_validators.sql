@@ -0,0 +1,3 @@
+-- Oil Price Scraper - Provider Validators (down)
+
+DROP TABLE IF EXISTS provider_validators;
@@ -0,0 +1,3 @@
+-- Oil Price Scraper - Provider Counters (down)
+
+DROP TABLE IF EXISTS provider_counters;
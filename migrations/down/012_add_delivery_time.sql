@@ -0,0 +1,4 @@
+-- Oil Price Scraper - Delivery Time (down)
+
+ALTER TABLE oil_prices DROP COLUMN IF EXISTS delivery_time_type;
+ALTER TABLE oil_prices DROP COLUMN IF EXISTS delivery_days;
@@ -0,0 +1,4 @@
+-- Oil Price Scraper - Product Flags (down)
+
+ALTER TABLE oil_prices DROP COLUMN IF EXISTS is_climate_neutral;
+ALTER TABLE oil_prices DROP COLUMN IF EXISTS is_premium;
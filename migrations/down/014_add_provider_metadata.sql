@@ -0,0 +1,3 @@
+-- Oil Price Scraper - Provider Metadata (down)
+
+DROP TABLE IF EXISTS provider_metadata;
@@ -0,0 +1,3 @@
+-- Oil Price Scraper - Failed Inserts (down)
+
+DROP TABLE IF EXISTS failed_inserts;
@@ -0,0 +1,8 @@
+-- Oil Price Scraper - Audit Log Deletes (down)
+
+-- Existing delete entries are kept, so the restored check is not validated against them
+ALTER TABLE audit_log DROP CONSTRAINT IF EXISTS audit_log_action_check;
+ALTER TABLE audit_log ADD CONSTRAINT audit_log_action_check CHECK (action IN ('insert', 'update', 'skip')) NOT VALID;
+
+COMMENT ON TABLE audit_log IS 'Append-only audit log of price inserts, updates, and skips';
+COMMENT ON COLUMN audit_log.action IS 'insert, update (old_price -> new_price), or skip (price already stored)';
@@ -0,0 +1,11 @@
+// Package migrations embeds the SQL schema migrations, so the binary can apply them
+// (see the migrate command). Up migrations are the numbered files in this directory,
+// the matching down migrations live in down/ (so Docker's initdb only runs the up migrations).
+package migrations
+
+import "embed"
+
+// FS contains the up migrations (*.sql) and down migrations (down/*.sql).
+//
+//go:embed *.sql down/*.sql
+var FS embed.FS